@@ -48,7 +48,7 @@ func (fn apiHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 				http.Error(w, encErr.Error(), http.StatusInternalServerError)
 			}
 		default:
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			http.Error(w, err.Error(), storeStatus(err))
 		}
 	}
 }
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // register GIF for image.Decode
+	"image/jpeg"
+	_ "image/png" // register PNG for image.Decode
+	"io"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/kusubooru/shimmie"
+	"github.com/kusubooru/teian/teian"
+)
+
+const (
+	attachFormFileName = "attachments"
+	maxAttachmentSize  = 10 << 20 // 10 MB
+	maxImageDimension  = 10000
+	// maxImagePixels limits the memory used to decode an image, which is
+	// about 4 bytes per pixel.
+	maxImagePixels = 25000000
+	thumbSize      = 192
+)
+
+var (
+	errTooManyAttachments = errors.New("too many attachments")
+	errAttachmentTooLarge = errors.New("attachment too large")
+	errNotImage           = errors.New("attachment is not a PNG, JPEG or GIF image")
+	errImageTooLarge      = errors.New("attachment image is too large")
+)
+
+// imageExts holds the extension of each accepted content type.
+var imageExts = map[string]string{
+	"image/png":  ".png",
+	"image/jpeg": ".jpg",
+	"image/gif":  ".gif",
+}
+
+// saveAttachments stores the images uploaded with a suggestion in the upload
+// directory of the user along with their thumbnails and charges their size
+// against the user's quota. Nothing is kept if any of them fails. If the
+// suggestion is then not created the attachments must be given to
+// discardAttachments.
+func (app *App) saveAttachments(ctx context.Context, user *shimmie.User, files []*multipart.FileHeader) ([]teian.Attachment, error) {
+	if len(files) == 0 {
+		return nil, nil
+	}
+	if len(files) > app.Conf.MaxAttachments {
+		return nil, errTooManyAttachments
+	}
+	limit, err := app.quotaLimit(ctx, user)
+	if err != nil {
+		return nil, err
+	}
+	dir := filepath.Join(*uploadDir, user.Name)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+
+	var attachments []teian.Attachment
+	var total int64
+	ok := false
+	defer func() {
+		if !ok {
+			removeAttachments(dir, attachments, app.Log)
+		}
+	}()
+	prefix := "suggest_" + time.Now().Format("2006-01-02_15.04.05.000_")
+	for i, fh := range files {
+		if fh.Size > maxAttachmentSize {
+			return nil, errAttachmentTooLarge
+		}
+		a, err := saveAttachment(dir, prefix+strconv.Itoa(i), fh)
+		if err != nil {
+			return nil, err
+		}
+		attachments = append(attachments, *a)
+		total += a.Size
+	}
+	if _, err := app.Suggestions.CheckQuota(ctx, user.Name, teian.Quota(total), limit); err != nil {
+		return nil, err
+	}
+	ok = true
+	return attachments, nil
+}
+
+// saveAttachment stores the image of fh as name plus the extension of its
+// content type in dir and creates its thumbnail.
+func saveAttachment(dir, name string, fh *multipart.FileHeader) (*teian.Attachment, error) {
+	file, err := fh.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	// Trust only the content, never the client provided type or filename.
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return nil, errNotImage
+	}
+	contentType := http.DetectContentType(head[:n])
+	ext, ok := imageExts[contentType]
+	if !ok {
+		return nil, errNotImage
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	cfg, _, err := image.DecodeConfig(file)
+	if err != nil {
+		return nil, errNotImage
+	}
+	if cfg.Width > maxImageDimension || cfg.Height > maxImageDimension || int64(cfg.Width)*int64(cfg.Height) > maxImagePixels {
+		return nil, errImageTooLarge
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(file)
+	if err != nil {
+		return nil, errNotImage
+	}
+
+	a := &teian.Attachment{
+		Name:        name + ext,
+		Thumb:       name + "_thumb.jpg",
+		ContentType: contentType,
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	f, err := os.Create(filepath.Join(dir, a.Name))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if a.Size, err = io.Copy(f, file); err != nil {
+		os.Remove(f.Name())
+		return nil, err
+	}
+
+	t, err := os.Create(filepath.Join(dir, a.Thumb))
+	if err != nil {
+		os.Remove(f.Name())
+		return nil, err
+	}
+	defer t.Close()
+	if err := jpeg.Encode(t, thumbnail(img, thumbSize), &jpeg.Options{Quality: 85}); err != nil {
+		os.Remove(f.Name())
+		os.Remove(t.Name())
+		return nil, fmt.Errorf("could not create thumbnail: %v", err)
+	}
+	return a, nil
+}
+
+// discardAttachments removes the attachments saved for a suggestion that
+// could not be created and gives back the quota they were charged.
+func (app *App) discardAttachments(username string, attachments []teian.Attachment) {
+	if len(attachments) == 0 {
+		return
+	}
+	removeAttachments(filepath.Join(*uploadDir, username), attachments, app.Log)
+	var total int64
+	for _, a := range attachments {
+		total += a.Size
+	}
+	// The request may be why the suggestion was not created so the refund
+	// must not depend on it.
+	if err := app.Suggestions.RefundQuota(context.Background(), username, teian.Quota(total)); err != nil {
+		app.Log.Println("quota refund failed:", err)
+	}
+}
+
+func removeAttachments(dir string, attachments []teian.Attachment, logger Logger) {
+	for _, a := range attachments {
+		for _, name := range []string{a.Name, a.Thumb} {
+			if err := os.Remove(filepath.Join(dir, name)); err != nil {
+				logger.Println("attachment cleanup failed:", err)
+			}
+		}
+	}
+}
+
+// thumbnail scales img down so that it fits in a size by size square keeping
+// its aspect ratio. Each pixel of the thumbnail is the average of the pixels
+// it covers. Transparent areas are drawn over white.
+func thumbnail(img image.Image, size int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > size || h > size {
+		if w > h {
+			w, h = size, max(1, h*size/w)
+		} else {
+			w, h = max(1, w*size/h), size
+		}
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		y0 := b.Min.Y + y*b.Dy()/h
+		y1 := max(y0+1, b.Min.Y+(y+1)*b.Dy()/h)
+		for x := 0; x < w; x++ {
+			x0 := b.Min.X + x*b.Dx()/w
+			x1 := max(x0+1, b.Min.X+(x+1)*b.Dx()/w)
+			var r, g, bl, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					// composite over white
+					r += uint64(cr + 0xffff - ca)
+					g += uint64(cg + 0xffff - ca)
+					bl += uint64(cb + 0xffff - ca)
+					n++
+				}
+			}
+			dst.Set(x, y, color.RGBA64{uint16(r / n), uint16(g / n), uint16(bl / n), 0xffff})
+		}
+	}
+	return dst
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// serveAttachment serves an image attached to a suggestion, or its
+// thumbnail, to admins and to the author of the suggestion.
+func (app *App) serveAttachment(w http.ResponseWriter, r *http.Request) {
+	user, ok := shimmie.FromContextGetUser(r.Context())
+	if !ok {
+		http.Error(w, "You are not authorized to view this page.", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseUint(r.FormValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("bad id provided: %v", err), http.StatusBadRequest)
+		return
+	}
+	s, err := app.Suggestions.Get(r.Context(), id)
+	if err != nil && !errors.Is(err, teian.ErrNotFound) {
+		app.storeError(w, err, "could not get suggestion")
+		return
+	}
+	if err != nil || (user.Admin != "Y" && s.Username != user.Name) {
+		http.NotFound(w, r)
+		return
+	}
+	name := r.FormValue("name")
+	for _, a := range s.Attachments {
+		contentType := a.ContentType
+		if name == a.Thumb {
+			contentType = "image/jpeg"
+		} else if name != a.Name {
+			continue
+		}
+		w.Header().Set("Content-Type", contentType)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		http.ServeFile(w, r, filepath.Join(*uploadDir, s.Username, name))
+		return
+	}
+	http.NotFound(w, r)
+}
@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io/ioutil"
+	"mime/multipart"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kusubooru/shimmie"
+	"github.com/kusubooru/teian/internal/mock"
+	"github.com/kusubooru/teian/teian"
+)
+
+func TestThumbnail(t *testing.T) {
+	tests := []struct {
+		w, h   int
+		tw, th int
+	}{
+		{400, 200, 192, 96},
+		{200, 400, 96, 192},
+		{100, 50, 100, 50},
+		{1000, 1, 192, 1},
+	}
+	for _, tt := range tests {
+		img := image.NewRGBA(image.Rect(0, 0, tt.w, tt.h))
+		b := thumbnail(img, thumbSize).Bounds()
+		if b.Dx() != tt.tw || b.Dy() != tt.th {
+			t.Errorf("thumbnail of %dx%d is %dx%d, want %dx%d", tt.w, tt.h, b.Dx(), b.Dy(), tt.tw, tt.th)
+		}
+	}
+
+	// A transparent image becomes white.
+	img := image.NewNRGBA(image.Rect(0, 0, 4, 4))
+	if got, want := color.RGBAModel.Convert(thumbnail(img, 2).At(0, 0)), (color.RGBA{255, 255, 255, 255}); got != want {
+		t.Errorf("thumbnail of transparent image has color %v, want %v", got, want)
+	}
+}
+
+func pngBytes(t *testing.T) []byte {
+	var buf bytes.Buffer
+	img := image.NewRGBA(image.Rect(0, 0, 300, 200))
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// hugeGIF returns a GIF whose header claims it is 6000x6000 pixels, more
+// than may be decoded, while each side is within the limit.
+func hugeGIF(t *testing.T) []byte {
+	var buf bytes.Buffer
+	if err := gif.Encode(&buf, image.NewPaletted(image.Rect(0, 0, 1, 1), color.Palette{color.Black}), nil); err != nil {
+		t.Fatal(err)
+	}
+	b := buf.Bytes()
+	// The logical screen size follows the 6 byte signature.
+	for i, v := range []uint16{6000, 6000} {
+		b[6+2*i], b[7+2*i] = byte(v), byte(v>>8)
+	}
+	return b
+}
+
+// multipartFiles returns the file headers of a form that uploads each of
+// contents as an attachment.
+func multipartFiles(t *testing.T, contents ...[]byte) []*multipart.FileHeader {
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	for _, c := range contents {
+		fw, err := mw.CreateFormFile(attachFormFileName, "screenshot.png")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fw.Write(c); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest("POST", "/suggest/submit", &buf)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	if err := r.ParseMultipartForm(defaultMaxMemory); err != nil {
+		t.Fatal(err)
+	}
+	return r.MultipartForm.File[attachFormFileName]
+}
+
+func TestApp_saveAttachments(t *testing.T) {
+	dir, err := ioutil.TempDir("", "teian_attach")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	old := *uploadDir
+	*uploadDir = dir
+	defer func() { *uploadDir = old }()
+
+	var charged teian.Quota
+	limits := make(map[string]teian.Quota)
+	s := &mock.SuggestionStore{}
+	s.QuotaOverrideFn = func(ctx context.Context, username string) (teian.Quota, error) {
+		if username == "jin" {
+			return 5 * teian.MB, nil
+		}
+		return 0, teian.ErrNotFound
+	}
+	s.CheckQuotaFn = func(ctx context.Context, username string, n, limit teian.Quota) (teian.Quota, error) {
+		charged += n
+		limits[username] = limit
+		return 0, nil
+	}
+	conf := teian.Conf{MaxAttachments: 2, Quotas: teian.ClassQuotas{"user": 200 * teian.MB}}
+	app := App{Log: discardLogger, Suggestions: s, Conf: conf}
+
+	img := pngBytes(t)
+	attachments, err := app.saveAttachments(context.Background(), &shimmie.User{Name: "jin", Class: "user"}, multipartFiles(t, img))
+	if err != nil {
+		t.Fatal("saveAttachments failed:", err)
+	}
+	if len(attachments) != 1 {
+		t.Fatalf("saveAttachments returned %d attachments, want 1", len(attachments))
+	}
+	a := attachments[0]
+	if a.ContentType != "image/png" || a.Size != int64(len(img)) || charged != teian.Quota(len(img)) {
+		t.Errorf("saveAttachments = %+v and charged %d, want PNG of %d bytes charged", a, charged, len(img))
+	}
+	if got, want := limits["jin"], 5*teian.MB; got != want {
+		t.Errorf("saveAttachments checked quota against %d, want the override %d", got, want)
+	}
+	for _, name := range []string{a.Name, a.Thumb} {
+		if _, err := os.Stat(filepath.Join(dir, "jin", name)); err != nil {
+			t.Errorf("attachment file %q not stored: %v", name, err)
+		}
+	}
+
+	tests := []struct {
+		contents [][]byte
+		err      error
+	}{
+		{[][]byte{img, img, img}, errTooManyAttachments},
+		{[][]byte{img, []byte("<html>not an image</html>")}, errNotImage},
+		{[][]byte{img, hugeGIF(t)}, errImageTooLarge},
+	}
+	for _, tt := range tests {
+		if _, err := app.saveAttachments(context.Background(), &shimmie.User{Name: "mary", Class: "user"}, multipartFiles(t, tt.contents...)); err != tt.err {
+			t.Errorf("saveAttachments of %d files returned %v, want %v", len(tt.contents), err, tt.err)
+		}
+	}
+	// The valid image of the failed upload must not be left behind.
+	if files, _ := ioutil.ReadDir(filepath.Join(dir, "mary")); len(files) != 0 {
+		t.Errorf("failed saveAttachments left %d files behind", len(files))
+	}
+}
+
+func TestApp_discardAttachments(t *testing.T) {
+	dir, err := ioutil.TempDir("", "teian_attach")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	old := *uploadDir
+	*uploadDir = dir
+	defer func() { *uploadDir = old }()
+
+	attachments := []teian.Attachment{{Name: "a.png", Thumb: "a_thumb.jpg", Size: 3}, {Name: "b.png", Thumb: "b_thumb.jpg", Size: 4}}
+	if err := os.Mkdir(filepath.Join(dir, "jin"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range attachments {
+		for _, name := range []string{a.Name, a.Thumb} {
+			if err := ioutil.WriteFile(filepath.Join(dir, "jin", name), []byte("x"), 0600); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	var refunded teian.Quota
+	s := &mock.SuggestionStore{}
+	s.RefundQuotaFn = func(ctx context.Context, username string, n teian.Quota) error {
+		refunded += n
+		return nil
+	}
+	app := App{Log: discardLogger, Suggestions: s}
+
+	app.discardAttachments("jin", attachments)
+	if got, want := refunded, teian.Quota(7); got != want {
+		t.Errorf("discardAttachments refunded %d, want %d", got, want)
+	}
+	if files, _ := ioutil.ReadDir(filepath.Join(dir, "jin")); len(files) != 0 {
+		t.Errorf("discardAttachments left %d files behind", len(files))
+	}
+
+	// Suggestions without attachments were never charged.
+	s.RefundQuotaInvoked = false
+	app.discardAttachments("jin", nil)
+	if s.RefundQuotaInvoked {
+		t.Error("discardAttachments without attachments should not refund")
+	}
+}
+
+func TestApp_serveAttachment(t *testing.T) {
+	s := &mock.SuggestionStore{}
+	s.GetFn = func(ctx context.Context, id uint64) (*teian.Suggestion, error) {
+		return &teian.Suggestion{ID: id, Username: "jin", Attachments: []teian.Attachment{{Name: "a.png", Thumb: "a_thumb.jpg"}}}, nil
+	}
+	app := App{Suggestions: s}
+
+	tests := []struct {
+		user *shimmie.User
+		name string
+		code int
+	}{
+		{&shimmie.User{Name: "mary"}, "a.png", 404},
+		{&shimmie.User{Name: "jin"}, "../../etc/passwd", 404},
+		{&shimmie.User{Name: "mary", Admin: "Y"}, "other.png", 404},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest("GET", "/suggest/attachment?id=1&name="+tt.name, nil)
+		r = r.WithContext(shimmie.NewContextWithUser(r.Context(), tt.user))
+		app.serveAttachment(w, r)
+		if w.Code != tt.code {
+			t.Errorf("serveAttachment(%q) as %q = %d, want %d", tt.name, tt.user.Name, w.Code, tt.code)
+		}
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/kusubooru/shimmie"
+	"github.com/kusubooru/teian/teian/boltstore"
+)
+
+// backuper is implemented by suggestion stores that can write a consistent
+// copy of their database while in use.
+type backuper interface {
+	Backup(w io.Writer) (int64, error)
+}
+
+// serveBackup sends a copy of the suggestion database to an admin.
+func (app *App) serveBackup(w http.ResponseWriter, r *http.Request) {
+	user, ok := shimmie.FromContextGetUser(r.Context())
+	if !ok || user.Admin != "Y" {
+		http.Error(w, "You are not authorized to view this page.", http.StatusUnauthorized)
+		return
+	}
+	b, ok := app.Suggestions.(backuper)
+	if !ok {
+		http.Error(w, "The suggestion store does not support backups.", http.StatusNotImplemented)
+		return
+	}
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="teian-%s.db"`, time.Now().UTC().Format("20060102-150405")))
+	if _, err := b.Backup(w); err != nil {
+		app.Log.Println("backup failed:", err)
+	}
+}
+
+// runBackup writes a backup of the bolt database to a file, either directly
+// from the file while the server is stopped or from the backup endpoint of a
+// running server.
+func runBackup(args []string) int {
+	fs := flag.NewFlagSet("backup", flag.ContinueOnError)
+	backupURL := fs.String("url", "", "backup endpoint of a running server, e.g. https://example.com/suggest/admin/backup")
+	cookie := fs.String("cookie", "", "cookies of an admin session for -url, e.g. 'shm_user=admin; shm_session=...'")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: backup [options] <file>\n\nOptions:\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	name := fs.Arg(0)
+
+	tmp := name + ".part"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "backup:", err)
+		return 1
+	}
+	defer os.Remove(tmp)
+	var n int64
+	if *backupURL != "" {
+		n, err = downloadBackup(f, *backupURL, *cookie)
+	} else {
+		n, err = boltstore.BackupFile(*boltFile, f)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = boltstore.Verify(tmp)
+	}
+	if err == nil {
+		err = os.Rename(tmp, name)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "backup failed:", err)
+		return 1
+	}
+	fmt.Printf("wrote %d bytes to %s\n", n, name)
+	return 0
+}
+
+func downloadBackup(w io.Writer, url, cookie string) (int64, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return 0, err
+	}
+	if cookie != "" {
+		req.Header.Set("Cookie", cookie)
+	}
+	// Without a valid session the server redirects to the login page.
+	client := &http.Client{
+		CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("%s: %s", url, resp.Status)
+	}
+	return io.Copy(w, resp.Body)
+}
+
+// runRestore replaces the bolt database with a backup.
+func runRestore(args []string) int {
+	fs := flag.NewFlagSet("restore", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: restore <file>\n\n")
+		fmt.Fprintf(fs.Output(), "The backup is verified before it replaces the -boltfile database which is\n")
+		fmt.Fprintf(fs.Output(), "kept with a .before-restore suffix. The server must be stopped.\n")
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	if err := boltstore.Restore(fs.Arg(0), *boltFile); err != nil {
+		fmt.Fprintln(os.Stderr, "restore failed:", err)
+		return 1
+	}
+	fmt.Printf("restored %s from %s\n", *boltFile, fs.Arg(0))
+	return 0
+}
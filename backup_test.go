@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kusubooru/shimmie"
+	"github.com/kusubooru/teian/internal/mock"
+)
+
+// backupStore is a suggestion store that supports backups.
+type backupStore struct {
+	*mock.SuggestionStore
+	err error
+}
+
+func (s backupStore) Backup(w io.Writer) (int64, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	n, err := io.WriteString(w, "bolt")
+	return int64(n), err
+}
+
+func TestApp_serveBackup(t *testing.T) {
+	admin := &shimmie.User{Name: "admin", Admin: "Y"}
+	app := App{Log: discardLogger, Suggestions: backupStore{SuggestionStore: &mock.SuggestionStore{}}}
+
+	w := httptest.NewRecorder()
+	app.serveBackup(w, newRequest("GET", nil, admin))
+	if w.Code != 200 {
+		t.Fatalf("serveBackup StatusCode = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	if got := w.Body.String(); got != "bolt" {
+		t.Errorf("serveBackup sent %q, want the backup", got)
+	}
+	if cd := w.Header().Get("Content-Disposition"); !strings.HasPrefix(cd, `attachment; filename="teian-`) {
+		t.Errorf("serveBackup Content-Disposition = %q", cd)
+	}
+
+	w = httptest.NewRecorder()
+	app.serveBackup(w, newRequest("GET", nil, &shimmie.User{Name: "jin"}))
+	if w.Code != 401 {
+		t.Errorf("serveBackup for non admin StatusCode = %d, want 401", w.Code)
+	}
+
+	app.Suggestions = backupStore{SuggestionStore: &mock.SuggestionStore{}, err: errors.New("boom")}
+	w = httptest.NewRecorder()
+	app.serveBackup(w, newRequest("GET", nil, admin))
+	if w.Body.Len() != 0 {
+		t.Errorf("serveBackup sent %q when the backup failed", w.Body.String())
+	}
+
+	app.Suggestions = &mock.SuggestionStore{}
+	w = httptest.NewRecorder()
+	app.serveBackup(w, newRequest("GET", nil, admin))
+	if w.Code != 501 {
+		t.Errorf("serveBackup of store without backups StatusCode = %d, want 501", w.Code)
+	}
+}
+
+func TestDownloadBackup(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie("shm_user"); err != nil || c.Value != "admin" {
+			http.Redirect(w, r, "/suggest/login", http.StatusFound)
+			return
+		}
+		io.WriteString(w, "bolt")
+	}))
+	defer ts.Close()
+
+	var buf bytes.Buffer
+	if _, err := downloadBackup(&buf, ts.URL, "shm_user=admin; shm_session=abc"); err != nil {
+		t.Fatal("downloadBackup failed:", err)
+	}
+	if got := buf.String(); got != "bolt" {
+		t.Errorf("downloadBackup wrote %q, want the backup", got)
+	}
+	if _, err := downloadBackup(&buf, ts.URL, ""); err == nil {
+		t.Error("downloadBackup without a session expected to return error")
+	}
+}
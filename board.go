@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"html/template"
+	"net/http"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+const boardPageSize = 50
+
+// boardPage queries a page of public suggestions, newest first, and returns
+// their public view.
+func (app *App) boardPage(r *http.Request) ([]teian.BoardEntry, *teian.Page, error) {
+	q := teian.Query{
+		Public: true,
+		Order:  teian.OrderDateDesc,
+		Limit:  boardPageSize,
+		Cursor: r.FormValue("c"),
+	}
+	page, err := app.Suggestions.Query(r.Context(), q)
+	if err != nil {
+		return nil, nil, err
+	}
+	entries := make([]teian.BoardEntry, len(page.Suggestions))
+	for i := range page.Suggestions {
+		entries[i] = teian.NewBoardEntry(&page.Suggestions[i])
+	}
+	return entries, page, nil
+}
+
+// serveBoard shows the public suggestions to everyone without requiring a
+// login.
+func (app *App) serveBoard(w http.ResponseWriter, r *http.Request) {
+	entries, page, err := app.boardPage(r)
+	if err != nil {
+		app.storeError(w, err, "could not get suggestions")
+		return
+	}
+	data := struct {
+		Entries []teian.BoardEntry
+		Prev    string
+		Next    string
+	}{
+		Entries: entries,
+	}
+	if page.Prev != "" {
+		data.Prev = pageURL(r, page.Prev)
+	}
+	if page.Next != "" {
+		data.Next = pageURL(r, page.Next)
+	}
+	app.render(w, boardTmpl, data)
+}
+
+type BoardResp struct {
+	Suggestions []teian.BoardEntry `json:"suggestions"`
+	Next        string             `json:"next,omitempty"`
+	Prev        string             `json:"prev,omitempty"`
+}
+
+// handleBoardJSON returns the same suggestions as serveBoard as JSON. The
+// next and prev values can be passed as the c parameter to get more pages.
+func (app *App) handleBoardJSON(w http.ResponseWriter, r *http.Request) error {
+	entries, page, err := app.boardPage(r)
+	if err != nil {
+		return E(err, "Could not get suggestions.", storeStatus(err))
+	}
+	resp := BoardResp{
+		Suggestions: entries,
+		Next:        page.Next,
+		Prev:        page.Prev,
+	}
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	if err := json.NewEncoder(w).Encode(resp); err != nil {
+		return E(err, "Could not encode suggestions.", http.StatusInternalServerError)
+	}
+	return nil
+}
+
+var boardTmpl = template.Must(template.New("boardTmpl").Parse(baseTemplate + boardTemplate))
+
+const boardTemplate = `
+{{define "subnav"}}
+<div id="subnav">
+	<a href="/suggest">New suggestion</a>
+	<a href="/suggest/board.json">JSON</a>
+</div>
+{{end}}
+{{define "content"}}
+{{ range .Data.Entries }}
+	<div class="suggestion">
+		<span>{{.FmtCreated}} by {{if .Username}}<a href="/user/{{.Username}}">{{.Username}}</a>{{else}}anonymous{{end}} ({{.Status}}, +{{.Upvotes}} / -{{.Downvotes}})</span>
+		<div class="text">{{.HTML}}</div>
+	</div>
+{{ else }}
+	<div class="suggestion-form">
+		<p>There are no public suggestions yet.</p>
+	</div>
+{{ end }}
+<div class="pagination">
+	{{if .Data.Prev}}<a href="{{.Data.Prev}}">&larr; Previous</a>{{end}}
+	{{if .Data.Next}}<a href="{{.Data.Next}}">Next &rarr;</a>{{end}}
+</div>
+{{end}}
+`
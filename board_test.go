@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kusubooru/teian/internal/mock"
+	"github.com/kusubooru/teian/teian"
+)
+
+func boardStore(query *teian.Query) *mock.SuggestionStore {
+	s := &mock.SuggestionStore{}
+	s.QueryFn = func(ctx context.Context, q teian.Query) (*teian.Page, error) {
+		*query = q
+		return &teian.Page{
+			Suggestions: []teian.Suggestion{
+				{ID: 2, Username: "secret", Text: "anonymous idea", Public: true, Anonymous: true, Status: teian.StatusPlanned},
+				{ID: 1, Username: "mary", Text: "public idea", Public: true},
+			},
+			Next: "next-cursor",
+		}, nil
+	}
+	return s
+}
+
+func TestApp_handleBoardJSON(t *testing.T) {
+	var query teian.Query
+	app := App{Log: discardLogger, Suggestions: boardStore(&query)}
+
+	w := httptest.NewRecorder()
+	if err := app.handleBoardJSON(w, httptest.NewRequest("GET", "/suggest/board.json?c=abc", nil)); err != nil {
+		t.Fatal("handleBoardJSON returned error:", err)
+	}
+	if !query.Public {
+		t.Error("handleBoardJSON should only query public suggestions")
+	}
+	if got, want := query.Cursor, "abc"; got != want {
+		t.Errorf("handleBoardJSON queried with cursor %q, want %q", got, want)
+	}
+	if strings.Contains(w.Body.String(), "secret") {
+		t.Error("handleBoardJSON exposed the username of an anonymous user")
+	}
+
+	var resp BoardResp
+	if err := json.NewDecoder(w.Body).Decode(&resp); err != nil {
+		t.Fatal("decoding board response failed:", err)
+	}
+	if got, want := len(resp.Suggestions), 2; got != want {
+		t.Fatalf("board response has %d suggestions, want %d", got, want)
+	}
+	if got, want := resp.Suggestions[0].Status, teian.StatusPlanned; got != want {
+		t.Errorf("board response status = %v, want %v", got, want)
+	}
+	if got, want := resp.Suggestions[1].Username, "mary"; got != want {
+		t.Errorf("board response username = %q, want %q", got, want)
+	}
+	if got, want := resp.Next, "next-cursor"; got != want {
+		t.Errorf("board response next = %q, want %q", got, want)
+	}
+}
+
+func TestApp_handleBoardJSON_badCursor(t *testing.T) {
+	s := &mock.SuggestionStore{}
+	s.QueryFn = func(ctx context.Context, q teian.Query) (*teian.Page, error) {
+		return nil, teian.Errorf(teian.ErrInvalid, "invalid cursor")
+	}
+	app := App{Log: discardLogger, Suggestions: s}
+
+	w := httptest.NewRecorder()
+	apiHandler(app.handleBoardJSON).ServeHTTP(w, httptest.NewRequest("GET", "/suggest/board.json?c=!", nil))
+	if got, want := w.Result().StatusCode, 400; got != want {
+		t.Errorf("StatusCode = %d, want %d", got, want)
+	}
+}
+
+func TestApp_serveBoard(t *testing.T) {
+	var query teian.Query
+	app := App{Log: discardLogger, Suggestions: boardStore(&query)}
+
+	w := httptest.NewRecorder()
+	app.serveBoard(w, httptest.NewRequest("GET", "/suggest/board", nil))
+	if got, want := w.Result().StatusCode, 200; got != want {
+		t.Fatalf("StatusCode = %d, want %d", got, want)
+	}
+	body := w.Body.String()
+	if strings.Contains(body, "secret") {
+		t.Error("serveBoard exposed the username of an anonymous user")
+	}
+	for _, want := range []string{"anonymous idea", "public idea", "planned"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("serveBoard body does not contain %q", want)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/kusubooru/shimmie"
+	"github.com/kusubooru/teian/teian"
+)
+
+// bulkFailure is a suggestion that a bulk action could not change.
+type bulkFailure struct {
+	ID  uint64
+	Err string
+}
+
+// bulkIDs returns the IDs of the suggestions selected on the admin list.
+func bulkIDs(r *http.Request) ([]uint64, error) {
+	var ids []uint64
+	for _, v := range r.PostForm["id"] {
+		id, err := strconv.ParseUint(v, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad id provided: %v", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// handleBulk applies an action to the suggestions selected on the admin
+// list and shows which of them succeeded and which failed.
+func (app *App) handleBulk(w http.ResponseWriter, r *http.Request) {
+	// only accept POST method
+	if r.Method != "POST" {
+		http.Error(w, fmt.Sprintf("%v method not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := shimmie.FromContextGetUser(r.Context())
+	if !ok || user.Admin != "Y" {
+		http.Error(w, "You are not authorized to perform this action.", http.StatusUnauthorized)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	ids, err := bulkIDs(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if len(ids) == 0 {
+		http.Error(w, "no suggestions selected", http.StatusBadRequest)
+		return
+	}
+
+	op := teian.BulkOp{By: user.Name}
+	action := r.PostFormValue("action")
+	switch action {
+	case "export":
+		app.exportSelection(w, r, ids)
+		return
+	case "delete":
+		op.Action = teian.BulkDelete
+	case "status":
+		op.Action = teian.BulkStatus
+		if op.Status, err = teian.ParseStatus(r.PostFormValue("status")); err != nil {
+			http.Error(w, fmt.Sprintf("bad status provided: %v", err), http.StatusBadRequest)
+			return
+		}
+	case "category":
+		op.Action = teian.BulkCategory
+		op.Category = r.PostFormValue("category")
+	default:
+		http.Error(w, teian.ErrBadBulkAction.Error(), http.StatusBadRequest)
+		return
+	}
+
+	failed, err := app.Suggestions.Bulk(r.Context(), ids, op)
+	if err != nil {
+		app.storeError(w, err, "bulk %s failed", action)
+		return
+	}
+	app.renderBulk(w, action, ids, failed)
+}
+
+// renderBulk shows which of the suggestions with ids a bulk action was
+// applied to and why it failed for the rest.
+func (app *App) renderBulk(w http.ResponseWriter, action string, ids []uint64, failed map[uint64]error) {
+	data := struct {
+		Action string
+		Done   []uint64
+		Failed []bulkFailure
+	}{
+		Action: action,
+	}
+	for _, id := range ids {
+		if err, ok := failed[id]; ok {
+			data.Failed = append(data.Failed, bulkFailure{id, err.Error()})
+		} else {
+			data.Done = append(data.Done, id)
+		}
+	}
+	sort.Slice(data.Failed, func(i, j int) bool { return data.Failed[i].ID < data.Failed[j].ID })
+	app.render(w, bulkTmpl, data)
+}
+
+// exportSelection sends the selected suggestions as a JSON file. The
+// suggestions that could not be read, such as those deleted since they were
+// selected, are left out. If none could be read the failures are shown like
+// those of the other bulk actions.
+func (app *App) exportSelection(w http.ResponseWriter, r *http.Request, ids []uint64) {
+	suggs, failed, err := app.Suggestions.GetMany(r.Context(), ids)
+	if err != nil {
+		app.storeError(w, err, "export selection failed")
+		return
+	}
+	if len(suggs) == 0 {
+		app.renderBulk(w, "export", ids, failed)
+		return
+	}
+	e, err := teian.NewExporter(w, teian.FormatJSON)
+	if err != nil {
+		app.Errorf(w, http.StatusInternalServerError, err, "export selection failed")
+		return
+	}
+	w.Header().Set("Content-Type", teian.ExportContentType(teian.FormatJSON))
+	w.Header().Set("Content-Disposition", `attachment; filename="suggestions.json"`)
+	for i := range suggs {
+		if err = e.Write(&suggs[i]); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = e.Close()
+	}
+	if err != nil {
+		app.Log.Println("export selection failed:", err)
+	}
+}
+
+var bulkTmpl = template.Must(template.New("bulkTmpl").Parse(baseTemplate + subnavTemplate + bulkTemplate))
+
+const bulkTemplate = `
+{{define "content"}}
+<div class="suggestion-form">
+	{{if .Data.Done}}
+	<div class="alert alert-success">
+		<strong>Done:</strong> {{.Data.Action}} applied to {{len .Data.Done}} suggestions:
+		{{range .Data.Done}}#{{.}} {{end}}
+	</div>
+	{{end}}
+	{{if .Data.Failed}}
+	<div class="alert alert-error">
+		<strong>Failed:</strong> {{.Data.Action}} could not be applied to {{len .Data.Failed}} suggestions:
+		<ul>
+			{{range .Data.Failed}}
+			<li>#{{.ID}}: {{.Err}}</li>
+			{{end}}
+		</ul>
+	</div>
+	{{end}}
+	<a href="/suggest/admin">Back to suggestions</a>
+</div>
+{{end}}
+`
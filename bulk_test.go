@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kusubooru/shimmie"
+	"github.com/kusubooru/teian/internal/mock"
+	"github.com/kusubooru/teian/teian"
+)
+
+func TestApp_handleBulk(t *testing.T) {
+	s := &mock.SuggestionStore{}
+	var gotIDs []uint64
+	var gotOp teian.BulkOp
+	s.BulkFn = func(ctx context.Context, ids []uint64, op teian.BulkOp) (map[uint64]error, error) {
+		gotIDs, gotOp = ids, op
+		return map[uint64]error{3: errors.New("entry does not exist")}, nil
+	}
+	app := App{Log: discardLogger, Suggestions: s}
+
+	admin := &shimmie.User{Name: "admin", Admin: "Y"}
+	v := url.Values{"id": {"1", "2", "3"}, "action": {"status"}, "status": {"planned"}}
+	w := httptest.NewRecorder()
+	app.handleBulk(w, newRequest("POST", v, admin))
+	if w.Code != 200 {
+		t.Fatalf("handleBulk StatusCode = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	if want := []uint64{1, 2, 3}; !reflect.DeepEqual(gotIDs, want) {
+		t.Errorf("handleBulk applied to %v, want %v", gotIDs, want)
+	}
+	if want := (teian.BulkOp{Action: teian.BulkStatus, Status: teian.StatusPlanned, By: "admin"}); gotOp != want {
+		t.Errorf("handleBulk applied %+v, want %+v", gotOp, want)
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, "#1 #2") || !strings.Contains(body, "#3: entry does not exist") {
+		t.Errorf("handleBulk summary does not list what succeeded and failed:\n%s", body)
+	}
+
+	tests := []struct {
+		v    url.Values
+		u    *shimmie.User
+		code int
+	}{
+		{url.Values{"id": {"1"}, "action": {"delete"}}, &shimmie.User{Name: "jin"}, 401},
+		{url.Values{"action": {"delete"}}, admin, 400},
+		{url.Values{"id": {"x"}, "action": {"delete"}}, admin, 400},
+		{url.Values{"id": {"1"}, "action": {"explode"}}, admin, 400},
+		{url.Values{"id": {"1"}, "action": {"status"}, "status": {"bogus"}}, admin, 400},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		app.handleBulk(w, newRequest("POST", tt.v, tt.u))
+		if w.Code != tt.code {
+			t.Errorf("handleBulk(%v) StatusCode = %d, want %d", tt.v, w.Code, tt.code)
+		}
+	}
+}
+
+func TestApp_handleBulk_export(t *testing.T) {
+	s := &mock.SuggestionStore{}
+	s.GetManyFn = func(ctx context.Context, ids []uint64) ([]teian.Suggestion, map[uint64]error, error) {
+		var suggs []teian.Suggestion
+		failed := make(map[uint64]error)
+		for _, id := range ids {
+			if id == 3 || id == 5 {
+				failed[id] = teian.ErrNotFound
+				continue
+			}
+			suggs = append(suggs, teian.Suggestion{ID: id, Text: "export me"})
+		}
+		return suggs, failed, nil
+	}
+	app := App{Log: discardLogger, Suggestions: s}
+
+	// Missing suggestions are left out of the export.
+	v := url.Values{"id": {"4", "3", "2"}, "action": {"export"}}
+	w := httptest.NewRecorder()
+	app.handleBulk(w, newRequest("POST", v, &shimmie.User{Name: "admin", Admin: "Y"}))
+	if s.BulkInvoked {
+		t.Error("export should not change suggestions")
+	}
+	var got []teian.Suggestion
+	if err := json.NewDecoder(w.Body).Decode(&got); err != nil {
+		t.Fatal("decoding export failed:", err)
+	}
+	if len(got) != 2 || got[0].ID != 4 || got[1].ID != 2 {
+		t.Errorf("export returned %v, want suggestions 4 and 2", got)
+	}
+
+	// With nothing to export the failures are shown instead.
+	v = url.Values{"id": {"3", "5"}, "action": {"export"}}
+	w = httptest.NewRecorder()
+	app.handleBulk(w, newRequest("POST", v, &shimmie.User{Name: "admin", Admin: "Y"}))
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("export of missing suggestions has Content-Type %q, want the results page", ct)
+	}
+	if body := w.Body.String(); !strings.Contains(body, "#3: not found") || !strings.Contains(body, "#5: not found") {
+		t.Errorf("export of missing suggestions does not report them:\n%s", body)
+	}
+}
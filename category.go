@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+
+	"github.com/kusubooru/shimmie"
+)
+
+// handleSetCategory allows an admin to move a suggestion to another
+// category.
+func (app *App) handleSetCategory(w http.ResponseWriter, r *http.Request) {
+	// only accept POST method
+	if r.Method != "POST" {
+		http.Error(w, fmt.Sprintf("%v method not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := shimmie.FromContextGetUser(r.Context())
+	if !ok || user.Admin != "Y" {
+		http.Error(w, "You are not authorized to perform this action.", http.StatusUnauthorized)
+		return
+	}
+	idValue := r.PostFormValue("id")
+	username := r.PostFormValue("username")
+	if idValue == "" || username == "" {
+		http.Error(w, "id and username must be present", http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.ParseUint(idValue, 10, 64)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("bad id provided: %v", err), http.StatusBadRequest)
+		return
+	}
+	err = app.Suggestions.SetCategory(r.Context(), username, id, r.PostFormValue("category"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("change suggestion category failed: %v", err), storeStatus(err))
+		return
+	}
+	http.Redirect(w, r, "/suggest/admin", http.StatusFound)
+}
+
+// serveCategories shows the categories along with how many suggestions each
+// has and allows an admin to add or remove them.
+func (app *App) serveCategories(w http.ResponseWriter, r *http.Request) {
+	user, ok := shimmie.FromContextGetUser(r.Context())
+	if !ok || user.Admin != "Y" {
+		http.Error(w, "You are not authorized to view this page.", http.StatusUnauthorized)
+		return
+	}
+	if r.Method == "POST" {
+		var err error
+		name := r.PostFormValue("name")
+		switch r.PostFormValue("action") {
+		case "add":
+			err = app.Suggestions.AddCategory(r.Context(), name)
+		case "remove":
+			err = app.Suggestions.RemoveCategory(r.Context(), name)
+		default:
+			http.Error(w, "unknown action", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("change categories failed: %v", err), storeStatus(err))
+			return
+		}
+		http.Redirect(w, r, "/suggest/admin/categories", http.StatusFound)
+		return
+	}
+
+	categories, err := app.Suggestions.Categories(r.Context())
+	if err != nil {
+		app.storeError(w, err, "could not get categories")
+		return
+	}
+	counts, err := app.Suggestions.CategoryCounts(r.Context())
+	if err != nil {
+		app.storeError(w, err, "could not count categories")
+		return
+	}
+	data := struct {
+		Categories []string
+		Counts     map[string]int
+	}{
+		Categories: categories,
+		Counts:     counts,
+	}
+	app.render(w, categoriesTmpl, data)
+}
+
+var categoriesTmpl = template.Must(template.New("categoriesTmpl").Parse(baseTemplate + subnavTemplate + categoriesTemplate))
+
+const categoriesTemplate = `
+{{define "content"}}
+{{ $counts := .Data.Counts }}
+<div class="suggestion-form">
+	{{ range .Data.Categories }}
+	<form method="post" action="/suggest/admin/categories">
+		<a href="/suggest/admin?cat={{.}}">{{.}}</a> ({{index $counts .}})
+		<input type="hidden" name="action" value="remove">
+		<input type="hidden" name="name" value="{{.}}">
+		<input type="submit" value="Remove">
+	</form>
+	{{ else }}
+	<p>There are no categories yet.</p>
+	{{ end }}
+	<form method="post" action="/suggest/admin/categories">
+		<label for="name">New category</label>
+		<input type="hidden" name="action" value="add">
+		<input type="text" id="name" name="name" required>
+		<input type="submit" value="Add">
+	</form>
+	<p>Removing a category leaves its suggestions uncategorized.</p>
+</div>
+{{end}}
+`
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"fmt"
+	"os"
+)
+
+// command is a subcommand of the program that is run instead of the server.
+type command struct {
+	name string
+	desc string
+	// run runs the command with its arguments and returns the exit code.
+	run func(args []string) int
+}
+
+var commands = []command{
+	{"export", "write the suggestions to stdout as CSV, JSON or JSONL", runExport},
+	{"import", "add the suggestions of a CSV, JSON or JSONL file", runImport},
+	{"backup", "write a copy of the database to a file", runBackup},
+	{"restore", "replace the database with a backup", runRestore},
+}
+
+// runCommand runs the command named by args[0] and returns its exit code.
+func runCommand(args []string) int {
+	for _, c := range commands {
+		if c.name == args[0] {
+			return c.run(args[1:])
+		}
+	}
+	fmt.Fprintf(os.Stderr, "unknown command %q\n", args[0])
+	usage()
+	return 2
+}
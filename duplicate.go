@@ -0,0 +1,182 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/kusubooru/shimmie"
+	"github.com/kusubooru/teian/teian"
+)
+
+// maxDuplicates is the most likely duplicates shown when submitting.
+const maxDuplicates = 5
+
+// duplicates returns the suggestions username can see that are likely
+// duplicates of text. Only open suggestions are returned since those are the
+// ones that can still be voted or commented on.
+func (app *App) duplicates(ctx context.Context, username, text string) ([]teian.BoardEntry, error) {
+	similar, err := app.Suggestions.Similar(ctx, text, teian.DuplicateThreshold)
+	if err != nil {
+		return nil, err
+	}
+	var entries []teian.BoardEntry
+	for i := range similar {
+		s := &similar[i].Suggestion
+		if !s.Status.Open() || !(s.Public || s.Username == username) {
+			continue
+		}
+		entries = append(entries, teian.NewBoardEntry(s))
+		if len(entries) == maxDuplicates {
+			break
+		}
+	}
+	return entries, nil
+}
+
+// renderDuplicates shows the likely duplicates of a suggestion that is being
+// submitted along with a form to submit it anyway.
+func (app *App) renderDuplicates(w http.ResponseWriter, form url.Values, entries []teian.BoardEntry) {
+	form = copyValues(form)
+	form.Set("force", "1")
+	data := struct {
+		Text    string
+		Form    url.Values
+		Entries []teian.BoardEntry
+	}{
+		Text:    form.Get("text"),
+		Form:    form,
+		Entries: entries,
+	}
+	app.render(w, duplicatesTmpl, data)
+}
+
+func copyValues(v url.Values) url.Values {
+	c := make(url.Values, len(v))
+	for k, vs := range v {
+		c[k] = append([]string(nil), vs...)
+	}
+	return c
+}
+
+// handleComment adds a comment from the logged in user to a suggestion they
+// can see, usually instead of submitting a duplicate of it.
+func (app *App) handleComment(w http.ResponseWriter, r *http.Request) {
+	// only accept POST method
+	if r.Method != "POST" {
+		http.Error(w, fmt.Sprintf("%v method not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := shimmie.FromContextGetUser(r.Context())
+	if !ok {
+		http.Error(w, "You are not authorized to perform this action.", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseUint(r.PostFormValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("bad id provided: %v", err), http.StatusBadRequest)
+		return
+	}
+	text := r.PostFormValue("text")
+	if len(strings.TrimSpace(text)) == 0 {
+		http.Error(w, "comment text must be present", http.StatusBadRequest)
+		return
+	}
+	s, err := app.Suggestions.Get(r.Context(), id)
+	if err != nil && !errors.Is(err, teian.ErrNotFound) {
+		app.storeError(w, err, "could not get suggestion")
+		return
+	}
+	if err != nil || !(s.Public || s.Username == user.Name) {
+		http.Error(w, "suggestion not found", http.StatusNotFound)
+		return
+	}
+	if !s.Status.Open() {
+		http.Error(w, "suggestion is closed", http.StatusBadRequest)
+		return
+	}
+	if err := app.Suggestions.AddReply(r.Context(), "", id, &teian.Reply{Username: user.Name, Text: text}); err != nil {
+		app.storeError(w, err, "comment failed")
+		return
+	}
+	http.Redirect(w, r, "/suggest/success", http.StatusSeeOther)
+}
+
+// handleMerge allows an admin to merge a duplicate suggestion into another.
+func (app *App) handleMerge(w http.ResponseWriter, r *http.Request) {
+	// only accept POST method
+	if r.Method != "POST" {
+		http.Error(w, fmt.Sprintf("%v method not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := shimmie.FromContextGetUser(r.Context())
+	if !ok || user.Admin != "Y" {
+		http.Error(w, "You are not authorized to perform this action.", http.StatusUnauthorized)
+		return
+	}
+	from, err := strconv.ParseUint(r.PostFormValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("bad id provided: %v", err), http.StatusBadRequest)
+		return
+	}
+	into, err := strconv.ParseUint(r.PostFormValue("into"), 10, 64)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("bad merge target provided: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := app.Suggestions.Merge(r.Context(), into, from, user.Name); err != nil {
+		http.Error(w, fmt.Sprintf("merge suggestions failed: %v", err), storeStatus(err))
+		return
+	}
+	http.Redirect(w, r, "/suggest/admin", http.StatusFound)
+}
+
+var duplicatesTmpl = template.Must(template.New("duplicatesTmpl").Parse(baseTemplate + subnavTemplate + duplicatesTemplate))
+
+const duplicatesTemplate = `
+{{define "css"}}
+.suggestion form {
+	display: inline;
+}
+{{end}}
+{{define "content"}}
+{{ $text := .Data.Text }}
+<div class="alert alert-warning">
+	<strong>Wait:</strong> Your suggestion looks like these existing ones. You
+	can vote or add your text as a comment to one of them instead.
+</div>
+{{ range .Data.Entries }}
+	<div class="suggestion">
+		<span>#{{.ID}} {{.FmtCreated}} by {{if .Username}}<a href="/user/{{.Username}}">{{.Username}}</a>{{else}}anonymous{{end}} ({{.Status}}, +{{.Upvotes}} / -{{.Downvotes}})</span>
+		<div class="text">{{.HTML}}</div>
+		<form method="post" action="/suggest/vote">
+			<input type="hidden" name="id" value="{{.ID}}">
+			<input type="hidden" name="vote" value="1">
+			<input type="submit" value="Vote up instead">
+		</form>
+		<form method="post" action="/suggest/comment">
+			<input type="hidden" name="id" value="{{.ID}}">
+			<input type="hidden" name="text" value="{{$text}}">
+			<input type="submit" value="Add mine as a comment">
+		</form>
+	</div>
+{{ end }}
+<div class="suggestion-form">
+	<form method="post" action="/suggest/submit" enctype="multipart/form-data">
+		{{ range $k, $vs := .Data.Form }}{{ range $vs }}
+		<input type="hidden" name="{{$k}}" value="{{.}}">
+		{{ end }}{{ end }}
+		{{if .Conf.MaxAttachments}}
+		<label for="attachments">Screenshots have to be attached again</label>
+		<input type="file" id="attachments" name="attachments" accept="image/png,image/jpeg,image/gif" multiple>
+		{{end}}
+		<input type="submit" value="Submit as a new suggestion anyway">
+	</form>
+</div>
+{{end}}
+`
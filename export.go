@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/kusubooru/shimmie"
+	"github.com/kusubooru/teian/teian"
+)
+
+// serveExport sends the suggestions that match the filters of the admin
+// toolbar as a file in the requested format.
+func (app *App) serveExport(w http.ResponseWriter, r *http.Request) {
+	user, ok := shimmie.FromContextGetUser(r.Context())
+	if !ok || user.Admin != "Y" {
+		http.Error(w, "You are not authorized to view this page.", http.StatusUnauthorized)
+		return
+	}
+	q, err := adminQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	format := r.FormValue("format")
+	if format == "" {
+		format = teian.FormatCSV
+	}
+	e, err := teian.NewExporter(w, format)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	w.Header().Set("Content-Type", teian.ExportContentType(format))
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="suggestions.%s"`, format))
+	// The headers have been sent by the time the store fails so the error
+	// can only be logged.
+	if err := teian.Export(r.Context(), app.Suggestions, q, e); err != nil {
+		app.Log.Println("export failed:", err)
+	}
+}
+
+// runExport writes the suggestions of the bolt database to stdout.
+func runExport(args []string) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	format := fs.String("format", teian.FormatCSV, "output format: "+strings.Join(teian.ExportFormats(), ", "))
+	v := url.Values{}
+	for _, f := range []struct{ name, usage string }{
+		{"u", "only suggestions of usernames containing this"},
+		{"t", "only suggestions matching this search query"},
+		{"o", "order: dd, da, ud, ua, vd or va"},
+		{"s", "only suggestions with this status"},
+		{"cat", "only suggestions of this category"},
+		{"from", "only suggestions created on or after this date (YYYY-MM-DD)"},
+		{"to", "only suggestions created on or before this date (YYYY-MM-DD)"},
+	} {
+		fs.Var(formValue{v, f.name}, f.name, f.usage)
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	q, err := formQuery(v)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "export:", err)
+		return 2
+	}
+
+	out := bufio.NewWriter(os.Stdout)
+	e, err := teian.NewExporter(out, *format)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "export:", err)
+		return 2
+	}
+
+	store := openSuggestionStore()
+	defer store.Close()
+	if err := teian.Export(context.Background(), store, q, e); err != nil {
+		fmt.Fprintln(os.Stderr, "export failed:", err)
+		return 1
+	}
+	if err := out.Flush(); err != nil {
+		fmt.Fprintln(os.Stderr, "export failed:", err)
+		return 1
+	}
+	return 0
+}
+
+// formValue is a flag that sets a value of a form so that commands can
+// share the filters of the admin toolbar.
+type formValue struct {
+	v    url.Values
+	name string
+}
+
+func (f formValue) String() string {
+	if f.v == nil {
+		return ""
+	}
+	return f.v.Get(f.name)
+}
+
+func (f formValue) Set(s string) error {
+	f.v.Set(f.name, s)
+	return nil
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+
+	"github.com/kusubooru/shimmie"
+	"github.com/kusubooru/teian/internal/mock"
+	"github.com/kusubooru/teian/teian"
+)
+
+func exportRequest(v url.Values, u *shimmie.User) *http.Request {
+	r := httptest.NewRequest("GET", "/suggest/admin/export?"+v.Encode(), nil)
+	return r.WithContext(shimmie.NewContextWithUser(r.Context(), u))
+}
+
+func TestApp_serveExport(t *testing.T) {
+	s := &mock.SuggestionStore{}
+	var queries []teian.Query
+	s.QueryFn = func(ctx context.Context, q teian.Query) (*teian.Page, error) {
+		queries = append(queries, q)
+		if q.Cursor == "" {
+			return &teian.Page{Suggestions: []teian.Suggestion{{ID: 3}, {ID: 2}}, Next: "next"}, nil
+		}
+		return &teian.Page{Suggestions: []teian.Suggestion{{ID: 1}}}, nil
+	}
+	app := App{Log: discardLogger, Suggestions: s}
+
+	admin := &shimmie.User{Name: "admin", Admin: "Y"}
+	v := url.Values{"format": {"csv"}, "u": {"jin"}, "o": {"da"}, "c": {"ignored"}}
+	w := httptest.NewRecorder()
+	app.serveExport(w, exportRequest(v, admin))
+	if w.Code != 200 {
+		t.Fatalf("serveExport StatusCode = %d, want 200: %s", w.Code, w.Body.String())
+	}
+	if len(queries) != 2 || queries[0].Username != "jin" || queries[0].Order != teian.OrderDateAsc || queries[0].Cursor != "" {
+		t.Errorf("serveExport queried %+v, want all pages of the filtered suggestions", queries)
+	}
+	records, err := csv.NewReader(w.Body).ReadAll()
+	if err != nil {
+		t.Fatal("reading CSV export failed:", err)
+	}
+	var ids []string
+	for _, r := range records[1:] {
+		ids = append(ids, r[0])
+	}
+	if want := []string{"3", "2", "1"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("serveExport exported %v, want %v", ids, want)
+	}
+
+	tests := []struct {
+		v    url.Values
+		u    *shimmie.User
+		code int
+	}{
+		{url.Values{"format": {"csv"}}, &shimmie.User{Name: "jin"}, 401},
+		{url.Values{"format": {"xml"}}, admin, 400},
+		{url.Values{"format": {"csv"}, "s": {"bogus"}}, admin, 400},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		app.serveExport(w, exportRequest(tt.v, tt.u))
+		if w.Code != tt.code {
+			t.Errorf("serveExport(%v) StatusCode = %d, want %d", tt.v, w.Code, tt.code)
+		}
+	}
+}
@@ -4,6 +4,9 @@ go 1.14
 
 require (
 	github.com/boltdb/bolt v1.3.1
+	github.com/go-sql-driver/mysql v1.2.1-0.20160802113842-0b58b37b664c
 	github.com/kusubooru/shimmie v0.2.0
+	github.com/mattn/go-sqlite3 v1.14.0
 	golang.org/x/sys v0.0.0-20200625212154-ddb9806d33ae // indirect
+	golang.org/x/text v0.3.3
 )
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+// runImport adds the suggestions of a file to the bolt database.
+func runImport(args []string) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := fs.String("format", "", "input format: "+strings.Join(teian.ExportFormats(), ", ")+" (default from the file extension)")
+	dryRun := fs.Bool("dry-run", false, "report what would be imported without changing the database")
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "Usage: import [options] <file>\n\nOptions:\n\n")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	name := fs.Arg(0)
+	if *format == "" {
+		*format = importFormat(name)
+	}
+	f, err := os.Open(name)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "import:", err)
+		return 1
+	}
+	defer f.Close()
+
+	store := openSuggestionStore()
+	defer store.Close()
+	if err := importSuggestions(context.Background(), store, f, *format, *dryRun, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "import failed:", err)
+		return 1
+	}
+	return 0
+}
+
+// importFormat guesses the format of a file from its extension.
+func importFormat(name string) string {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".json":
+		return teian.FormatJSON
+	case ".jsonl", ".ndjson":
+		return teian.FormatJSONL
+	}
+	return teian.FormatCSV
+}
+
+// importSuggestions reads suggestions in format from r, adds them to store
+// and writes a report of each row to out. If any row is invalid nothing is
+// imported.
+func importSuggestions(ctx context.Context, store teian.SuggestionStore, r io.Reader, format string, dryRun bool, out io.Writer) error {
+	rows, invalid, err := teian.ReadImport(r, format)
+	if err != nil {
+		return err
+	}
+	categories, err := store.Categories(ctx)
+	if err != nil {
+		return err
+	}
+	known := make(map[string]bool)
+	for _, c := range categories {
+		known[c] = true
+	}
+	for _, row := range rows {
+		if c := row.Suggestion.Category; c != "" && !known[c] {
+			invalid = append(invalid, &teian.ImportError{Row: row.Row, Err: fmt.Errorf("%v %q", teian.ErrUnknownCategory, c)})
+		}
+	}
+	if len(invalid) != 0 {
+		for _, e := range invalid {
+			fmt.Fprintln(out, e)
+		}
+		return fmt.Errorf("%d invalid rows, nothing was imported", len(invalid))
+	}
+
+	suggs := make([]teian.Suggestion, len(rows))
+	for i := range rows {
+		suggs[i] = rows[i].Suggestion
+	}
+	added, err := store.Import(ctx, suggs, dryRun)
+	if err != nil {
+		return err
+	}
+	verb := "imported"
+	if dryRun {
+		verb = "would import"
+	}
+	n := 0
+	for i, s := range suggs {
+		if added[i] {
+			n++
+			fmt.Fprintf(out, "row %d: %s as #%d\n", rows[i].Row, verb, s.ID)
+		} else {
+			fmt.Fprintf(out, "row %d: skipped, already imported\n", rows[i].Row)
+		}
+	}
+	fmt.Fprintf(out, "%s %d of %d suggestions\n", verb, n, len(suggs))
+	return nil
+}
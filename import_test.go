@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/kusubooru/teian/internal/mock"
+	"github.com/kusubooru/teian/teian"
+)
+
+func TestImportSuggestions(t *testing.T) {
+	s := &mock.SuggestionStore{}
+	s.CategoriesFn = func(ctx context.Context) ([]string, error) { return []string{"ui"}, nil }
+	var gotDryRun bool
+	s.ImportFn = func(ctx context.Context, suggs []teian.Suggestion, dryRun bool) ([]bool, error) {
+		gotDryRun = dryRun
+		suggs[0].ID = 5
+		return []bool{true, false}, nil
+	}
+
+	in := "username,text,category\njin,dark theme,ui\nmugen,more tags,\n"
+	var out bytes.Buffer
+	if err := importSuggestions(context.Background(), s, strings.NewReader(in), teian.FormatCSV, true, &out); err != nil {
+		t.Fatal("importSuggestions failed:", err)
+	}
+	if !gotDryRun {
+		t.Error("importSuggestions did not pass dry run to the store")
+	}
+	want := "row 2: would import as #5\nrow 3: skipped, already imported\nwould import 1 of 2 suggestions\n"
+	if got := out.String(); got != want {
+		t.Errorf("importSuggestions report =\n%s\nwant\n%s", got, want)
+	}
+
+	// Nothing is imported if any row is invalid.
+	s.ImportInvoked = false
+	out.Reset()
+	in = "username,text,category\njin,dark theme,games\n,more tags,\n"
+	if err := importSuggestions(context.Background(), s, strings.NewReader(in), teian.FormatCSV, false, &out); err == nil {
+		t.Error("importSuggestions with invalid rows expected to return error")
+	}
+	if s.ImportInvoked {
+		t.Error("importSuggestions with invalid rows should not import")
+	}
+	if got := out.String(); !strings.Contains(got, "row 2: unknown category") || !strings.Contains(got, "row 3: username is empty") {
+		t.Errorf("importSuggestions report does not list the invalid rows:\n%s", got)
+	}
+}
+
+func TestImportFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+	}{
+		{"forum.csv", teian.FormatCSV},
+		{"sheet.CSV", teian.FormatCSV},
+		{"dump.json", teian.FormatJSON},
+		{"dump.jsonl", teian.FormatJSONL},
+		{"dump", teian.FormatCSV},
+	}
+	for _, tt := range tests {
+		if got := importFormat(tt.name); got != tt.want {
+			t.Errorf("importFormat(%q) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
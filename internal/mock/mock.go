@@ -1,41 +1,250 @@
 package mock
 
-import "github.com/kusubooru/teian/teian"
+import (
+	"context"
+
+	"github.com/kusubooru/teian/teian"
+)
 
 type SuggestionStore struct {
-	CreateFn      func(username string, sugg *teian.Suggestion) error
+	CreateFn      func(ctx context.Context, username string, sugg *teian.Suggestion) error
 	CreateInvoked bool
 
-	OfUserFn      func(username string) ([]teian.Suggestion, error)
+	GetFn      func(ctx context.Context, id uint64) (*teian.Suggestion, error)
+	GetInvoked bool
+
+	GetManyFn      func(ctx context.Context, ids []uint64) ([]teian.Suggestion, map[uint64]error, error)
+	GetManyInvoked bool
+
+	OfUserFn      func(ctx context.Context, username string) ([]teian.Suggestion, error)
 	OfUserInvoked bool
 
-	AllFn      func() ([]teian.Suggestion, error)
+	AllFn      func(ctx context.Context) ([]teian.Suggestion, error)
 	AllInvoked bool
 
-	DeleteFn      func(username string, id uint64) error
+	SearchFn      func(ctx context.Context, query string) ([]teian.Suggestion, error)
+	SearchInvoked bool
+
+	QueryFn      func(ctx context.Context, q teian.Query) (*teian.Page, error)
+	QueryInvoked bool
+
+	VoteFn      func(ctx context.Context, id uint64, username string, vote int) error
+	VoteInvoked bool
+
+	VotesOfFn      func(ctx context.Context, username string) (map[uint64]int, error)
+	VotesOfInvoked bool
+
+	DeleteFn      func(ctx context.Context, username string, id uint64, by string) error
 	DeleteInvoked bool
 
-	CheckQuotaFn      func(username string, n teian.Quota) (teian.Quota, error)
-	CheckQuotaInvoked bool
+	BulkFn      func(ctx context.Context, ids []uint64, op teian.BulkOp) (map[uint64]error, error)
+	BulkInvoked bool
+
+	TrashFn      func(ctx context.Context) ([]teian.Suggestion, error)
+	TrashInvoked bool
+
+	TrashedFn      func(ctx context.Context, id uint64) (*teian.Suggestion, error)
+	TrashedInvoked bool
+
+	RestoreFn      func(ctx context.Context, id uint64) error
+	RestoreInvoked bool
+
+	PurgeFn      func(ctx context.Context, id uint64) error
+	PurgeInvoked bool
+
+	ImportFn      func(ctx context.Context, suggs []teian.Suggestion, dryRun bool) ([]bool, error)
+	ImportInvoked bool
+
+	SetStatusFn      func(ctx context.Context, username string, id uint64, status teian.Status, by string) error
+	SetStatusInvoked bool
+
+	EditFn      func(ctx context.Context, username string, id uint64, text string) error
+	EditInvoked bool
+
+	RevisionsFn      func(ctx context.Context, id uint64) ([]teian.Revision, error)
+	RevisionsInvoked bool
+
+	RevertFn      func(ctx context.Context, id uint64, revision int, by string) error
+	RevertInvoked bool
+
+	AddReplyFn      func(ctx context.Context, username string, id uint64, reply *teian.Reply) error
+	AddReplyInvoked bool
+
+	SetCategoryFn      func(ctx context.Context, username string, id uint64, category string) error
+	SetCategoryInvoked bool
+
+	SimilarFn      func(ctx context.Context, text string, threshold float64) ([]teian.ScoredSuggestion, error)
+	SimilarInvoked bool
+
+	MergeFn      func(ctx context.Context, into, from uint64, by string) error
+	MergeInvoked bool
+
+	CategoriesFn      func(ctx context.Context) ([]string, error)
+	CategoriesInvoked bool
+
+	AddCategoryFn      func(ctx context.Context, name string) error
+	AddCategoryInvoked bool
+
+	RemoveCategoryFn      func(ctx context.Context, name string) error
+	RemoveCategoryInvoked bool
+
+	CategoryCountsFn      func(ctx context.Context) (map[string]int, error)
+	CategoryCountsInvoked bool
+
+	CheckQuotaFn       func(ctx context.Context, username string, n, limit teian.Quota) (teian.Quota, error)
+	CheckQuotaInvoked  bool
+	RefundQuotaFn      func(ctx context.Context, username string, n teian.Quota) error
+	RefundQuotaInvoked bool
+
+	QuotaOverrideFn      func(ctx context.Context, username string) (teian.Quota, error)
+	QuotaOverrideInvoked bool
+
+	SetQuotaOverrideFn      func(ctx context.Context, username string, limit teian.Quota) error
+	SetQuotaOverrideInvoked bool
+
+	RemoveQuotaOverrideFn      func(ctx context.Context, username string) error
+	RemoveQuotaOverrideInvoked bool
+
+	QuotaUsageFn      func(ctx context.Context) ([]teian.QuotaUsage, error)
+	QuotaUsageInvoked bool
 }
 
-func (s *SuggestionStore) Create(username string, sugg *teian.Suggestion) error {
+func (s *SuggestionStore) Create(ctx context.Context, username string, sugg *teian.Suggestion) error {
 	s.CreateInvoked = true
-	return s.CreateFn(username, sugg)
+	return s.CreateFn(ctx, username, sugg)
+}
+func (s *SuggestionStore) Get(ctx context.Context, id uint64) (*teian.Suggestion, error) {
+	s.GetInvoked = true
+	return s.GetFn(ctx, id)
+}
+func (s *SuggestionStore) GetMany(ctx context.Context, ids []uint64) ([]teian.Suggestion, map[uint64]error, error) {
+	s.GetManyInvoked = true
+	return s.GetManyFn(ctx, ids)
 }
-func (s *SuggestionStore) OfUser(username string) ([]teian.Suggestion, error) {
+
+func (s *SuggestionStore) OfUser(ctx context.Context, username string) ([]teian.Suggestion, error) {
 	s.OfUserInvoked = true
-	return s.OfUserFn(username)
+	return s.OfUserFn(ctx, username)
 }
-func (s *SuggestionStore) All() ([]teian.Suggestion, error) {
+func (s *SuggestionStore) All(ctx context.Context) ([]teian.Suggestion, error) {
 	s.AllInvoked = true
-	return s.AllFn()
+	return s.AllFn(ctx)
+}
+func (s *SuggestionStore) Search(ctx context.Context, query string) ([]teian.Suggestion, error) {
+	s.SearchInvoked = true
+	return s.SearchFn(ctx, query)
+}
+func (s *SuggestionStore) Query(ctx context.Context, q teian.Query) (*teian.Page, error) {
+	s.QueryInvoked = true
+	return s.QueryFn(ctx, q)
+}
+func (s *SuggestionStore) Vote(ctx context.Context, id uint64, username string, vote int) error {
+	s.VoteInvoked = true
+	return s.VoteFn(ctx, id, username, vote)
 }
-func (s *SuggestionStore) Delete(username string, id uint64) error {
+func (s *SuggestionStore) VotesOf(ctx context.Context, username string) (map[uint64]int, error) {
+	s.VotesOfInvoked = true
+	return s.VotesOfFn(ctx, username)
+}
+func (s *SuggestionStore) Delete(ctx context.Context, username string, id uint64, by string) error {
 	s.DeleteInvoked = true
-	return s.DeleteFn(username, id)
+	return s.DeleteFn(ctx, username, id, by)
+}
+func (s *SuggestionStore) Bulk(ctx context.Context, ids []uint64, op teian.BulkOp) (map[uint64]error, error) {
+	s.BulkInvoked = true
+	return s.BulkFn(ctx, ids, op)
 }
-func (s *SuggestionStore) CheckQuota(username string, n teian.Quota) (teian.Quota, error) {
+func (s *SuggestionStore) Trash(ctx context.Context) ([]teian.Suggestion, error) {
+	s.TrashInvoked = true
+	return s.TrashFn(ctx)
+}
+
+func (s *SuggestionStore) Trashed(ctx context.Context, id uint64) (*teian.Suggestion, error) {
+	s.TrashedInvoked = true
+	return s.TrashedFn(ctx, id)
+}
+func (s *SuggestionStore) Restore(ctx context.Context, id uint64) error {
+	s.RestoreInvoked = true
+	return s.RestoreFn(ctx, id)
+}
+func (s *SuggestionStore) Purge(ctx context.Context, id uint64) error {
+	s.PurgeInvoked = true
+	return s.PurgeFn(ctx, id)
+}
+func (s *SuggestionStore) Import(ctx context.Context, suggs []teian.Suggestion, dryRun bool) ([]bool, error) {
+	s.ImportInvoked = true
+	return s.ImportFn(ctx, suggs, dryRun)
+}
+func (s *SuggestionStore) CheckQuota(ctx context.Context, username string, n, limit teian.Quota) (teian.Quota, error) {
 	s.CheckQuotaInvoked = true
-	return s.CheckQuotaFn(username, n)
+	return s.CheckQuotaFn(ctx, username, n, limit)
+}
+
+func (s *SuggestionStore) RefundQuota(ctx context.Context, username string, n teian.Quota) error {
+	s.RefundQuotaInvoked = true
+	return s.RefundQuotaFn(ctx, username, n)
+}
+func (s *SuggestionStore) QuotaOverride(ctx context.Context, username string) (teian.Quota, error) {
+	s.QuotaOverrideInvoked = true
+	return s.QuotaOverrideFn(ctx, username)
+}
+func (s *SuggestionStore) SetQuotaOverride(ctx context.Context, username string, limit teian.Quota) error {
+	s.SetQuotaOverrideInvoked = true
+	return s.SetQuotaOverrideFn(ctx, username, limit)
+}
+func (s *SuggestionStore) RemoveQuotaOverride(ctx context.Context, username string) error {
+	s.RemoveQuotaOverrideInvoked = true
+	return s.RemoveQuotaOverrideFn(ctx, username)
+}
+func (s *SuggestionStore) QuotaUsage(ctx context.Context) ([]teian.QuotaUsage, error) {
+	s.QuotaUsageInvoked = true
+	return s.QuotaUsageFn(ctx)
+}
+func (s *SuggestionStore) SetStatus(ctx context.Context, username string, id uint64, status teian.Status, by string) error {
+	s.SetStatusInvoked = true
+	return s.SetStatusFn(ctx, username, id, status, by)
+}
+func (s *SuggestionStore) Edit(ctx context.Context, username string, id uint64, text string) error {
+	s.EditInvoked = true
+	return s.EditFn(ctx, username, id, text)
+}
+func (s *SuggestionStore) Revisions(ctx context.Context, id uint64) ([]teian.Revision, error) {
+	s.RevisionsInvoked = true
+	return s.RevisionsFn(ctx, id)
+}
+func (s *SuggestionStore) Revert(ctx context.Context, id uint64, revision int, by string) error {
+	s.RevertInvoked = true
+	return s.RevertFn(ctx, id, revision, by)
+}
+func (s *SuggestionStore) AddReply(ctx context.Context, username string, id uint64, reply *teian.Reply) error {
+	s.AddReplyInvoked = true
+	return s.AddReplyFn(ctx, username, id, reply)
+}
+func (s *SuggestionStore) SetCategory(ctx context.Context, username string, id uint64, category string) error {
+	s.SetCategoryInvoked = true
+	return s.SetCategoryFn(ctx, username, id, category)
+}
+func (s *SuggestionStore) Similar(ctx context.Context, text string, threshold float64) ([]teian.ScoredSuggestion, error) {
+	s.SimilarInvoked = true
+	return s.SimilarFn(ctx, text, threshold)
+}
+func (s *SuggestionStore) Merge(ctx context.Context, into, from uint64, by string) error {
+	s.MergeInvoked = true
+	return s.MergeFn(ctx, into, from, by)
+}
+func (s *SuggestionStore) Categories(ctx context.Context) ([]string, error) {
+	s.CategoriesInvoked = true
+	return s.CategoriesFn(ctx)
+}
+func (s *SuggestionStore) AddCategory(ctx context.Context, name string) error {
+	s.AddCategoryInvoked = true
+	return s.AddCategoryFn(ctx, name)
+}
+func (s *SuggestionStore) RemoveCategory(ctx context.Context, name string) error {
+	s.RemoveCategoryInvoked = true
+	return s.RemoveCategoryFn(ctx, name)
+}
+func (s *SuggestionStore) CategoryCounts(ctx context.Context) (map[string]int, error) {
+	s.CategoryCountsInvoked = true
+	return s.CategoryCountsFn(ctx)
 }
@@ -0,0 +1,1190 @@
+// Package storetest holds the conformance tests that every implementation of
+// teian.SuggestionStore runs to prove that they behave the same.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+// Quota is the upload quota the tests check users against.
+const Quota = 10 << 20 // 10 MB
+
+// Store is a suggestion store under test.
+type Store interface {
+	teian.SuggestionStore
+}
+
+// Opener returns an empty store and a function that releases it. It is
+// called once for every test.
+type Opener func(t *testing.T) (store Store, close func())
+
+var tests = []struct {
+	name string
+	fn   func(ctx context.Context, t *testing.T, s Store)
+}{
+	{"Create", testCreate},
+	{"Copies", testCopies},
+	{"OfUser_All", testOfUserAll},
+	{"SetStatus", testSetStatus},
+	{"AddReply", testAddReply},
+	{"Revisions", testRevisions},
+	{"Vote", testVote},
+	{"Query", testQuery},
+	{"Query_pages", testQueryPages},
+	{"Search", testSearch},
+	{"Similar_Merge", testSimilarMerge},
+	{"Categories", testCategories},
+	{"Trash", testTrash},
+	{"Bulk", testBulk},
+	{"Import", testImport},
+	{"Quota", testQuota},
+	{"QuotaOverrides", testQuotaOverrides},
+	{"Concurrency", testConcurrency},
+	{"Canceled", testCanceled},
+}
+
+// Run runs every conformance test on a new store returned by open.
+func Run(t *testing.T, open Opener) {
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			s, close := open(t)
+			defer close()
+			tt.fn(context.Background(), t, s)
+		})
+	}
+}
+
+// normalize returns s with its times in UTC and without monotonic clock
+// readings so that suggestions from different stores can be compared with
+// reflect.DeepEqual.
+func normalize(s teian.Suggestion) teian.Suggestion {
+	s.Created = normalTime(s.Created)
+	s.DeletedAt = normalTime(s.DeletedAt)
+	if s.History != nil {
+		s.History = append([]teian.StatusChange(nil), s.History...)
+		for i := range s.History {
+			s.History[i].At = normalTime(s.History[i].At)
+		}
+	}
+	if s.Replies != nil {
+		s.Replies = append([]teian.Reply(nil), s.Replies...)
+		for i := range s.Replies {
+			s.Replies[i].Created = normalTime(s.Replies[i].Created)
+		}
+	}
+	return s
+}
+
+func normalTime(t time.Time) time.Time {
+	if t.IsZero() {
+		return time.Time{}
+	}
+	return t.Round(0).UTC()
+}
+
+func ids(suggs []teian.Suggestion) []uint64 {
+	var ids []uint64
+	for _, s := range suggs {
+		ids = append(ids, s.ID)
+	}
+	return ids
+}
+
+func mustCreate(ctx context.Context, t *testing.T, s Store, username string, sugg *teian.Suggestion) {
+	t.Helper()
+	if err := s.Create(ctx, username, sugg); err != nil {
+		t.Fatalf("store.Create(%q, %q) failed: %v", username, sugg.Text, err)
+	}
+}
+
+func mustGet(ctx context.Context, t *testing.T, s Store, id uint64) *teian.Suggestion {
+	t.Helper()
+	sugg, err := s.Get(ctx, id)
+	if err != nil {
+		t.Fatalf("store.Get(%d) failed: %v", id, err)
+	}
+	return sugg
+}
+
+func testCreate(ctx context.Context, t *testing.T, s Store) {
+	if err := s.AddCategory(ctx, "ui"); err != nil {
+		t.Fatal("store.AddCategory failed:", err)
+	}
+	sugg := &teian.Suggestion{
+		ID:          42,
+		Username:    "someone else",
+		Text:        "my first suggestion",
+		Created:     time.Date(2001, 1, 1, 0, 0, 0, 0, time.UTC),
+		Public:      true,
+		Anonymous:   true,
+		Category:    "ui",
+		Tags:        []string{"cat", "dog"},
+		History:     []teian.StatusChange{},
+		Attachments: []teian.Attachment{{Name: "a.png", Thumb: "a_thumb.png", ContentType: "image/png", Size: 42}},
+	}
+	before := time.Now().Add(-time.Second)
+	mustCreate(ctx, t, s, "john", sugg)
+
+	// The store gives the ID, username and creation time.
+	if sugg.ID != 1 || sugg.Username != "john" || sugg.Created.Before(before) {
+		t.Errorf("store.Create set ID %d, username %q and created %v, want 1, john and now", sugg.ID, sugg.Username, sugg.Created)
+	}
+	want := teian.Suggestion{
+		ID:          1,
+		Username:    "john",
+		Text:        "my first suggestion",
+		Created:     sugg.Created,
+		Public:      true,
+		Anonymous:   true,
+		Category:    "ui",
+		Tags:        []string{"cat", "dog"},
+		Attachments: sugg.Attachments,
+	}
+	if got := mustGet(ctx, t, s, 1); !reflect.DeepEqual(normalize(*got), normalize(want)) {
+		t.Errorf("store.Get after Create = \n%#v, want \n%#v", normalize(*got), normalize(want))
+	}
+
+	next := &teian.Suggestion{Text: "second"}
+	mustCreate(ctx, t, s, "mary", next)
+	if next.ID != 2 {
+		t.Errorf("second store.Create assigned ID %d, want 2", next.ID)
+	}
+	if _, err := s.Get(ctx, 3); !errors.Is(err, teian.ErrNotFound) {
+		t.Errorf("store.Get of missing suggestion returned %v, want %v", err, teian.ErrNotFound)
+	}
+	if err := s.Create(ctx, "john", &teian.Suggestion{Text: "x", Category: "nope"}); err != teian.ErrUnknownCategory {
+		t.Errorf("store.Create with unknown category returned %v, want %v", err, teian.ErrUnknownCategory)
+	}
+	// A failed create does not use an ID.
+	mustCreate(ctx, t, s, "john", next)
+	if next.ID != 3 {
+		t.Errorf("store.Create after failed create assigned ID %d, want 3", next.ID)
+	}
+}
+
+func testCopies(ctx context.Context, t *testing.T, s Store) {
+	sugg := &teian.Suggestion{Text: "text", Tags: []string{"a"}}
+	mustCreate(ctx, t, s, "john", sugg)
+
+	// Changing what was passed to or returned by the store does not change
+	// the store.
+	sugg.Text = "changed"
+	sugg.Tags[0] = "changed"
+	got := mustGet(ctx, t, s, 1)
+	got.Tags[0] = "changed again"
+	all, err := s.All(ctx)
+	if err != nil {
+		t.Fatal("store.All failed:", err)
+	}
+	all[0].Text = "changed again"
+	if got := mustGet(ctx, t, s, 1); got.Text != "text" || !reflect.DeepEqual(got.Tags, []string{"a"}) {
+		t.Errorf("store.Get after changing copies = %q %q, want %q %q", got.Text, got.Tags, "text", []string{"a"})
+	}
+}
+
+func testOfUserAll(ctx context.Context, t *testing.T, s Store) {
+	for i := 1; i <= 5; i++ {
+		username := "mary"
+		if i%2 == 0 {
+			username = "john"
+		}
+		mustCreate(ctx, t, s, username, &teian.Suggestion{Text: fmt.Sprintf("suggestion #%d", i)})
+	}
+	if err := s.Delete(ctx, "john", 4, "john"); err != nil {
+		t.Fatal("store.Delete failed:", err)
+	}
+	if err := s.Delete(ctx, "john", 3, "john"); !errors.Is(err, teian.ErrNotFound) {
+		t.Errorf("store.Delete of suggestion of other user returned %v, want %v", err, teian.ErrNotFound)
+	}
+
+	for username, want := range map[string][]uint64{"john": {2}, "mary": {1, 3, 5}, "bob": nil} {
+		got, err := s.OfUser(ctx, username)
+		if err != nil {
+			t.Fatalf("store.OfUser(%q) failed: %v", username, err)
+		}
+		if !reflect.DeepEqual(ids(got), want) {
+			t.Errorf("store.OfUser(%q) returned IDs %v, want %v", username, ids(got), want)
+		}
+	}
+	all, err := s.All(ctx)
+	if err != nil {
+		t.Fatal("store.All failed:", err)
+	}
+	if got, want := ids(all), []uint64{1, 2, 3, 5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("store.All returned IDs %v, want %v", got, want)
+	}
+}
+
+func testSetStatus(ctx context.Context, t *testing.T, s Store) {
+	mustCreate(ctx, t, s, "john", &teian.Suggestion{Text: "text"})
+	for _, status := range []teian.Status{teian.StatusPlanned, teian.StatusPlanned, teian.StatusDone} {
+		if err := s.SetStatus(ctx, "john", 1, status, "admin"); err != nil {
+			t.Fatal("store.SetStatus failed:", err)
+		}
+	}
+	if err := s.SetStatus(ctx, "mary", 1, teian.StatusRejected, "admin"); !errors.Is(err, teian.ErrNotFound) {
+		t.Errorf("store.SetStatus of suggestion of other user returned %v, want %v", err, teian.ErrNotFound)
+	}
+	got := mustGet(ctx, t, s, 1)
+	if got.Status != teian.StatusDone {
+		t.Errorf("status = %v, want %v", got.Status, teian.StatusDone)
+	}
+	if len(got.History) != 2 {
+		t.Fatalf("history = %v, want 2 changes", got.History)
+	}
+	for i, want := range []teian.StatusChange{
+		{From: teian.StatusNew, To: teian.StatusPlanned, By: "admin"},
+		{From: teian.StatusPlanned, To: teian.StatusDone, By: "admin"},
+	} {
+		c := got.History[i]
+		if c.At.IsZero() {
+			t.Errorf("history change %d has no time", i)
+		}
+		c.At = time.Time{}
+		if c != want {
+			t.Errorf("history change %d = %+v, want %+v", i, c, want)
+		}
+	}
+}
+
+func testAddReply(ctx context.Context, t *testing.T, s Store) {
+	mustCreate(ctx, t, s, "john", &teian.Suggestion{Text: "text"})
+	reply := &teian.Reply{Username: "admin", Text: "thanks"}
+	if err := s.AddReply(ctx, "john", 1, reply); err != nil {
+		t.Fatal("store.AddReply failed:", err)
+	}
+	if reply.Created.IsZero() {
+		t.Error("store.AddReply did not set the reply time")
+	}
+	if err := s.AddReply(ctx, "mary", 1, &teian.Reply{Text: "no"}); !errors.Is(err, teian.ErrNotFound) {
+		t.Errorf("store.AddReply to suggestion of other user returned %v, want %v", err, teian.ErrNotFound)
+	}
+	got := mustGet(ctx, t, s, 1)
+	want := []teian.Reply{{Username: "admin", Text: "thanks", Created: normalTime(reply.Created)}}
+	if !reflect.DeepEqual(normalize(*got).Replies, want) {
+		t.Errorf("replies = %v, want %v", got.Replies, want)
+	}
+}
+
+func revisionTexts(revs []teian.Revision) []string {
+	var texts []string
+	for _, r := range revs {
+		texts = append(texts, r.By+": "+r.Text)
+	}
+	return texts
+}
+
+func testRevisions(ctx context.Context, t *testing.T, s Store) {
+	sugg := &teian.Suggestion{Text: "one"}
+	mustCreate(ctx, t, s, "john", sugg)
+	for _, text := range []string{"two", "two"} {
+		if err := s.Edit(ctx, "john", 1, text); err != nil {
+			t.Fatal("store.Edit failed:", err)
+		}
+	}
+	if err := s.Edit(ctx, "mary", 1, "three"); !errors.Is(err, teian.ErrNotFound) {
+		t.Errorf("store.Edit of suggestion of other user returned %v, want %v", err, teian.ErrNotFound)
+	}
+	if err := s.Revert(ctx, 1, 0, "admin"); err != nil {
+		t.Fatal("store.Revert failed:", err)
+	}
+	for _, rev := range []int{-1, 3} {
+		if err := s.Revert(ctx, 1, rev, "admin"); !errors.Is(err, teian.ErrNotFound) {
+			t.Errorf("store.Revert to revision %d returned %v, want %v", rev, err, teian.ErrNotFound)
+		}
+	}
+	revs, err := s.Revisions(ctx, 1)
+	if err != nil {
+		t.Fatal("store.Revisions failed:", err)
+	}
+	if got, want := revisionTexts(revs), []string{"john: one", "john: two", "admin: one"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("store.Revisions = %q, want %q", got, want)
+	}
+	if !revs[0].At.Equal(sugg.Created) {
+		t.Errorf("first revision at %v, want the creation time %v", revs[0].At, sugg.Created)
+	}
+	if got := mustGet(ctx, t, s, 1); got.Text != "one" {
+		t.Errorf("text after revert = %q, want %q", got.Text, "one")
+	}
+	if _, err := s.Revisions(ctx, 2); !errors.Is(err, teian.ErrNotFound) {
+		t.Errorf("store.Revisions of missing suggestion returned %v, want %v", err, teian.ErrNotFound)
+	}
+}
+
+func testVote(ctx context.Context, t *testing.T, s Store) {
+	mustCreate(ctx, t, s, "john", &teian.Suggestion{Text: "text", Public: true})
+	steps := []struct {
+		username string
+		vote     int
+		up, down int
+	}{
+		{"mary", 1, 1, 0},
+		{"mary", 1, 1, 0},
+		{"bob", -1, 1, 1},
+		{"mary", -1, 0, 2},
+		{"bob", 0, 0, 1},
+		{"bob", 0, 0, 1},
+	}
+	for _, st := range steps {
+		if err := s.Vote(ctx, 1, st.username, st.vote); err != nil {
+			t.Fatal("store.Vote failed:", err)
+		}
+		got := mustGet(ctx, t, s, 1)
+		if got.Upvotes != st.up || got.Downvotes != st.down {
+			t.Errorf("after vote %d by %s got %d up %d down, want %d up %d down", st.vote, st.username, got.Upvotes, got.Downvotes, st.up, st.down)
+		}
+	}
+	if err := s.Vote(ctx, 1, "mary", 2); err != teian.ErrBadVote {
+		t.Errorf("store.Vote(1, mary, 2) returned %v, want %v", err, teian.ErrBadVote)
+	}
+	if err := s.Vote(ctx, 9, "mary", 1); !errors.Is(err, teian.ErrNotFound) {
+		t.Errorf("store.Vote on missing suggestion returned %v, want %v", err, teian.ErrNotFound)
+	}
+
+	// Private suggestions can only be voted by their owner and closed ones
+	// by no one.
+	mustCreate(ctx, t, s, "john", &teian.Suggestion{Text: "private"})
+	if err := s.Vote(ctx, 2, "mary", 1); !errors.Is(err, teian.ErrNotFound) {
+		t.Errorf("store.Vote on private suggestion of another user returned %v, want %v", err, teian.ErrNotFound)
+	}
+	if err := s.Vote(ctx, 2, "john", 1); err != nil {
+		t.Error("store.Vote on own private suggestion failed:", err)
+	}
+	if err := s.SetStatus(ctx, "", 1, teian.StatusDone, "admin"); err != nil {
+		t.Fatal("store.SetStatus failed:", err)
+	}
+	if err := s.Vote(ctx, 1, "bob", 1); !errors.Is(err, teian.ErrConflict) {
+		t.Errorf("store.Vote on closed suggestion returned %v, want %v", err, teian.ErrConflict)
+	}
+	if got := mustGet(ctx, t, s, 1); got.Upvotes != 0 || got.Downvotes != 1 {
+		t.Errorf("vote on closed suggestion changed it to %d up %d down", got.Upvotes, got.Downvotes)
+	}
+
+	for username, want := range map[string]map[uint64]int{"mary": {1: -1}, "bob": {}, "john": {2: 1}} {
+		got, err := s.VotesOf(ctx, username)
+		if err != nil {
+			t.Fatal("store.VotesOf failed:", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("store.VotesOf(%q) = %v, want %v", username, got, want)
+		}
+	}
+}
+
+func testQuery(ctx context.Context, t *testing.T, s Store) {
+	if err := s.AddCategory(ctx, "ui"); err != nil {
+		t.Fatal("store.AddCategory failed:", err)
+	}
+	// IDs 1-7 alternate between mary and john. 7 is deleted.
+	for i := 1; i <= 7; i++ {
+		username := "mary"
+		if i%2 == 0 {
+			username = "john"
+		}
+		category := ""
+		if i%3 == 0 {
+			category = "ui"
+		}
+		mustCreate(ctx, t, s, username, &teian.Suggestion{Text: fmt.Sprintf("suggestion #%d", i), Public: i <= 3, Category: category})
+	}
+	if err := s.Delete(ctx, "", 7, "admin"); err != nil {
+		t.Fatal("store.Delete failed:", err)
+	}
+	if err := s.SetStatus(ctx, "john", 4, teian.StatusDone, "admin"); err != nil {
+		t.Fatal("store.SetStatus failed:", err)
+	}
+	// Most of the suggestions are private so their owners vote on them.
+	for id, vote := range map[uint64]int{2: 1, 3: -1, 5: 1} {
+		username := "mary"
+		if id%2 == 0 {
+			username = "john"
+		}
+		if err := s.Vote(ctx, id, username, vote); err != nil {
+			t.Fatal("store.Vote failed:", err)
+		}
+	}
+
+	from := time.Now().Add(time.Hour)
+	tests := []struct {
+		q    teian.Query
+		want []uint64
+	}{
+		{teian.Query{}, []uint64{6, 5, 4, 3, 2, 1}},
+		{teian.Query{Order: teian.OrderDateDesc}, []uint64{6, 5, 4, 3, 2, 1}},
+		{teian.Query{Order: teian.OrderDateAsc}, []uint64{1, 2, 3, 4, 5, 6}},
+		{teian.Query{Order: teian.OrderUserAsc}, []uint64{2, 4, 6, 1, 3, 5}},
+		{teian.Query{Order: teian.OrderUserDesc}, []uint64{5, 3, 1, 6, 4, 2}},
+		{teian.Query{Order: teian.OrderVotesAsc}, []uint64{3, 1, 4, 6, 2, 5}},
+		{teian.Query{Order: teian.OrderVotesDesc}, []uint64{5, 2, 6, 4, 1, 3}},
+		{teian.Query{Username: "ar"}, []uint64{5, 3, 1}},
+		{teian.Query{Text: "#2"}, []uint64{2}},
+		{teian.Query{Public: true}, []uint64{3, 2, 1}},
+		{teian.Query{Category: "ui"}, []uint64{6, 3}},
+		{teian.Query{Statuses: []teian.Status{teian.StatusDone}}, []uint64{4}},
+		{teian.Query{Statuses: teian.OpenStatuses()}, []uint64{6, 5, 3, 2, 1}},
+		{teian.Query{Since: from}, nil},
+		{teian.Query{Until: from}, []uint64{6, 5, 4, 3, 2, 1}},
+		{teian.Query{Limit: 2}, []uint64{6, 5}},
+		{teian.Query{Limit: 2, Order: teian.OrderUserAsc, Username: "mary"}, []uint64{1, 3}},
+	}
+	for _, tt := range tests {
+		page, err := s.Query(ctx, tt.q)
+		if err != nil {
+			t.Fatalf("store.Query(%+v) failed: %v", tt.q, err)
+		}
+		if got := ids(page.Suggestions); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("store.Query(%+v) returned IDs %v, want %v", tt.q, got, tt.want)
+		}
+		if page.Prev != "" {
+			t.Errorf("store.Query(%+v) returned prev cursor %q on the first page", tt.q, page.Prev)
+		}
+	}
+	if _, err := s.Query(ctx, teian.Query{Cursor: "!"}); !errors.Is(err, teian.ErrInvalid) {
+		t.Errorf("store.Query with bad cursor returned %v, want %v", err, teian.ErrInvalid)
+	}
+}
+
+func testQueryPages(ctx context.Context, t *testing.T, s Store) {
+	n := 130
+	for i := 1; i <= n; i++ {
+		username := fmt.Sprintf("user%d", i%7)
+		mustCreate(ctx, t, s, username, &teian.Suggestion{Text: fmt.Sprintf("suggestion #%d", i)})
+		if err := s.Vote(ctx, uint64(i), username, i%3-1); err != nil {
+			t.Fatal("store.Vote failed:", err)
+		}
+	}
+	orders := []teian.Order{
+		teian.OrderDateDesc, teian.OrderDateAsc,
+		teian.OrderUserDesc, teian.OrderUserAsc,
+		teian.OrderVotesDesc, teian.OrderVotesAsc,
+	}
+	for _, order := range orders {
+		all, err := s.Query(ctx, teian.Query{Order: order})
+		if err != nil {
+			t.Fatalf("store.Query order %q failed: %v", order, err)
+		}
+		if len(all.Suggestions) != n || all.Next != "" {
+			t.Fatalf("store.Query order %q returned %d suggestions and next %q, want %d and none", order, len(all.Suggestions), all.Next, n)
+		}
+
+		// Walk forwards page by page and then back.
+		q := teian.Query{Order: order, Limit: 9, Text: "1"}
+		var want []uint64
+		for _, sugg := range all.Suggestions {
+			if q.Match(&sugg) {
+				want = append(want, sugg.ID)
+			}
+		}
+		var got []uint64
+		var pages []*teian.Page
+		for {
+			page, err := s.Query(ctx, q)
+			if err != nil {
+				t.Fatalf("store.Query(%+v) failed: %v", q, err)
+			}
+			pages = append(pages, page)
+			got = append(got, ids(page.Suggestions)...)
+			if page.Next == "" {
+				break
+			}
+			q.Cursor = page.Next
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("paging order %q returned IDs %v, want %v", order, got, want)
+		}
+		for i := len(pages) - 1; i > 0; i-- {
+			q.Cursor = pages[i].Prev
+			prev, err := s.Query(ctx, q)
+			if err != nil {
+				t.Fatalf("store.Query(%+v) failed: %v", q, err)
+			}
+			if got, want := ids(prev.Suggestions), ids(pages[i-1].Suggestions); !reflect.DeepEqual(got, want) {
+				t.Errorf("order %q page %d has previous page %v, want %v", order, i, got, want)
+			}
+		}
+	}
+
+	// A page whose first suggestion was deleted starts at the one that
+	// followed it.
+	q := teian.Query{Limit: 3}
+	page, err := s.Query(ctx, q)
+	if err != nil {
+		t.Fatal("store.Query failed:", err)
+	}
+	if err := s.Delete(ctx, "", page.Suggestions[len(page.Suggestions)-1].ID-1, "admin"); err != nil {
+		t.Fatal("store.Delete failed:", err)
+	}
+	q.Cursor = page.Next
+	next, err := s.Query(ctx, q)
+	if err != nil {
+		t.Fatal("store.Query failed:", err)
+	}
+	if got, want := ids(next.Suggestions), []uint64{uint64(n - 4), uint64(n - 5), uint64(n - 6)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("page after deleting its first suggestion = %v, want %v", got, want)
+	}
+}
+
+func testSearch(ctx context.Context, t *testing.T, s Store) {
+	texts := []string{
+		"add a dark theme",
+		"dark mode for the board",
+		"faster uploads",
+		"a dark theme and a dark board",
+		"Dárk theme",
+	}
+	for _, text := range texts {
+		mustCreate(ctx, t, s, "john", &teian.Suggestion{Text: text})
+	}
+	if err := s.Delete(ctx, "john", 2, "john"); err != nil {
+		t.Fatal("store.Delete failed:", err)
+	}
+	tests := []struct {
+		query string
+		want  []uint64
+	}{
+		{"dark", []uint64{4, 5, 1}},
+		{"dark theme -add", []uint64{4, 5}},
+		{`"dark board"`, []uint64{4}},
+		{"uploads", []uint64{3}},
+		{"mode", []uint64{}},
+		{"-dark", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		found, err := s.Search(ctx, tt.query)
+		if err != nil {
+			t.Fatalf("store.Search(%q) failed: %v", tt.query, err)
+		}
+		got := ids(found)
+		if len(got) == 0 && len(tt.want) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("store.Search(%q) returned IDs %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func testSimilarMerge(ctx context.Context, t *testing.T, s Store) {
+	for _, text := range []string{"add a dark theme", "dark theme please", "faster uploads", "dark theme"} {
+		mustCreate(ctx, t, s, "john", &teian.Suggestion{Text: text, Public: true})
+	}
+	if err := s.Delete(ctx, "john", 4, "john"); err != nil {
+		t.Fatal("store.Delete failed:", err)
+	}
+	similar, err := s.Similar(ctx, "a dark theme", 0.3)
+	if err != nil {
+		t.Fatal("store.Similar failed:", err)
+	}
+	var got []uint64
+	for _, sugg := range similar {
+		got = append(got, sugg.ID)
+	}
+	if want := []uint64{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("store.Similar returned IDs %v, want %v", got, want)
+	}
+
+	// mary voted on both so only bob's vote moves.
+	for _, v := range []struct {
+		id       uint64
+		username string
+		vote     int
+	}{{1, "mary", 1}, {2, "mary", -1}, {2, "bob", 1}} {
+		if err := s.Vote(ctx, v.id, v.username, v.vote); err != nil {
+			t.Fatal("store.Vote failed:", err)
+		}
+	}
+	if err := s.SetStatus(ctx, "john", 2, teian.StatusPlanned, "admin"); err != nil {
+		t.Fatal("store.SetStatus failed:", err)
+	}
+	if err := s.Merge(ctx, 1, 2, "admin"); err != nil {
+		t.Fatal("store.Merge failed:", err)
+	}
+	into, from := mustGet(ctx, t, s, 1), mustGet(ctx, t, s, 2)
+	if into.Upvotes != 2 || into.Downvotes != 0 || !reflect.DeepEqual(into.Merged, []uint64{2}) || len(into.History) != 1 || into.History[0].Merged != 2 || into.Status != teian.StatusNew {
+		t.Errorf("merged into suggestion has %d up %d down merged %v history %v status %v, want 2 up 0 down merged [2], only the merge in its history and new", into.Upvotes, into.Downvotes, into.Merged, into.History, into.Status)
+	}
+	if len(from.History) != 2 || from.History[0].To != teian.StatusPlanned {
+		t.Errorf("duplicate history = %v, want the planned change and the merge", from.History)
+	}
+	if from.Upvotes != 0 || from.Downvotes != 0 || from.MergedInto != 1 || from.Status != teian.StatusDuplicate {
+		t.Errorf("duplicate has %d up %d down merged into %d status %v, want no votes merged into 1 and duplicate", from.Upvotes, from.Downvotes, from.MergedInto, from.Status)
+	}
+	for username, want := range map[string]map[uint64]int{"mary": {1: 1}, "bob": {1: 1}} {
+		votes, err := s.VotesOf(ctx, username)
+		if err != nil {
+			t.Fatal("store.VotesOf failed:", err)
+		}
+		if !reflect.DeepEqual(votes, want) {
+			t.Errorf("store.VotesOf(%q) after merge = %v, want %v", username, votes, want)
+		}
+	}
+	page, err := s.Query(ctx, teian.Query{Order: teian.OrderVotesDesc, Limit: 1})
+	if err != nil {
+		t.Fatal("store.Query failed:", err)
+	}
+	if got, want := ids(page.Suggestions), []uint64{1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("store.Query by votes after merge = %v, want %v", got, want)
+	}
+
+	if err := s.Merge(ctx, 3, 2, "admin"); !errors.Is(err, teian.ErrConflict) {
+		t.Errorf("store.Merge of merged suggestion returned %v, want %v", err, teian.ErrConflict)
+	}
+	// Merging back would make a cycle and merging into a merged suggestion
+	// a chain.
+	for _, from := range []uint64{1, 3} {
+		if err := s.Merge(ctx, 2, from, "admin"); !errors.Is(err, teian.ErrConflict) {
+			t.Errorf("store.Merge(2, %d) into merged suggestion returned %v, want %v", from, err, teian.ErrConflict)
+		}
+	}
+	// Merging a suggestion that has duplicates would make a chain too.
+	if err := s.Merge(ctx, 3, 1, "admin"); !errors.Is(err, teian.ErrConflict) {
+		t.Errorf("store.Merge(3, 1) of suggestion with duplicates returned %v, want %v", err, teian.ErrConflict)
+	}
+	if got := mustGet(ctx, t, s, 1); got.MergedInto != 0 || got.Status == teian.StatusDuplicate {
+		t.Errorf("suggestion 1 after merge into its duplicate = %+v, want unchanged", got)
+	}
+	if err := s.Merge(ctx, 1, 1, "admin"); !errors.Is(err, teian.ErrInvalid) {
+		t.Errorf("store.Merge into itself returned %v, want %v", err, teian.ErrInvalid)
+	}
+	if err := s.Merge(ctx, 1, 4, "admin"); !errors.Is(err, teian.ErrNotFound) {
+		t.Errorf("store.Merge of deleted suggestion returned %v, want %v", err, teian.ErrNotFound)
+	}
+}
+
+func testCategories(ctx context.Context, t *testing.T, s Store) {
+	names, err := s.Categories(ctx)
+	if err != nil {
+		t.Fatal("store.Categories failed:", err)
+	}
+	if len(names) != 0 {
+		t.Errorf("store.Categories of empty store = %q, want none", names)
+	}
+	for _, name := range []string{"ui", " api ", "ui", "Bugs"} {
+		if err := s.AddCategory(ctx, name); err != nil {
+			t.Fatalf("store.AddCategory(%q) failed: %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "  ", "a\x00b"} {
+		if err := s.AddCategory(ctx, name); !errors.Is(err, teian.ErrInvalid) {
+			t.Errorf("store.AddCategory(%q) returned %v, want %v", name, err, teian.ErrInvalid)
+		}
+	}
+	names, err = s.Categories(ctx)
+	if err != nil {
+		t.Fatal("store.Categories failed:", err)
+	}
+	if want := []string{"Bugs", "api", "ui"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("store.Categories = %q, want %q", names, want)
+	}
+
+	for i := 0; i < 3; i++ {
+		mustCreate(ctx, t, s, "john", &teian.Suggestion{Text: "text", Category: "ui"})
+	}
+	if err := s.SetCategory(ctx, "john", 3, "api"); err != nil {
+		t.Fatal("store.SetCategory failed:", err)
+	}
+	if err := s.SetCategory(ctx, "john", 3, "nope"); err != teian.ErrUnknownCategory {
+		t.Errorf("store.SetCategory with unknown category returned %v, want %v", err, teian.ErrUnknownCategory)
+	}
+	if err := s.SetCategory(ctx, "mary", 3, "ui"); !errors.Is(err, teian.ErrNotFound) {
+		t.Errorf("store.SetCategory of suggestion of other user returned %v, want %v", err, teian.ErrNotFound)
+	}
+	if err := s.Delete(ctx, "john", 2, "john"); err != nil {
+		t.Fatal("store.Delete failed:", err)
+	}
+	counts, err := s.CategoryCounts(ctx)
+	if err != nil {
+		t.Fatal("store.CategoryCounts failed:", err)
+	}
+	if want := map[string]int{"ui": 1, "api": 1}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("store.CategoryCounts = %v, want %v", counts, want)
+	}
+
+	if err := s.RemoveCategory(ctx, "ui"); err != nil {
+		t.Fatal("store.RemoveCategory failed:", err)
+	}
+	if err := s.RemoveCategory(ctx, "ui"); !errors.Is(err, teian.ErrNotFound) {
+		t.Errorf("store.RemoveCategory of missing category returned %v, want %v", err, teian.ErrNotFound)
+	}
+	if got := mustGet(ctx, t, s, 1); got.Category != "" {
+		t.Errorf("suggestion of removed category has category %q, want none", got.Category)
+	}
+	// The trash is left untouched.
+	trash, err := s.Trash(ctx)
+	if err != nil {
+		t.Fatal("store.Trash failed:", err)
+	}
+	if len(trash) != 1 || trash[0].Category != "ui" {
+		t.Errorf("store.Trash after removing category = %v, want suggestion 2 still in ui", trash)
+	}
+	counts, err = s.CategoryCounts(ctx)
+	if err != nil {
+		t.Fatal("store.CategoryCounts failed:", err)
+	}
+	if want := map[string]int{"api": 1}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("store.CategoryCounts after remove = %v, want %v", counts, want)
+	}
+}
+
+func testTrash(ctx context.Context, t *testing.T, s Store) {
+	for i := 0; i < 3; i++ {
+		mustCreate(ctx, t, s, "john", &teian.Suggestion{Text: "text", Public: true})
+	}
+	if err := s.Vote(ctx, 1, "mary", 1); err != nil {
+		t.Fatal("store.Vote failed:", err)
+	}
+	if err := s.Edit(ctx, "john", 1, "edited"); err != nil {
+		t.Fatal("store.Edit failed:", err)
+	}
+	for _, id := range []uint64{1, 2} {
+		if err := s.Delete(ctx, "", id, "admin"); err != nil {
+			t.Fatal("store.Delete failed:", err)
+		}
+		// Make sure the deletion times differ.
+		time.Sleep(2 * time.Millisecond)
+	}
+	if err := s.Delete(ctx, "", 1, "admin"); !errors.Is(err, teian.ErrNotFound) {
+		t.Errorf("store.Delete of deleted suggestion returned %v, want %v", err, teian.ErrNotFound)
+	}
+
+	trash, err := s.Trash(ctx)
+	if err != nil {
+		t.Fatal("store.Trash failed:", err)
+	}
+	if got, want := ids(trash), []uint64{2, 1}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("store.Trash returned IDs %v, want %v", got, want)
+	}
+	if trash[0].DeletedBy != "admin" || trash[0].DeletedAt.IsZero() {
+		t.Errorf("store.Trash returned deleted by %q at %v, want admin and a time", trash[0].DeletedBy, trash[0].DeletedAt)
+	}
+	if _, err := s.Get(ctx, 1); !errors.Is(err, teian.ErrNotFound) {
+		t.Errorf("store.Get of deleted suggestion returned %v, want %v", err, teian.ErrNotFound)
+	}
+	if got, err := s.Trashed(ctx, 2); err != nil || !reflect.DeepEqual(normalize(*got), normalize(trash[0])) {
+		t.Errorf("store.Trashed(2) = %+v, %v, want %+v", got, err, trash[0])
+	}
+	for _, id := range []uint64{3, 4} {
+		if _, err := s.Trashed(ctx, id); !errors.Is(err, teian.ErrNotFound) {
+			t.Errorf("store.Trashed(%d) of suggestion not in the trash returned %v, want %v", id, err, teian.ErrNotFound)
+		}
+	}
+	if _, err := s.Revisions(ctx, 1); !errors.Is(err, teian.ErrNotFound) {
+		t.Errorf("store.Revisions of deleted suggestion returned %v, want %v", err, teian.ErrNotFound)
+	}
+	// Votes are kept while the suggestion is in the trash.
+	if votes, err := s.VotesOf(ctx, "mary"); err != nil || !reflect.DeepEqual(votes, map[uint64]int{1: 1}) {
+		t.Errorf("store.VotesOf with deleted suggestion = %v, %v, want its vote", votes, err)
+	}
+
+	if err := s.Restore(ctx, 1); err != nil {
+		t.Fatal("store.Restore failed:", err)
+	}
+	restored := mustGet(ctx, t, s, 1)
+	if restored.DeletedBy != "" || !restored.DeletedAt.IsZero() || restored.Upvotes != 1 || restored.Text != "edited" {
+		t.Errorf("store.Restore lead to %#v, want the edited suggestion with its vote", restored)
+	}
+	if revs, err := s.Revisions(ctx, 1); err != nil || len(revs) != 2 {
+		t.Errorf("store.Revisions of restored suggestion = %v, %v, want 2 revisions", revs, err)
+	}
+	page, err := s.Query(ctx, teian.Query{Order: teian.OrderVotesDesc, Limit: 1})
+	if err != nil {
+		t.Fatal("store.Query failed:", err)
+	}
+	if got, want := ids(page.Suggestions), []uint64{1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("store.Query by votes after restore = %v, want %v", got, want)
+	}
+	if err := s.Restore(ctx, 1); !errors.Is(err, teian.ErrNotFound) {
+		t.Errorf("store.Restore of restored suggestion returned %v, want %v", err, teian.ErrNotFound)
+	}
+
+	if err := s.Purge(ctx, 3); !errors.Is(err, teian.ErrNotFound) {
+		t.Errorf("store.Purge of suggestion not in the trash returned %v, want %v", err, teian.ErrNotFound)
+	}
+	if err := s.Delete(ctx, "", 1, "admin"); err != nil {
+		t.Fatal("store.Delete failed:", err)
+	}
+	if err := s.Purge(ctx, 1); err != nil {
+		t.Fatal("store.Purge failed:", err)
+	}
+	if votes, err := s.VotesOf(ctx, "mary"); err != nil || len(votes) != 0 {
+		t.Errorf("store.VotesOf after purge = %v, %v, want none", votes, err)
+	}
+	trash, err = s.Trash(ctx)
+	if err != nil {
+		t.Fatal("store.Trash failed:", err)
+	}
+	if got, want := ids(trash), []uint64{2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("store.Trash after purge returned IDs %v, want %v", got, want)
+	}
+
+	// IDs of purged suggestions are not reused.
+	sugg := &teian.Suggestion{Text: "new"}
+	mustCreate(ctx, t, s, "john", sugg)
+	if sugg.ID != 4 {
+		t.Errorf("store.Create after purge assigned ID %d, want 4", sugg.ID)
+	}
+}
+
+func testBulk(ctx context.Context, t *testing.T, s Store) {
+	if err := s.AddCategory(ctx, "ui"); err != nil {
+		t.Fatal("store.AddCategory failed:", err)
+	}
+	for i := 0; i < 3; i++ {
+		mustCreate(ctx, t, s, "john", &teian.Suggestion{Text: "text"})
+	}
+	ops := []teian.BulkOp{
+		{Action: teian.BulkStatus, Status: teian.StatusPlanned, By: "admin"},
+		{Action: teian.BulkCategory, Category: "ui", By: "admin"},
+		{Action: teian.BulkDelete, By: "admin"},
+	}
+	for _, op := range ops {
+		failed, err := s.Bulk(ctx, []uint64{1, 2, 9}, op)
+		if err != nil {
+			t.Fatalf("store.Bulk(%+v) failed: %v", op, err)
+		}
+		if len(failed) != 1 || !errors.Is(failed[9], teian.ErrNotFound) {
+			t.Errorf("store.Bulk(%+v) failed IDs = %v, want only 9", op, failed)
+		}
+	}
+	trash, err := s.Trash(ctx)
+	if err != nil {
+		t.Fatal("store.Trash failed:", err)
+	}
+	if got, want := ids(trash), []uint64{2, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("store.Trash after bulk delete returned IDs %v, want %v", got, want)
+	}
+	for _, sugg := range trash {
+		if sugg.Status != teian.StatusPlanned || sugg.Category != "ui" || len(sugg.History) != 1 {
+			t.Errorf("bulk changed suggestion %d has status %v category %q history %v, want planned ui and one change", sugg.ID, sugg.Status, sugg.Category, sugg.History)
+		}
+	}
+	if got := mustGet(ctx, t, s, 3); got.Status != teian.StatusNew || got.Category != "" {
+		t.Errorf("suggestion left out of bulk has status %v category %q, want it unchanged", got.Status, got.Category)
+	}
+
+	if _, err := s.Bulk(ctx, []uint64{3}, teian.BulkOp{Action: teian.BulkCategory, Category: "nope"}); err != teian.ErrUnknownCategory {
+		t.Errorf("store.Bulk with unknown category returned %v, want %v", err, teian.ErrUnknownCategory)
+	}
+	if _, err := s.Bulk(ctx, []uint64{3}, teian.BulkOp{}); err != teian.ErrBadBulkAction {
+		t.Errorf("store.Bulk with no action returned %v, want %v", err, teian.ErrBadBulkAction)
+	}
+
+	// Deleted and missing suggestions are reported while the rest are
+	// returned in the order asked.
+	mustCreate(ctx, t, s, "mary", &teian.Suggestion{Text: "more"})
+	suggs, failed, err := s.GetMany(ctx, []uint64{4, 1, 3, 9})
+	if err != nil {
+		t.Fatal("store.GetMany failed:", err)
+	}
+	if got, want := ids(suggs), []uint64{4, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("store.GetMany returned IDs %v, want %v", got, want)
+	}
+	if len(failed) != 2 || !errors.Is(failed[1], teian.ErrNotFound) || !errors.Is(failed[9], teian.ErrNotFound) {
+		t.Errorf("store.GetMany failed IDs = %v, want 1 and 9 not found", failed)
+	}
+	if len(suggs) == 2 && !reflect.DeepEqual(normalize(suggs[1]), normalize(*mustGet(ctx, t, s, 3))) {
+		t.Errorf("store.GetMany returned %+v, want the same as store.Get", suggs[1])
+	}
+}
+
+func testImport(ctx context.Context, t *testing.T, s Store) {
+	if err := s.AddCategory(ctx, "ui"); err != nil {
+		t.Fatal("store.AddCategory failed:", err)
+	}
+	mustCreate(ctx, t, s, "john", &teian.Suggestion{Text: "existing"})
+	mustCreate(ctx, t, s, "john", &teian.Suggestion{Text: "deleted"})
+	if err := s.Delete(ctx, "john", 2, "john"); err != nil {
+		t.Fatal("store.Delete failed:", err)
+	}
+	created := time.Date(2019, 5, 6, 7, 8, 9, 0, time.UTC)
+	suggs := func() []teian.Suggestion {
+		return []teian.Suggestion{
+			{ID: 1, Username: "john", Text: "existing"},
+			{ID: 2, Username: "john", Text: "deleted"},
+			{ID: 7, Username: "mary", Text: "seven", Created: created, Category: "ui", Status: teian.StatusDone},
+			{Username: "john", Text: "existing"},
+			{Username: "bob", Text: "new"},
+			{Username: "bob", Text: "new"},
+		}
+	}
+	want := []bool{false, false, true, false, true, false}
+
+	added, err := s.Import(ctx, suggs(), true)
+	if err != nil {
+		t.Fatal("store.Import dry run failed:", err)
+	}
+	if !reflect.DeepEqual(added, want) {
+		t.Errorf("store.Import dry run added = %v, want %v", added, want)
+	}
+	if all, err := s.All(ctx); err != nil || len(all) != 1 {
+		t.Fatalf("store.All after dry run = %v, %v, want 1 suggestion", all, err)
+	}
+
+	added, err = s.Import(ctx, suggs(), false)
+	if err != nil {
+		t.Fatal("store.Import failed:", err)
+	}
+	if !reflect.DeepEqual(added, want) {
+		t.Errorf("store.Import added = %v, want %v", added, want)
+	}
+	all, err := s.All(ctx)
+	if err != nil {
+		t.Fatal("store.All failed:", err)
+	}
+	if got, want := ids(all), []uint64{1, 7, 8}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("store.All after import returned IDs %v, want %v", got, want)
+	}
+	wantSeven := teian.Suggestion{ID: 7, Username: "mary", Text: "seven", Created: created, Category: "ui", Status: teian.StatusDone}
+	if got := normalize(all[1]); !reflect.DeepEqual(got, wantSeven) {
+		t.Errorf("imported suggestion = \n%#v, want \n%#v", got, wantSeven)
+	}
+	if all[2].Created.IsZero() {
+		t.Error("imported suggestion without creation time was not created now")
+	}
+	revs, err := s.Revisions(ctx, 7)
+	if err != nil {
+		t.Fatal("store.Revisions failed:", err)
+	}
+	if len(revs) != 1 || revs[0].Text != "seven" || revs[0].By != "mary" || !revs[0].At.Equal(created) {
+		t.Errorf("store.Revisions of imported suggestion = %v, want its text by mary when it was created", revs)
+	}
+
+	// Importing again adds nothing and new suggestions continue the
+	// sequence.
+	added, err = s.Import(ctx, suggs(), false)
+	if err != nil {
+		t.Fatal("store.Import again failed:", err)
+	}
+	if !reflect.DeepEqual(added, make([]bool, len(added))) {
+		t.Errorf("store.Import again added = %v, want nothing", added)
+	}
+	sugg := &teian.Suggestion{Text: "next"}
+	mustCreate(ctx, t, s, "john", sugg)
+	if sugg.ID != 9 {
+		t.Errorf("store.Create after import assigned ID %d, want 9", sugg.ID)
+	}
+
+	// An unknown category fails the whole import.
+	bad := []teian.Suggestion{{Username: "bob", Text: "fine"}, {Username: "bob", Text: "bad", Category: "nope"}}
+	if _, err := s.Import(ctx, bad, false); err != teian.ErrUnknownCategory {
+		t.Errorf("store.Import with unknown category returned %v, want %v", err, teian.ErrUnknownCategory)
+	}
+	if all, err := s.All(ctx); err != nil || len(all) != 4 {
+		t.Errorf("store.All after failed import = %v, %v, want 4 suggestions", ids(all), err)
+	}
+}
+
+func testQuota(ctx context.Context, t *testing.T, s Store) {
+	// add 5 out of 10 MB quota
+	if _, err := s.CheckQuota(ctx, "john", 5<<20, Quota); err != nil {
+		t.Fatal("store.CheckQuota failed:", err)
+	}
+	steps := []struct {
+		username string
+		n        teian.Quota
+		remain   teian.Quota
+		err      error
+	}{
+		{"john", 2 << 20, 3 << 20, nil},
+		// Going over the quota does not use any of it.
+		{"john", 4 << 20, 0, teian.ErrOverQuota},
+		{"john", 0, 3 << 20, nil},
+		{"mary", 10 << 20, 0, nil},
+		{"mary", 1, 0, teian.ErrOverQuota},
+		{"john", 3 << 20, 0, nil},
+	}
+	for _, st := range steps {
+		remain, err := s.CheckQuota(ctx, st.username, st.n, Quota)
+		if err != st.err {
+			t.Fatalf("store.CheckQuota(%q, %d) returned error %v, want %v", st.username, st.n, err, st.err)
+		}
+		if err == nil && remain != st.remain {
+			t.Errorf("store.CheckQuota(%q, %d) = %d, want %d", st.username, st.n, remain, st.remain)
+		}
+	}
+
+	// The limit is the one of each call so a raised limit applies at once.
+	if remain, err := s.CheckQuota(ctx, "john", 0, 2*Quota); err != nil || remain != Quota {
+		t.Errorf("store.CheckQuota with twice the limit = %d, %v, want %d", remain, err, Quota)
+	}
+	usage, err := s.QuotaUsage(ctx)
+	if err != nil {
+		t.Fatal("store.QuotaUsage failed:", err)
+	}
+	want := []teian.QuotaUsage{{Username: "john", Used: Quota}, {Username: "mary", Used: Quota}}
+	if !reflect.DeepEqual(usage, want) {
+		t.Errorf("store.QuotaUsage = %+v, want %+v", usage, want)
+	}
+
+	// A refund gives back the most recent upload of its size only.
+	if err := s.RefundQuota(ctx, "john", 2<<20); err != nil {
+		t.Fatal("store.RefundQuota failed:", err)
+	}
+	if err := s.RefundQuota(ctx, "john", 7<<20); err != nil {
+		t.Fatal("store.RefundQuota of a missing upload failed:", err)
+	}
+	if remain, err := s.CheckQuota(ctx, "john", 0, Quota); err != nil || remain != 2<<20 {
+		t.Errorf("store.CheckQuota after refund = %d, %v, want %d", remain, err, 2<<20)
+	}
+}
+
+func testQuotaOverrides(ctx context.Context, t *testing.T, s Store) {
+	if _, err := s.QuotaOverride(ctx, "john"); !errors.Is(err, teian.ErrNotFound) {
+		t.Errorf("store.QuotaOverride without override returned %v, want %v", err, teian.ErrNotFound)
+	}
+	if _, err := s.CheckQuota(ctx, "john", 2<<20, Quota); err != nil {
+		t.Fatal("store.CheckQuota failed:", err)
+	}
+	for _, limit := range []teian.Quota{1 << 20, 20 << 20} {
+		if err := s.SetQuotaOverride(ctx, "mary", limit); err != nil {
+			t.Fatal("store.SetQuotaOverride failed:", err)
+		}
+	}
+	if got, err := s.QuotaOverride(ctx, "mary"); err != nil || got != 20<<20 {
+		t.Errorf("store.QuotaOverride = %d, %v, want the last override %d", got, err, 20<<20)
+	}
+	if err := s.SetQuotaOverride(ctx, "john", 0); err != nil {
+		t.Fatal("store.SetQuotaOverride failed:", err)
+	}
+	for _, username := range []string{"", "mary"} {
+		if err := s.SetQuotaOverride(ctx, username, -1); !errors.Is(err, teian.ErrInvalid) {
+			t.Errorf("store.SetQuotaOverride(%q, -1) returned %v, want %v", username, err, teian.ErrInvalid)
+		}
+	}
+
+	usage, err := s.QuotaUsage(ctx)
+	if err != nil {
+		t.Fatal("store.QuotaUsage failed:", err)
+	}
+	want := []teian.QuotaUsage{
+		{Username: "john", Used: 2 << 20, Limit: 0, Override: true},
+		{Username: "mary", Used: 0, Limit: 20 << 20, Override: true},
+	}
+	if !reflect.DeepEqual(usage, want) {
+		t.Errorf("store.QuotaUsage = %+v, want %+v", usage, want)
+	}
+
+	if err := s.RemoveQuotaOverride(ctx, "john"); err != nil {
+		t.Fatal("store.RemoveQuotaOverride failed:", err)
+	}
+	if err := s.RemoveQuotaOverride(ctx, "john"); !errors.Is(err, teian.ErrNotFound) {
+		t.Errorf("store.RemoveQuotaOverride of removed override returned %v, want %v", err, teian.ErrNotFound)
+	}
+	usage, err = s.QuotaUsage(ctx)
+	if err != nil {
+		t.Fatal("store.QuotaUsage failed:", err)
+	}
+	if len(usage) != 2 || usage[0] != (teian.QuotaUsage{Username: "john", Used: 2 << 20}) {
+		t.Errorf("store.QuotaUsage after removing override = %+v, want john without override", usage)
+	}
+}
+
+func testConcurrency(ctx context.Context, t *testing.T, s Store) {
+	mustCreate(ctx, t, s, "john", &teian.Suggestion{Text: "popular", Public: true})
+	const workers, each = 10, 5
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*(each+2))
+	created := make(chan uint64, workers*each)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			username := fmt.Sprintf("user%d", w)
+			for i := 0; i < each; i++ {
+				sugg := &teian.Suggestion{Text: fmt.Sprintf("%s #%d", username, i)}
+				if err := s.Create(ctx, username, sugg); err != nil {
+					errs <- err
+					return
+				}
+				created <- sugg.ID
+			}
+			if err := s.Vote(ctx, 1, username, 1); err != nil {
+				errs <- err
+			}
+			// Two workers share each quota of 10 MB.
+			if _, err := s.CheckQuota(ctx, fmt.Sprintf("quota%d", w/2), 6<<20, Quota); err != nil && err != teian.ErrOverQuota {
+				errs <- err
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	close(created)
+	for err := range errs {
+		t.Fatal("concurrent use failed:", err)
+	}
+
+	seen := make(map[uint64]bool)
+	for id := range created {
+		if seen[id] || id < 2 || id > workers*each+1 {
+			t.Errorf("concurrent store.Create assigned ID %d twice or out of sequence", id)
+		}
+		seen[id] = true
+	}
+	if got := mustGet(ctx, t, s, 1); got.Upvotes != workers {
+		t.Errorf("suggestion has %d upvotes after concurrent votes, want %d", got.Upvotes, workers)
+	}
+	for q := 0; q < workers/2; q++ {
+		remain, err := s.CheckQuota(ctx, fmt.Sprintf("quota%d", q), 0, Quota)
+		if err != nil || remain != 4<<20 {
+			t.Errorf("quota %d after concurrent checks has %d remaining, %v, want only one check counted", q, remain, err)
+		}
+	}
+}
+
+func testCanceled(ctx context.Context, t *testing.T, s Store) {
+	mustCreate(ctx, t, s, "john", &teian.Suggestion{Text: "text"})
+	canceled, cancel := context.WithCancel(ctx)
+	cancel()
+
+	calls := map[string]func() error{
+		"Create": func() error { return s.Create(canceled, "john", &teian.Suggestion{Text: "other"}) },
+		"Get": func() error {
+			_, err := s.Get(canceled, 1)
+			return err
+		},
+		"GetMany": func() error {
+			_, _, err := s.GetMany(canceled, []uint64{1})
+			return err
+		},
+		"Query": func() error {
+			_, err := s.Query(canceled, teian.Query{})
+			return err
+		},
+		"Vote":   func() error { return s.Vote(canceled, 1, "mary", 1) },
+		"Delete": func() error { return s.Delete(canceled, "john", 1, "john") },
+		"CheckQuota": func() error {
+			_, err := s.CheckQuota(canceled, "john", 1, Quota)
+			return err
+		},
+		"SetQuotaOverride": func() error { return s.SetQuotaOverride(canceled, "john", 1) },
+		"RefundQuota":      func() error { return s.RefundQuota(canceled, "john", 1) },
+	}
+	for name, call := range calls {
+		if err := call(); !errors.Is(err, context.Canceled) {
+			t.Errorf("store.%s with canceled context returned %v, want %v", name, err, context.Canceled)
+		}
+	}
+
+	// Nothing was changed.
+	all, err := s.All(ctx)
+	if err != nil {
+		t.Fatal("store.All failed:", err)
+	}
+	if len(all) != 1 || all[0].Upvotes != 0 {
+		t.Errorf("store.All after canceled calls = %v, want the suggestion unchanged", all)
+	}
+	if remain, err := s.CheckQuota(ctx, "john", 0, Quota); err != nil || remain != Quota {
+		t.Errorf("store.CheckQuota after canceled call = %d, %v, want %d", remain, err, Quota)
+	}
+}
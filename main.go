@@ -1,18 +1,23 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"flag"
 	"fmt"
 	"html/template"
 	"log"
+	"mime/multipart"
 	"net/http"
+	"net/url"
 	"os"
 	"os/signal"
 	"runtime"
 	"sort"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/kusubooru/shimmie"
 	"github.com/kusubooru/shimmie/store"
@@ -27,6 +32,9 @@ var (
 	dbDriver  = flag.String("dbdriver", "mysql", "database driver")
 	dbConfig  = flag.String("dbconfig", "", "username:password@(host:port)/database?parseTime=true")
 	boltFile  = flag.String("boltfile", "teian.db", "BoltDB database file to store suggestions")
+	storeKind = flag.String("store", "bolt", "where to store suggestions, bolt for -boltfile, sql for a MySQL or SQLite database or mem for memory")
+	sqlDriver = flag.String("storedriver", "", "database driver of the sql store, mysql or sqlite3 (default -dbdriver)")
+	sqlConfig = flag.String("storeconfig", "", "database configuration of the sql store, a file for sqlite3 (default -dbconfig)")
 	loginURL  = flag.String("loginurl", "/suggest/login", "login URL path to redirect to")
 	writeMsg  = flag.String("writemsg", writeMessage, "message that appears on new suggestion screen")
 	version   = flag.Bool("v", false, "print program version")
@@ -35,6 +43,11 @@ var (
 	imagePath = flag.String("imagepath", "", "path where images are stored")
 	thumbPath = flag.String("thumbpath", "", "path where image thumbnails are stored")
 	uploadDir = flag.String("updir", "tagaa_uploads", "upload directory")
+	editGrace = flag.Duration("editgrace", 15*time.Minute, "how long after submitting users can edit their suggestions")
+	maxAttach = flag.Int("attachments", 3, "how many images can be attached to a suggestion")
+	retention = flag.Duration("trashretention", 30*24*time.Hour, "how long deleted suggestions are kept in the trash, 0 keeps them forever")
+	quotas    = flag.String("quota", "admin=1000,user=200,ghost=0", "upload quota in MB per 24 hours of each shimmie user class, classes not listed cannot upload")
+	migrate   = flag.Bool("migrate-only", false, "upgrade the suggestion store to the latest schema version and exit")
 	// Set after flag parsing based on certFile & keyFile.
 	useTLS bool
 )
@@ -46,18 +59,25 @@ const (
 	writeMessage         = `Do you have a suggestion on how to improve the site? Write it here!`
 	submitFailureMessage = "Something broke! :'( Our developers were notified."
 
-	userUploadQuota     = 200 << 20 // 200 MB
-	resetHour       int = 12
-	resetMinute     int = 00
-	resetSecond     int = 00
+	adminPageSize = 50
+	dateLayout    = "2006-01-02"
 )
 
 func usage() {
-	fmt.Fprintf(os.Stderr, "Usage: %s [options]\n", os.Args[0])
-	fmt.Fprintln(os.Stderr, description)
+	fmt.Fprintf(os.Stderr, "Usage: %s [options] [command]\n", os.Args[0])
+	fmt.Fprint(os.Stderr, description, "\n")
 	fmt.Fprintf(os.Stderr, "Options:\n\n")
 	flag.PrintDefaults()
 	fmt.Fprintf(os.Stderr, "\n")
+	fmt.Fprintf(os.Stderr, "Commands:\n\n")
+	for _, c := range commands {
+		fmt.Fprintf(os.Stderr, "  %-10s %s\n", c.name, c.desc)
+	}
+	fmt.Fprintf(os.Stderr, "\n")
+	fmt.Fprintf(os.Stderr, "Commands work on the suggestion store selected by -store while the server is\n")
+	fmt.Fprintf(os.Stderr, "stopped. Backup and restore only work on the -boltfile database; backup -url\n")
+	fmt.Fprintf(os.Stderr, "downloads a backup from a running server.\n")
+	fmt.Fprintf(os.Stderr, "Run '%s <command> -h' for the options of a command.\n\n", os.Args[0])
 }
 
 func main() {
@@ -70,6 +90,32 @@ func main() {
 		os.Exit(0)
 	}
 
+	if flag.NArg() != 0 {
+		os.Exit(runCommand(flag.Args()))
+	}
+
+	classQuotas, err := teian.ParseClassQuotas(*quotas)
+	if err != nil {
+		log.Fatalln("bad -quota:", err)
+	}
+
+	if *migrate {
+		if *storeKind != "bolt" {
+			// The sql store creates its tables when it is opened.
+			openSuggestionStore().Close()
+			log.Printf("%s store schema is up to date", *storeKind)
+			return
+		}
+		s := boltstore.NewSuggestionStore(*boltFile)
+		defer s.Close()
+		version, err := s.SchemaVersion()
+		if err != nil {
+			log.Fatalln("could not get schema version:", err)
+		}
+		log.Printf("%s is at schema version %d", *boltFile, version)
+		return
+	}
+
 	// open store with new database connection and create new Shimmie
 	shim := shimmie.New(*imagePath, *thumbPath, store.Open(*dbDriver, *dbConfig))
 
@@ -80,19 +126,7 @@ func main() {
 	}
 
 	// create suggestion store
-	suggStore := boltstore.NewSuggestionStore(*boltFile, userUploadQuota)
-	closeStoreOnSignal(suggStore)
-
-	t := teian.NewTimer(resetHour, resetMinute, resetSecond)
-	go func() {
-		for {
-			<-t.C
-			if err := suggStore.CleanQuota(); err != nil {
-				log.Println("Clean quota failed:", err)
-			}
-			t.Reset()
-		}
-	}()
+	suggStore := openSuggestionStore()
 
 	// Prepare directory for uploads.
 	mkDirIfNotExist(*uploadDir, 0700)
@@ -102,20 +136,52 @@ func main() {
 		Shimmie:     shim,
 		Suggestions: suggStore,
 		Conf: teian.Conf{
-			Title:       common.Title,
-			AnalyticsID: common.AnalyticsID,
-			Description: common.Description,
-			Keywords:    common.Keywords,
-			WriteMsg:    *writeMsg,
-			Version:     theVersion,
+			Title:          common.Title,
+			AnalyticsID:    common.AnalyticsID,
+			Description:    common.Description,
+			Keywords:       common.Keywords,
+			WriteMsg:       *writeMsg,
+			Version:        theVersion,
+			EditGrace:      *editGrace,
+			MaxAttachments: *maxAttach,
+			Quotas:         classQuotas,
 		},
 	}
 
+	stopPurge := func() {}
+	if *retention > 0 {
+		stopPurge = app.purgeTrashEvery(time.Hour, *retention)
+	}
+	closeStoreOnSignal(suggStore, stopPurge)
+
 	http.Handle("/suggest", shim.AuthFunc(app.serveIndex, *loginURL))
 	http.Handle("/suggest/admin", shim.AuthFunc(app.serveAdmin, *loginURL))
 	http.Handle("/suggest/admin/delete", shim.AuthFunc(app.handleDelete, *loginURL))
+	http.Handle("/suggest/admin/status", shim.AuthFunc(app.handleStatus, *loginURL))
+	http.Handle("/suggest/admin/reply", shim.AuthFunc(app.handleReply, *loginURL))
+	http.Handle("/suggest/admin/category", shim.AuthFunc(app.handleSetCategory, *loginURL))
+	http.Handle("/suggest/admin/categories", shim.AuthFunc(app.serveCategories, *loginURL))
+	http.Handle("/suggest/admin/merge", shim.AuthFunc(app.handleMerge, *loginURL))
+	http.Handle("/suggest/admin/revisions", shim.AuthFunc(app.serveRevisions, *loginURL))
+	http.Handle("/suggest/admin/revert", shim.AuthFunc(app.handleRevert, *loginURL))
+	http.Handle("/suggest/admin/trash", shim.AuthFunc(app.serveTrash, *loginURL))
+	http.Handle("/suggest/admin/backup", shim.AuthFunc(app.serveBackup, *loginURL))
+	http.Handle("/suggest/admin/export", shim.AuthFunc(app.serveExport, *loginURL))
+	http.Handle("/suggest/admin/bulk", shim.AuthFunc(app.handleBulk, *loginURL))
+	http.Handle("/suggest/admin/trash/action", shim.AuthFunc(app.handleTrash, *loginURL))
+	http.Handle("/suggest/admin/quota", shim.AuthFunc(app.serveQuota, *loginURL))
+	http.Handle("/suggest/mine", shim.AuthFunc(app.serveMine, *loginURL))
+	http.Handle("/suggest/list", shim.AuthFunc(app.serveList, *loginURL))
+	http.Handle("/suggest/vote", shim.AuthFunc(app.handleVote, *loginURL))
+	http.Handle("/suggest/comment", shim.AuthFunc(app.handleComment, *loginURL))
+	http.Handle("/suggest/attachment", shim.AuthFunc(app.serveAttachment, *loginURL))
+	http.HandleFunc("/suggest/board", app.serveBoard)
+	http.Handle("/suggest/board.json", allowCORS(apiHandler(app.handleBoardJSON)))
+	http.Handle("/suggest/mine/edit", shim.AuthFunc(app.handleEdit, *loginURL))
+	http.Handle("/suggest/mine/withdraw", shim.AuthFunc(app.handleWithdraw, *loginURL))
 	http.Handle("/suggest/submit", shim.Auth(app.handleSubmit("/suggest", *loginURL, "/suggest/success"), *loginURL))
 	http.Handle("/suggest/success", shim.AuthFunc(app.serveSuccess, *loginURL))
+	http.Handle("/suggest/preview", shim.AuthFunc(app.handlePreview, *loginURL))
 	http.HandleFunc("/suggest/login", app.serveLogin)
 	http.HandleFunc("/suggest/login/submit", app.handleLogin)
 	http.Handle("/suggest/login/test", allowCORSFunc(app.testLogin))
@@ -140,12 +206,15 @@ func main() {
 	}
 }
 
-func closeStoreOnSignal(s *boltstore.Boltstore) {
+// closeStoreOnSignal closes the store and exits when the program is
+// interrupted. The background work that uses the store is stopped first.
+func closeStoreOnSignal(s suggestionStore, stop func()) {
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, os.Kill)
 	go func() {
 		for sig := range c {
 			log.Printf("%v signal received, releasing database resources and exiting...", sig)
+			stop()
 			s.Close()
 			os.Exit(1)
 		}
@@ -173,58 +242,217 @@ type App struct {
 }
 
 func (app *App) serveIndex(w http.ResponseWriter, r *http.Request) {
-	app.render(w, suggestionTmpl, nil)
+	categories, err := app.Suggestions.Categories(r.Context())
+	if err != nil {
+		app.storeError(w, err, "could not get categories")
+		return
+	}
+	app.render(w, suggestionTmpl, categories)
 }
 
 func (app *App) serveLogin(w http.ResponseWriter, r *http.Request) {
 	app.render(w, loginTmpl, nil)
 }
 
+func (app *App) serveMine(w http.ResponseWriter, r *http.Request) {
+	user, ok := shimmie.FromContextGetUser(r.Context())
+	if !ok {
+		http.Error(w, "You are not authorized to view this page.", http.StatusUnauthorized)
+		return
+	}
+	suggs, err := app.Suggestions.OfUser(r.Context(), user.Name)
+	if err != nil {
+		app.storeError(w, err, "could not get suggestions")
+		return
+	}
+	sort.Sort(sort.Reverse(teian.ByDate(suggs)))
+	app.render(w, mineTmpl, suggs)
+}
+
+// handleEdit lets users change the text of their own suggestions as long as
+// the edit grace period has not passed. The owner is always the logged in
+// user and never taken from the form.
+func (app *App) handleEdit(w http.ResponseWriter, r *http.Request) {
+	// only accept POST method
+	if r.Method != "POST" {
+		http.Error(w, fmt.Sprintf("%v method not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := shimmie.FromContextGetUser(r.Context())
+	if !ok {
+		http.Error(w, "You are not authorized to perform this action.", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseUint(r.PostFormValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("bad id provided: %v", err), http.StatusBadRequest)
+		return
+	}
+	text := r.PostFormValue("text")
+	if len(strings.TrimSpace(text)) == 0 {
+		http.Error(w, "suggestion text must not be empty", http.StatusBadRequest)
+		return
+	}
+	sugg, err := app.Suggestions.Get(r.Context(), id)
+	if err != nil && !errors.Is(err, teian.ErrNotFound) {
+		app.storeError(w, err, "could not get suggestion")
+		return
+	}
+	if err != nil || sugg.Username != user.Name {
+		http.Error(w, "suggestion not found", http.StatusNotFound)
+		return
+	}
+	if !sugg.Editable(app.Conf.EditGrace) {
+		http.Error(w, "suggestion can no longer be edited", http.StatusForbidden)
+		return
+	}
+	if err := app.Suggestions.Edit(r.Context(), user.Name, id, text); err != nil {
+		app.storeError(w, err, "edit suggestion failed")
+		return
+	}
+	http.Redirect(w, r, "/suggest/mine", http.StatusSeeOther)
+}
+
+// handleWithdraw lets users delete their own suggestions.
+func (app *App) handleWithdraw(w http.ResponseWriter, r *http.Request) {
+	// only accept POST method
+	if r.Method != "POST" {
+		http.Error(w, fmt.Sprintf("%v method not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := shimmie.FromContextGetUser(r.Context())
+	if !ok {
+		http.Error(w, "You are not authorized to perform this action.", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseUint(r.PostFormValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("bad id provided: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := app.Suggestions.Delete(r.Context(), user.Name, id, user.Name); err != nil {
+		app.storeError(w, err, "withdraw suggestion failed")
+		return
+	}
+	http.Redirect(w, r, "/suggest/mine", http.StatusSeeOther)
+}
+
 func (app *App) serveAdmin(w http.ResponseWriter, r *http.Request) {
 	user, ok := shimmie.FromContextGetUser(r.Context())
 	if !ok || user.Admin != "Y" {
 		http.Error(w, "You are not authorized to view this page.", http.StatusUnauthorized)
 		return
 	}
-	suggs, err := app.Suggestions.All()
+	q, err := adminQuery(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var page *teian.Page
+	if q.Text != "" {
+		page, err = app.search(r.Context(), q)
+	} else {
+		page, err = app.Suggestions.Query(r.Context(), q)
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %v", err), storeStatus(err))
+		return
+	}
+	categories, err := app.Suggestions.Categories(r.Context())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %v", err), storeStatus(err))
+		return
+	}
+	counts, err := app.Suggestions.CategoryCounts(r.Context())
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Error: %v", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("Error: %v", err), storeStatus(err))
+		return
 	}
 
-	u := r.FormValue("u")
-	t := r.FormValue("t")
-	o := r.FormValue("o")
+	data := struct {
+		Suggestions   []teian.Suggestion
+		Statuses      []teian.Status
+		Categories    []string
+		Counts        map[string]int
+		Prev          string
+		Next          string
+		ExportFormats []string
+	}{
+		Suggestions:   page.Suggestions,
+		Statuses:      teian.Statuses(),
+		Categories:    categories,
+		Counts:        counts,
+		ExportFormats: teian.ExportFormats(),
+	}
+	if page.Prev != "" {
+		data.Prev = pageURL(r, page.Prev)
+	}
+	if page.Next != "" {
+		data.Next = pageURL(r, page.Next)
+	}
+	app.render(w, listTmpl, data)
+}
 
-	suggs = filterSuggestions(suggs, u, t, o)
-	app.render(w, listTmpl, suggs)
+// search uses q.Text as a full text search query and returns the page of
+// results, ranked by relevance, that also match the rest of the filters of
+// q.
+func (app *App) search(ctx context.Context, q teian.Query) (*teian.Page, error) {
+	suggs, err := app.Suggestions.Search(ctx, q.Text)
+	if err != nil {
+		return nil, err
+	}
+	return teian.PageSearch(suggs, q)
 }
 
-func filterSuggestions(suggs []teian.Suggestion, username, text, order string) []teian.Suggestion {
-	if len(suggs) == 0 || len(suggs) == 1 {
-		return suggs
+// adminQuery builds a suggestion query out of the admin toolbar values.
+func adminQuery(r *http.Request) (teian.Query, error) {
+	if err := r.ParseForm(); err != nil {
+		return teian.Query{}, err
 	}
-	// filter
-	if username != "" {
-		suggs = teian.FilterByUser(suggs, username)
+	return formQuery(r.Form)
+}
+
+// formQuery builds a suggestion query out of the values of the admin
+// toolbar form.
+func formQuery(v url.Values) (teian.Query, error) {
+	q := teian.Query{
+		Username: v.Get("u"),
+		Text:     v.Get("t"),
+		Order:    teian.Order(v.Get("o")),
+		Limit:    adminPageSize,
+		Category: v.Get("cat"),
+		Cursor:   v.Get("c"),
 	}
-	if text != "" {
-		suggs = teian.FilterByText(suggs, text)
+	if s := v.Get("s"); s != "" {
+		st, err := teian.ParseStatus(s)
+		if err != nil {
+			return q, err
+		}
+		q.Statuses = []teian.Status{st}
 	}
-
-	// order
-	switch order {
-	case "ua":
-		sort.Sort(teian.ByUser(suggs))
-	case "ud":
-		sort.Sort(sort.Reverse(teian.ByUser(suggs)))
-	case "da":
-		sort.Sort(teian.ByDate(suggs))
-	case "dd":
-		fallthrough
-	default:
-		sort.Sort(sort.Reverse(teian.ByDate(suggs)))
+	if from := v.Get("from"); from != "" {
+		t, err := time.Parse(dateLayout, from)
+		if err != nil {
+			return q, fmt.Errorf("bad from date: %v", err)
+		}
+		q.Since = t
 	}
-	return suggs
+	if to := v.Get("to"); to != "" {
+		t, err := time.Parse(dateLayout, to)
+		if err != nil {
+			return q, fmt.Errorf("bad to date: %v", err)
+		}
+		// include the whole day
+		q.Until = t.AddDate(0, 0, 1)
+	}
+	return q, nil
+}
+
+// pageURL returns the URL of the current request with its cursor replaced.
+func pageURL(r *http.Request, cursor string) string {
+	v := r.URL.Query()
+	v.Set("c", cursor)
+	return r.URL.Path + "?" + v.Encode()
 }
 
 func (app *App) handleDelete(w http.ResponseWriter, r *http.Request) {
@@ -249,9 +477,79 @@ func (app *App) handleDelete(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("bad id provided: %v", err), http.StatusBadRequest)
 		return
 	}
-	err = app.Suggestions.Delete(username, id)
+	err = app.Suggestions.Delete(r.Context(), username, id, user.Name)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("delete suggestion failed: %v", err), storeStatus(err))
+		return
+	}
+	http.Redirect(w, r, "/suggest/admin", http.StatusFound)
+}
+
+func (app *App) handleStatus(w http.ResponseWriter, r *http.Request) {
+	// only accept POST method
+	if r.Method != "POST" {
+		http.Error(w, fmt.Sprintf("%v method not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := shimmie.FromContextGetUser(r.Context())
+	if !ok || user.Admin != "Y" {
+		http.Error(w, "You are not authorized to perform this action.", http.StatusUnauthorized)
+		return
+	}
+	idValue := r.PostFormValue("id")
+	username := r.PostFormValue("username")
+	if idValue == "" || username == "" {
+		http.Error(w, "id and username must be present", http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.ParseUint(idValue, 10, 64)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("bad id provided: %v", err), http.StatusBadRequest)
+		return
+	}
+	status, err := teian.ParseStatus(r.PostFormValue("status"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("bad status provided: %v", err), http.StatusBadRequest)
+		return
+	}
+	err = app.Suggestions.SetStatus(r.Context(), username, id, status, user.Name)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("change suggestion status failed: %v", err), storeStatus(err))
+		return
+	}
+	http.Redirect(w, r, "/suggest/admin", http.StatusFound)
+}
+
+func (app *App) handleReply(w http.ResponseWriter, r *http.Request) {
+	// only accept POST method
+	if r.Method != "POST" {
+		http.Error(w, fmt.Sprintf("%v method not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := shimmie.FromContextGetUser(r.Context())
+	if !ok || user.Admin != "Y" {
+		http.Error(w, "You are not authorized to perform this action.", http.StatusUnauthorized)
+		return
+	}
+	idValue := r.PostFormValue("id")
+	username := r.PostFormValue("username")
+	if idValue == "" || username == "" {
+		http.Error(w, "id and username must be present", http.StatusBadRequest)
+		return
+	}
+	id, err := strconv.ParseUint(idValue, 10, 64)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("bad id provided: %v", err), http.StatusBadRequest)
+		return
+	}
+	text := r.PostFormValue("text")
+	if len(strings.TrimSpace(text)) == 0 {
+		http.Error(w, "reply text must not be empty", http.StatusBadRequest)
+		return
+	}
+	err = app.Suggestions.AddReply(r.Context(), username, id, &teian.Reply{Username: user.Name, Text: text})
 	if err != nil {
-		http.Error(w, fmt.Sprintf("delete suggestion failed: %v", err), http.StatusInternalServerError)
+		http.Error(w, fmt.Sprintf("reply to suggestion failed: %v", err), storeStatus(err))
 		return
 	}
 	http.Redirect(w, r, "/suggest/admin", http.StatusFound)
@@ -357,6 +655,33 @@ func (app *App) Errorf(w http.ResponseWriter, code int, err error, format string
 	http.Error(w, msg, code)
 }
 
+// storeStatus returns the HTTP status code for an error of the suggestion
+// store: 404 for teian.ErrNotFound, 409 for teian.ErrConflict, 400 for
+// teian.ErrInvalid and 500 for everything else.
+func storeStatus(err error) int {
+	switch {
+	case errors.Is(err, teian.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, teian.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, teian.ErrInvalid):
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// storeError responds to a failed operation of the suggestion store with
+// the status code of err. The error is shown along with the message unless
+// it is an internal error, which is logged instead.
+func (app *App) storeError(w http.ResponseWriter, err error, format string, a ...interface{}) {
+	code := storeStatus(err)
+	if code != http.StatusInternalServerError {
+		format += ": %v"
+		a = append(a, err)
+	}
+	app.Errorf(w, code, err, format, a...)
+}
+
 func (app *App) handleSubmit(badInputURL, loginURL, successURL string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// only accept POST method
@@ -370,6 +695,9 @@ func (app *App) handleSubmit(badInputURL, loginURL, successURL string) http.Hand
 			http.Redirect(w, r, loginURL, http.StatusFound)
 			return
 		}
+		// Limit max upload. The form also holds the text so allow a
+		// little more than the attachments.
+		r.Body = http.MaxBytesReader(w, r.Body, int64(app.Conf.MaxAttachments+1)*maxAttachmentSize)
 		text := r.PostFormValue("text")
 		// redirect if suggestion text is empty
 		if len(strings.TrimSpace(text)) == 0 {
@@ -377,8 +705,55 @@ func (app *App) handleSubmit(badInputURL, loginURL, successURL string) http.Hand
 			return
 		}
 
+		// offer the likely duplicates first unless the user has already
+		// seen them and chose to submit anyway
+		if r.PostFormValue("force") == "" {
+			dups, err := app.duplicates(r.Context(), user.Name, text)
+			if err != nil {
+				app.storeError(w, err, "could not check for duplicates")
+				return
+			}
+			if len(dups) != 0 {
+				app.renderDuplicates(w, r.PostForm, dups)
+				return
+			}
+		}
+
+		var files []*multipart.FileHeader
+		if r.MultipartForm != nil {
+			files = r.MultipartForm.File[attachFormFileName]
+		}
+		attachments, err := app.saveAttachments(r.Context(), user, files)
+		switch {
+		case err == nil:
+		case errors.Is(err, errTooManyAttachments), errors.Is(err, errAttachmentTooLarge), errors.Is(err, errNotImage), errors.Is(err, errImageTooLarge):
+			app.Errorf(w, http.StatusBadRequest, err, "%v", err)
+			return
+		case errors.Is(err, teian.ErrOverQuota):
+			app.Errorf(w, http.StatusForbidden, err, "Your upload quota has been exceeded.")
+			return
+		default:
+			app.Errorf(w, http.StatusInternalServerError, err, submitFailureMessage)
+			return
+		}
+
 		// create and store suggestion
-		err := app.Suggestions.Create(user.Name, &teian.Suggestion{Text: text})
+		sugg := &teian.Suggestion{
+			Text:        text,
+			Public:      r.PostFormValue("public") != "",
+			Anonymous:   r.PostFormValue("anonymous") != "",
+			Category:    r.PostFormValue("category"),
+			Tags:        teian.ParseTags(r.PostFormValue("tags")),
+			Attachments: attachments,
+		}
+		err = app.Suggestions.Create(r.Context(), user.Name, sugg)
+		if err != nil {
+			app.discardAttachments(user.Name, attachments)
+		}
+		if errors.Is(err, teian.ErrInvalid) {
+			http.Redirect(w, r, badInputURL, http.StatusFound)
+			return
+		}
 		if err != nil {
 			app.Errorf(w, http.StatusInternalServerError, err, submitFailureMessage)
 			return
@@ -392,6 +767,22 @@ func (app *App) serveSuccess(w http.ResponseWriter, r *http.Request) {
 	app.render(w, successTmpl, nil)
 }
 
+// maxPreviewSize is the longest text handlePreview renders.
+const maxPreviewSize = 1 << 20 // 1 MB
+
+// handlePreview renders the posted text the way it will be shown once
+// submitted. It returns an HTML fragment for the submit form to display.
+func (app *App) handlePreview(w http.ResponseWriter, r *http.Request) {
+	// only accept POST method
+	if r.Method != "POST" {
+		http.Error(w, fmt.Sprintf("%v method not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, maxPreviewSize)
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, teian.Markdown(r.PostFormValue("text")))
+}
+
 func (app *App) renderTemplate(w http.ResponseWriter, t *template.Template, data interface{}) {
 	if err := t.Execute(w, data); err != nil {
 		app.Errorf(w, http.StatusInternalServerError, err, "could not render template")
@@ -412,7 +803,8 @@ func (app *App) render(w http.ResponseWriter, t *template.Template, data interfa
 var (
 	suggestionTmpl = template.Must(template.New("suggestionTmpl").Parse(baseTemplate + subnavTemplate + suggestionTemplate))
 	successTmpl    = template.Must(template.New("successTmpl").Parse(baseTemplate + subnavTemplate + successTemplate))
-	listTmpl       = template.Must(template.New("listTmpl").Parse(baseTemplate + subnavTemplate + toolbarTemplate + listTemplate))
+	listTmpl       = template.Must(template.New("listTmpl").Parse(baseTemplate + subnavTemplate + toolbarTemplate + listTemplate + tagsTemplate + repliesTemplate))
+	mineTmpl       = template.Must(template.New("mineTmpl").Parse(baseTemplate + subnavTemplate + mineTemplate + repliesTemplate))
 	loginTmpl      = template.Must(template.New("loginTmpl").Parse(baseTemplate + loginTemplate))
 )
 
@@ -559,10 +951,49 @@ const (
 			font-size: 120%;
 			line-height:1.2;
 		}
+		.text, .preview {
+			font-size: 120%;
+			line-height: 1.4;
+			max-width: 70em;
+			overflow-wrap: break-word;
+		}
+		.text p, .preview p {
+			margin: 0.3em 0;
+		}
+		.text pre, .preview pre {
+			background: #eee;
+			padding: 0.5em;
+			overflow-x: auto;
+		}
+		.suggestion .history {
+			margin: 0;
+			color: #777;
+		}
+		.suggestion .replies {
+			margin-left: 2em;
+		}
+		.suggestion .reply-form, .suggestion .edit-form {
+			display: block;
+		}
 		.suggestion:nth-of-type(even) {
 		    background: #f6f6f6;
 		}
 
+		.attachments img {
+			max-width: 192px;
+			max-height: 192px;
+			margin-right: 0.5em;
+		}
+
+		.category-counts a, .tags a {
+			font-size: 100%;
+			padding-right: 0.5em;
+		}
+
+		.pagination {
+			padding: 0.5em;
+		}
+
 		.suggestion-form {
 			padding: 0.5em;
 			line-height: 200%;
@@ -577,6 +1008,10 @@ const (
 			}
 		}
 
+		.suggestion-form label {
+			display: block;
+		}
+
 		.suggestion-form input[type=submit] {
 			padding: 0.5em;
 			margin-top: 0.5em;
@@ -614,6 +1049,12 @@ const (
 			border-color: #ebccd1;
 		}
 
+		.alert-warning {
+			color: #8a6d3b;
+			background-color: #fcf8e3;
+			border-color: #faebcc;
+		}
+
 		footer {
 			color: #ccc;
 			font-size: 0.9em;
@@ -645,17 +1086,78 @@ const (
 <div class="toolbar">
 	<form method="get" action="/suggest/admin">
 		<input type="text" name="u" placeholder="Username">
-		<input type="text" name="t" placeholder="Text">
+		<input type="text" name="t" placeholder="Search text" title='Words must all appear. Use "quotes" for phrases and -word to exclude.'>
+		<label for="from">From</label>
+		<input type="date" id="from" name="from">
+		<label for="to">To</label>
+		<input type="date" id="to" name="to">
+		<label for="status">Status</label>
+		<select id="status" name="s">
+			<option value="">All</option>
+			{{range .Data.Statuses}}
+			<option value="{{.}}">{{.}}</option>
+			{{end}}
+		</select>
+		<label for="category">Category</label>
+		<select id="category" name="cat">
+			<option value="">All</option>
+			{{range .Data.Categories}}
+			<option value="{{.}}">{{.}}</option>
+			{{end}}
+		</select>
 		<label for="order">Order By</label>
 		<select id="order" name="o">
 			<option value="dd">Date Desc</option>
 			<option value="da">Date Asc</option>
 			<option value="ud">Username Desc</option>
 			<option value="ua">Username Asc</option>
+			<option value="vd">Votes Desc</option>
+			<option value="va">Votes Asc</option>
 		</select>
 	    <button type="submit">Search</button>
 		<input type="reset" value="Reset">
+		{{range .Data.ExportFormats}}
+		<button type="submit" formaction="/suggest/admin/export" name="format" value="{{.}}">Export {{.}}</button>
+		{{end}}
+	</form>
+	<div class="category-counts">
+		{{range $name, $n := .Data.Counts}}
+		<a href="/suggest/admin?cat={{$name}}">{{$name}} ({{$n}})</a>
+		{{end}}
+		<a href="/suggest/admin/categories">Manage categories</a>
+		<a href="/suggest/admin/trash">Trash</a>
+		<a href="/suggest/admin/backup">Backup</a>
+		<a href="/suggest/admin/quota">Upload quotas</a>
+	</div>
+	<form id="bulk" class="bulk-form" method="post" action="/suggest/admin/bulk">
+		<label><input type="checkbox" id="select-all"> Select all</label>
+		<select name="action">
+			<option value="status">Set status</option>
+			<option value="category">Set category</option>
+			<option value="delete">Delete</option>
+			<option value="export">Export</option>
+		</select>
+		<select name="status">
+			{{range .Data.Statuses}}
+			<option value="{{.}}">{{.}}</option>
+			{{end}}
+		</select>
+		<select name="category">
+			<option value="">uncategorized</option>
+			{{range .Data.Categories}}
+			<option value="{{.}}">{{.}}</option>
+			{{end}}
+		</select>
+		<input type="submit" value="Apply to selected">
 	</form>
+	<script>
+	document.getElementById('select-all').addEventListener('change', function(e) {
+		var boxes = document.querySelectorAll('input[type=checkbox][form=bulk]');
+		for (var i = 0; i < boxes.length; i++) {
+			boxes[i].checked = e.target.checked;
+		}
+	});
+	</script>
 </div>
 {{end}}
 `
@@ -663,6 +1165,9 @@ const (
 {{define "subnav"}}
 <div id="subnav">
 	<a href="/suggest">New suggestion</a>
+	<a href="/suggest/mine">My suggestions</a>
+	<a href="/suggest/list">Vote</a>
+	<a href="/suggest/board">Board</a>
 	<form class="subnav-button-form" method="post" action="/suggest/logout">
 	     <input class="subnav-button-link" type="submit" value="Logout">
 	</form>
@@ -672,12 +1177,83 @@ const (
 	suggestionTemplate = `
 {{define "content"}}
 <div class="suggestion-form">
-	<form method="post" action="/suggest/submit">
+	<form method="post" action="/suggest/submit" enctype="multipart/form-data">
 		<p>{{.Conf.WriteMsg}}</p>
-		<textarea class="large" rows="20" cols="80" name="text" placeholder="Write your suggestion here."></textarea>
+		<textarea class="large" rows="20" cols="80" id="text" name="text" placeholder="Write your suggestion here."></textarea>
+		<small>You can use *emphasis*, **strong**, ` + "`code`" + `, [links](https://example.com), lists, "post #123" and "tag:name".</small>
+		<div id="preview" class="preview"></div>
+		{{if .Data}}
+		<label for="category">Category</label>
+		<select id="category" name="category">
+			<option value="">Other</option>
+			{{range .Data}}
+			<option value="{{.}}">{{.}}</option>
+			{{end}}
+		</select>
+		{{end}}
+		<label for="tags">Tags</label>
+		<input type="text" id="tags" name="tags" list="tag-suggestions" autocomplete="off" placeholder="Booru tags your suggestion is about (optional)">
+		<datalist id="tag-suggestions"></datalist>
+		{{if .Conf.MaxAttachments}}
+		<label for="attachments">Screenshots (up to {{.Conf.MaxAttachments}} PNG, JPEG or GIF images)</label>
+		<input type="file" id="attachments" name="attachments" accept="image/png,image/jpeg,image/gif" multiple>
+		{{end}}
+		<label><input type="checkbox" name="public" value="1"> Show my suggestion on the <a href="/suggest/board">public board</a></label>
+		<label><input type="checkbox" name="anonymous" value="1"> Do not show my username to other users</label>
 		<input type="submit">
 	</form>
 </div>
+<script>
+(function() {
+	var text = document.getElementById('text');
+	var preview = document.getElementById('preview');
+	var timer;
+	text.addEventListener('input', function() {
+		clearTimeout(timer);
+		timer = setTimeout(function() {
+			var xhr = new XMLHttpRequest();
+			xhr.open('POST', '/suggest/preview');
+			xhr.setRequestHeader('Content-Type', 'application/x-www-form-urlencoded');
+			xhr.onload = function() {
+				if (xhr.status === 200) {
+					preview.innerHTML = xhr.responseText;
+				}
+			};
+			xhr.send('text=' + encodeURIComponent(text.value));
+		}, 300);
+	});
+})();
+(function() {
+	var input = document.getElementById('tags');
+	var list = document.getElementById('tag-suggestions');
+	input.addEventListener('input', function() {
+		var words = input.value.split(' ');
+		var q = words[words.length - 1];
+		if (q.length < 2) {
+			return;
+		}
+		var prefix = words.slice(0, -1).join(' ');
+		if (prefix) {
+			prefix += ' ';
+		}
+		var xhr = new XMLHttpRequest();
+		xhr.open('GET', '/suggest/autocomplete?q=' + encodeURIComponent(q));
+		xhr.onload = function() {
+			if (xhr.status !== 200) {
+				return;
+			}
+			var tags = JSON.parse(xhr.responseText) || [];
+			list.innerHTML = '';
+			tags.forEach(function(t) {
+				var opt = document.createElement('option');
+				opt.value = prefix + t.name;
+				list.appendChild(opt);
+			});
+		};
+		xhr.send();
+	});
+})();
+</script>
 {{end}}
 `
 	successTemplate = `
@@ -695,15 +1271,124 @@ const (
 `
 	listTemplate = `
 {{define "content"}}
-{{ range $k, $v := .Data }}
+{{ $statuses := .Data.Statuses }}
+{{ $categories := .Data.Categories }}
+{{ range $k, $v := .Data.Suggestions }}
 	<div class="suggestion">
-		<span>{{$v.FmtCreated}} by <a href="/user/{{$v.Username}}">{{$v.Username}}</a></span>
+		<input type="checkbox" name="id" value="{{$v.ID}}" form="bulk">
+		<span>#{{$v.ID}} {{$v.FmtCreated}} by <a href="/user/{{$v.Username}}">{{$v.Username}}</a> (+{{$v.Upvotes}} / -{{$v.Downvotes}}){{if $v.Public}} public{{end}}{{if $v.Anonymous}} anonymous{{end}}{{if $v.MergedInto}} merged into #{{$v.MergedInto}}{{end}}{{if $v.Merged}} merged{{range $v.Merged}} #{{.}}{{end}}{{end}}</span>
+		<form method="post" action="/suggest/admin/status">
+			<input type="hidden" name="username" value="{{$v.Username}}">
+			<input type="hidden" name="id" value="{{$v.ID}}">
+			<select name="status">
+				{{range $statuses}}
+				<option value="{{.}}"{{if eq . $v.Status}} selected{{end}}>{{.}}</option>
+				{{end}}
+			</select>
+			<input type="submit" value="Set status">
+		</form>
+		<form method="post" action="/suggest/admin/category">
+			<input type="hidden" name="username" value="{{$v.Username}}">
+			<input type="hidden" name="id" value="{{$v.ID}}">
+			<select name="category">
+				<option value="">uncategorized</option>
+				{{range $categories}}
+				<option value="{{.}}"{{if eq . $v.Category}} selected{{end}}>{{.}}</option>
+				{{end}}
+			</select>
+			<input type="submit" value="Set category">
+		</form>
+		{{if not $v.MergedInto}}
+		<form method="post" action="/suggest/admin/merge">
+			<input type="hidden" name="id" value="{{$v.ID}}">
+			<input type="number" name="into" min="1" placeholder="#" required>
+			<input type="submit" value="Merge into">
+		</form>
+		{{end}}
 		<form method="post" action="/suggest/admin/delete">
 			<input type="hidden" name="username" value="{{$v.Username}}">
 			<input type="hidden" name="id" value="{{$v.ID}}">
 			<input type="submit" value="Delete">
 		</form>
-		<textarea cols="80" readonly>{{$v.Text}}</textarea>
+		<div class="text">{{$v.HTML}}</div>
+		<a href="/suggest/admin/revisions?id={{$v.ID}}">Revisions</a>
+		{{template "tags" $v.Tags}}
+		{{if $v.Attachments}}
+		<div class="attachments">
+			{{range $v.Attachments}}
+			<a href="/suggest/attachment?id={{$v.ID}}&name={{.Name}}"><img src="/suggest/attachment?id={{$v.ID}}&name={{.Thumb}}" alt="{{.Name}}"></a>
+			{{end}}
+		</div>
+		{{end}}
+		{{if $v.History}}
+		<ul class="history">
+			{{range $v.History}}
+			<li>{{.FmtAt}}: {{if .Merged}}merged #{{.Merged}}{{else}}{{.From}} &rarr; {{.To}}{{end}} by {{.By}}</li>
+			{{end}}
+		</ul>
+		{{end}}
+		{{template "replies" $v.Replies}}
+		<form class="reply-form" method="post" action="/suggest/admin/reply">
+			<input type="hidden" name="username" value="{{$v.Username}}">
+			<input type="hidden" name="id" value="{{$v.ID}}">
+			<textarea cols="80" rows="3" name="text" placeholder="Reply to {{$v.Username}}"></textarea>
+			<input type="submit" value="Reply">
+		</form>
+	</div>
+{{ end }}
+<div class="pagination">
+	{{if .Data.Prev}}<a href="{{.Data.Prev}}">&larr; Previous</a>{{end}}
+	{{if .Data.Next}}<a href="{{.Data.Next}}">Next &rarr;</a>{{end}}
+</div>
+{{end}}
+`
+	tagsTemplate = `
+{{define "tags"}}
+{{if .}}
+<div class="tags">
+	Tags: {{range .}}<a href="/post/list/{{.}}/1">{{.}}</a> {{end}}
+</div>
+{{end}}
+{{end}}
+`
+	repliesTemplate = `
+{{define "replies"}}
+{{if .}}
+<div class="replies">
+	{{range .}}
+	<div class="reply">
+		<span>{{.FmtCreated}} by <a href="/user/{{.Username}}">{{.Username}}</a></span>
+		<div class="text">{{.HTML}}</div>
+	</div>
+	{{end}}
+</div>
+{{end}}
+{{end}}
+`
+	mineTemplate = `
+{{define "content"}}
+{{ $grace := .Conf.EditGrace }}
+{{ range $k, $v := .Data }}
+	<div class="suggestion">
+		<span>{{$v.FmtCreated}} ({{$v.Status}}{{if $v.Public}}, public{{end}}{{if $v.Anonymous}}, anonymous{{end}})</span>
+		<form method="post" action="/suggest/mine/withdraw">
+			<input type="hidden" name="id" value="{{$v.ID}}">
+			<input type="submit" value="Withdraw">
+		</form>
+		{{if $v.Editable $grace}}
+		<form class="edit-form" method="post" action="/suggest/mine/edit">
+			<input type="hidden" name="id" value="{{$v.ID}}">
+			<textarea cols="80" rows="5" name="text">{{$v.Text}}</textarea>
+			<input type="submit" value="Save">
+		</form>
+		{{else}}
+		<div class="text">{{$v.HTML}}</div>
+		{{end}}
+		{{template "replies" $v.Replies}}
+	</div>
+{{ else }}
+	<div class="suggestion-form">
+		<p>You have not submitted any suggestions yet.</p>
 	</div>
 {{ end }}
 {{end}}
@@ -1,14 +1,20 @@
 package main
 
 import (
+	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
 	"log"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"os"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/kusubooru/shimmie"
 	"github.com/kusubooru/teian/internal/mock"
@@ -17,7 +23,8 @@ import (
 
 func TestApp_handleSubmit(t *testing.T) {
 	s := &mock.SuggestionStore{}
-	s.CreateFn = func(username string, sugg *teian.Suggestion) error { return nil }
+	s.CreateFn = func(ctx context.Context, username string, sugg *teian.Suggestion) error { return nil }
+	s.SimilarFn = noSimilar
 	app := App{Suggestions: s}
 
 	h := app.handleSubmit("/bad", "/login", "/success")
@@ -56,6 +63,97 @@ func TestApp_handleSubmit(t *testing.T) {
 	}
 }
 
+func TestApp_handleSubmit_public(t *testing.T) {
+	s := &mock.SuggestionStore{}
+	s.SimilarFn = noSimilar
+	var created *teian.Suggestion
+	s.CreateFn = func(ctx context.Context, username string, sugg *teian.Suggestion) error {
+		created = sugg
+		return nil
+	}
+	app := App{Suggestions: s}
+
+	h := app.handleSubmit("/bad", "/login", "/success")
+	v := url.Values{"text": {"blah"}, "public": {"1"}, "anonymous": {"1"}}
+	h.ServeHTTP(httptest.NewRecorder(), newRequest("POST", v, &shimmie.User{Name: "jin"}))
+	if created == nil {
+		t.Fatal("handleSubmit did not create suggestion")
+	}
+	if !created.Public || !created.Anonymous {
+		t.Errorf("handleSubmit created suggestion with Public %v and Anonymous %v, want both true", created.Public, created.Anonymous)
+	}
+}
+
+func TestApp_handleSubmit_category(t *testing.T) {
+	s := &mock.SuggestionStore{}
+	s.SimilarFn = noSimilar
+	var created *teian.Suggestion
+	s.CreateFn = func(ctx context.Context, username string, sugg *teian.Suggestion) error {
+		if sugg.Category != "uploads" {
+			return teian.ErrUnknownCategory
+		}
+		created = sugg
+		return nil
+	}
+	app := App{Suggestions: s}
+
+	h := app.handleSubmit("/bad", "/login", "/success")
+	v := url.Values{"text": {"blah"}, "category": {"uploads"}, "tags": {"Touhou smile"}}
+	h.ServeHTTP(httptest.NewRecorder(), newRequest("POST", v, &shimmie.User{Name: "jin"}))
+	if created == nil {
+		t.Fatal("handleSubmit did not create suggestion")
+	}
+	if want := []string{"touhou", "smile"}; !reflect.DeepEqual(created.Tags, want) {
+		t.Errorf("handleSubmit created suggestion with tags %q, want %q", created.Tags, want)
+	}
+
+	w := httptest.NewRecorder()
+	v.Set("category", "unknown")
+	h.ServeHTTP(w, newRequest("POST", v, &shimmie.User{Name: "jin"}))
+	if got, want := w.Result().Header.Get("Location"), "/bad"; got != want {
+		t.Errorf("submit with unknown category redirected to %q, want %q", got, want)
+	}
+}
+
+func noSimilar(ctx context.Context, text string, threshold float64) ([]teian.ScoredSuggestion, error) {
+	return nil, nil
+}
+
+func TestApp_handleSubmit_duplicates(t *testing.T) {
+	s := &mock.SuggestionStore{}
+	s.SimilarFn = func(ctx context.Context, text string, threshold float64) ([]teian.ScoredSuggestion, error) {
+		return []teian.ScoredSuggestion{
+			{Suggestion: teian.Suggestion{ID: 1, Username: "mary", Text: "private idea"}},
+			{Suggestion: teian.Suggestion{ID: 2, Username: "mary", Text: "closed idea", Public: true, Status: teian.StatusDone}},
+			{Suggestion: teian.Suggestion{ID: 3, Username: "mary", Text: "public idea", Public: true, Anonymous: true}},
+		}, nil
+	}
+	s.CreateFn = func(ctx context.Context, username string, sugg *teian.Suggestion) error { return nil }
+	app := App{Suggestions: s}
+
+	h := app.handleSubmit("/bad", "/login", "/success")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, newRequest("POST", url.Values{"text": {"an idea"}}, &shimmie.User{Name: "jin"}))
+	if s.CreateInvoked {
+		t.Fatal("handleSubmit created suggestion despite likely duplicates")
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, "public idea") {
+		t.Error("duplicates page does not show the public suggestion")
+	}
+	for _, hidden := range []string{"private idea", "closed idea", "mary"} {
+		if strings.Contains(body, hidden) {
+			t.Errorf("duplicates page shows %q", hidden)
+		}
+	}
+
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, newRequest("POST", url.Values{"text": {"an idea"}, "force": {"1"}}, &shimmie.User{Name: "jin"}))
+	if !s.CreateInvoked {
+		t.Error("handleSubmit with force did not create suggestion")
+	}
+}
+
 func newRequest(method string, form url.Values, u *shimmie.User) *http.Request {
 	var r *http.Request
 	if len(form) == 0 {
@@ -74,7 +172,8 @@ var discardLogger = log.New(ioutil.Discard, "", 0)
 
 func TestApp_handleSubmit_createSuggestionFailure(t *testing.T) {
 	s := &mock.SuggestionStore{}
-	s.CreateFn = func(username string, sugg *teian.Suggestion) error { return fmt.Errorf("boom") }
+	s.CreateFn = func(ctx context.Context, username string, sugg *teian.Suggestion) error { return fmt.Errorf("boom") }
+	s.SimilarFn = noSimilar
 	app := App{Log: discardLogger, Suggestions: s}
 
 	h := app.handleSubmit("/bad", "/login", "/success")
@@ -91,3 +190,224 @@ func TestApp_handleSubmit_createSuggestionFailure(t *testing.T) {
 		t.Errorf("StatusCode = %d, want %d", got, want)
 	}
 }
+
+func TestApp_handleSubmit_overQuota(t *testing.T) {
+	dir, err := ioutil.TempDir("", "teian_attach")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	old := *uploadDir
+	*uploadDir = dir
+	defer func() { *uploadDir = old }()
+
+	s := &mock.SuggestionStore{}
+	s.SimilarFn = noSimilar
+	s.QuotaOverrideFn = func(ctx context.Context, username string) (teian.Quota, error) { return 0, teian.ErrNotFound }
+	s.CheckQuotaFn = func(ctx context.Context, username string, n, limit teian.Quota) (teian.Quota, error) {
+		return 0, fmt.Errorf("check quota: %w", teian.ErrOverQuota)
+	}
+	conf := teian.Conf{MaxAttachments: 2, Quotas: teian.ClassQuotas{"user": teian.MB}}
+	app := App{Log: discardLogger, Suggestions: s, Conf: conf}
+
+	var buf bytes.Buffer
+	mw := multipart.NewWriter(&buf)
+	if err := mw.WriteField("text", "blah"); err != nil {
+		t.Fatal(err)
+	}
+	fw, err := mw.CreateFormFile(attachFormFileName, "screenshot.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fw.Write(pngBytes(t)); err != nil {
+		t.Fatal(err)
+	}
+	if err := mw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest("POST", "/suggest/submit", &buf)
+	r.Header.Set("Content-Type", mw.FormDataContentType())
+	r = r.WithContext(shimmie.NewContextWithUser(r.Context(), &shimmie.User{Name: "jin", Class: "user"}))
+
+	w := httptest.NewRecorder()
+	app.handleSubmit("/bad", "/login", "/success").ServeHTTP(w, r)
+	if got, want := w.Result().StatusCode, http.StatusForbidden; got != want {
+		t.Errorf("handleSubmit over wrapped quota error StatusCode = %d, want %d", got, want)
+	}
+}
+
+func TestApp_serveMine(t *testing.T) {
+	s := &mock.SuggestionStore{}
+	s.OfUserFn = func(ctx context.Context, username string) ([]teian.Suggestion, error) {
+		return []teian.Suggestion{{ID: 1, Username: username, Text: "my idea", Replies: []teian.Reply{{Username: "admin", Text: "on it"}}}}, nil
+	}
+	app := App{Log: discardLogger, Suggestions: s}
+
+	r := newRequest("GET", nil, &shimmie.User{Name: "jin"})
+	w := httptest.NewRecorder()
+	app.serveMine(w, r)
+	resp := w.Result()
+	if got, want := resp.StatusCode, 200; got != want {
+		t.Errorf("StatusCode = %d, want %d", got, want)
+	}
+	body := w.Body.String()
+	for _, want := range []string{"my idea", "on it"} {
+		if !strings.Contains(body, want) {
+			t.Errorf("serveMine body does not contain %q", want)
+		}
+	}
+}
+
+func TestApp_handleEdit(t *testing.T) {
+	now := time.Now()
+	s := &mock.SuggestionStore{}
+	s.GetFn = func(ctx context.Context, id uint64) (*teian.Suggestion, error) {
+		switch id {
+		case 1:
+			return &teian.Suggestion{ID: 1, Username: "jin", Created: now}, nil
+		case 2:
+			return &teian.Suggestion{ID: 2, Username: "jin", Created: now.Add(-time.Hour)}, nil
+		case 3:
+			return &teian.Suggestion{ID: 3, Username: "mary", Created: now}, nil
+		}
+		return nil, teian.ErrNotFound
+	}
+	s.EditFn = func(ctx context.Context, username string, id uint64, text string) error { return nil }
+	app := App{Log: discardLogger, Suggestions: s, Conf: teian.Conf{EditGrace: 15 * time.Minute}}
+
+	tests := []struct {
+		v    url.Values
+		code int
+	}{
+		{url.Values{"id": {"1"}, "text": {"edited"}}, 303},
+		{url.Values{"id": {"2"}, "text": {"edited"}}, 403},
+		{url.Values{"id": {"3"}, "text": {"edited"}}, 404},
+		{url.Values{"id": {"4"}, "text": {"edited"}}, 404},
+		{url.Values{"id": {"1"}, "text": {" "}}, 400},
+		{url.Values{"id": {"x"}, "text": {"edited"}}, 400},
+	}
+	for _, tt := range tests {
+		s.EditInvoked = false
+		w := httptest.NewRecorder()
+		app.handleEdit(w, newRequest("POST", tt.v, &shimmie.User{Name: "jin"}))
+		if got, want := w.Result().StatusCode, tt.code; got != want {
+			t.Errorf("handleEdit(%v) StatusCode = %d, want %d", tt.v, got, want)
+		}
+		if got, want := s.EditInvoked, tt.code == 303; got != want {
+			t.Errorf("handleEdit(%v) EditInvoked = %v, want %v", tt.v, got, want)
+		}
+	}
+}
+
+func TestApp_handleWithdraw_ownerFromSession(t *testing.T) {
+	s := &mock.SuggestionStore{}
+	var owner, by string
+	s.DeleteFn = func(ctx context.Context, username string, id uint64, deletedBy string) error {
+		owner, by = username, deletedBy
+		return nil
+	}
+	app := App{Log: discardLogger, Suggestions: s}
+
+	v := url.Values{"id": {"1"}, "username": {"someone-else"}}
+	w := httptest.NewRecorder()
+	app.handleWithdraw(w, newRequest("POST", v, &shimmie.User{Name: "jin"}))
+	if got, want := w.Result().StatusCode, 303; got != want {
+		t.Errorf("StatusCode = %d, want %d", got, want)
+	}
+	if got, want := owner, "jin"; got != want {
+		t.Errorf("handleWithdraw deleted suggestion of %q, want %q", got, want)
+	}
+	if got, want := by, "jin"; got != want {
+		t.Errorf("handleWithdraw recorded deletion by %q, want %q", got, want)
+	}
+}
+
+func TestApp_handleDelete_requestContext(t *testing.T) {
+	s := &mock.SuggestionStore{}
+	s.DeleteFn = func(ctx context.Context, username string, id uint64, by string) error {
+		return ctx.Err()
+	}
+	app := App{Log: discardLogger, Suggestions: s}
+
+	// The client went away before the store was reached.
+	v := url.Values{"id": {"1"}, "username": {"jin"}}
+	r := newRequest("POST", v, &shimmie.User{Name: "admin", Admin: "Y"})
+	ctx, cancel := context.WithCancel(r.Context())
+	cancel()
+	w := httptest.NewRecorder()
+	app.handleDelete(w, r.WithContext(ctx))
+	if !s.DeleteInvoked {
+		t.Fatal("handleDelete did not call store.Delete")
+	}
+	if got, want := w.Result().StatusCode, 500; got != want {
+		t.Errorf("StatusCode = %d, want %d", got, want)
+	}
+}
+
+func TestApp_handleDelete_storeErrors(t *testing.T) {
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{nil, 302},
+		{teian.ErrNotFound, 404},
+		{teian.Errorf(teian.ErrConflict, "conflict"), 409},
+		{teian.ErrUnknownCategory, 400},
+		{fmt.Errorf("disk on fire"), 500},
+	}
+	for _, tt := range tests {
+		s := &mock.SuggestionStore{}
+		s.DeleteFn = func(ctx context.Context, username string, id uint64, by string) error {
+			return tt.err
+		}
+		app := App{Log: discardLogger, Suggestions: s}
+
+		v := url.Values{"id": {"1"}, "username": {"jin"}}
+		w := httptest.NewRecorder()
+		app.handleDelete(w, newRequest("POST", v, &shimmie.User{Name: "admin", Admin: "Y"}))
+		if got, want := w.Result().StatusCode, tt.code; got != want {
+			t.Errorf("handleDelete with store error %v StatusCode = %d, want %d", tt.err, got, want)
+		}
+	}
+}
+
+func TestAdminQuery(t *testing.T) {
+	r := httptest.NewRequest("GET", "/suggest/admin?u=jin&t=tag&s=planned&cat=uploads&o=ua&from=2016-01-02&to=2016-01-03&c=abc", nil)
+	q, err := adminQuery(r)
+	if err != nil {
+		t.Fatal("adminQuery failed:", err)
+	}
+	want := teian.Query{
+		Username: "jin",
+		Text:     "tag",
+		Statuses: []teian.Status{teian.StatusPlanned},
+		Category: "uploads",
+		Since:    time.Date(2016, 1, 2, 0, 0, 0, 0, time.UTC),
+		Until:    time.Date(2016, 1, 4, 0, 0, 0, 0, time.UTC),
+		Order:    teian.OrderUserAsc,
+		Limit:    adminPageSize,
+		Cursor:   "abc",
+	}
+	if !reflect.DeepEqual(q, want) {
+		t.Errorf("adminQuery = \n%#v, want \n%#v", q, want)
+	}
+
+	for _, bad := range []string{"s=unknown", "from=yesterday", "to=2016-13-01"} {
+		r := httptest.NewRequest("GET", "/suggest/admin?"+bad, nil)
+		if _, err := adminQuery(r); err == nil {
+			t.Errorf("adminQuery(%q) expected to return error", bad)
+		}
+	}
+}
+
+func TestApp_handlePreview(t *testing.T) {
+	app := App{}
+	w := httptest.NewRecorder()
+	app.handlePreview(w, newRequest("POST", url.Values{"text": {"**hi** <b>"}}, &shimmie.User{Name: "jin"}))
+	if got, want := w.Body.String(), "<p><strong>hi</strong> &lt;b&gt;</p>\n"; got != want {
+		t.Errorf("handlePreview body = %q, want %q", got, want)
+	}
+	if got, want := w.Header().Get("Content-Type"), "text/html; charset=utf-8"; got != want {
+		t.Errorf("handlePreview Content-Type = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/kusubooru/shimmie"
+	"github.com/kusubooru/teian/teian"
+)
+
+// quotaLimit returns the upload quota of user which is the override an
+// admin set for them or else the quota of their class.
+func (app *App) quotaLimit(ctx context.Context, user *shimmie.User) (teian.Quota, error) {
+	limit, err := app.Suggestions.QuotaOverride(ctx, user.Name)
+	if errors.Is(err, teian.ErrNotFound) {
+		return app.Conf.Quotas.Limit(user.Class), nil
+	}
+	return limit, err
+}
+
+// formatMB formats q in MB with one decimal.
+func formatMB(q teian.Quota) string {
+	return fmt.Sprintf("%.1f", float64(q)/float64(teian.MB))
+}
+
+// quotaRow is a user on the upload quota page.
+type quotaRow struct {
+	Username string
+	Class    string
+	Used     string
+	Limit    string
+	Override bool
+}
+
+// serveQuota lists how much of their upload quota every user has used and
+// allows an admin to override the quota of a user's class.
+func (app *App) serveQuota(w http.ResponseWriter, r *http.Request) {
+	user, ok := shimmie.FromContextGetUser(r.Context())
+	if !ok || user.Admin != "Y" {
+		http.Error(w, "You are not authorized to view this page.", http.StatusUnauthorized)
+		return
+	}
+	if r.Method == "POST" {
+		var err error
+		username := r.PostFormValue("username")
+		switch r.PostFormValue("action") {
+		case "set":
+			mb, perr := strconv.ParseInt(r.PostFormValue("limit"), 10, 64)
+			if perr != nil {
+				http.Error(w, fmt.Sprintf("bad limit provided: %v", perr), http.StatusBadRequest)
+				return
+			}
+			err = app.Suggestions.SetQuotaOverride(r.Context(), username, teian.Quota(mb)*teian.MB)
+		case "remove":
+			err = app.Suggestions.RemoveQuotaOverride(r.Context(), username)
+		default:
+			http.Error(w, "unknown action", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("change quota failed: %v", err), storeStatus(err))
+			return
+		}
+		http.Redirect(w, r, "/suggest/admin/quota", http.StatusFound)
+		return
+	}
+
+	usage, err := app.Suggestions.QuotaUsage(r.Context())
+	if err != nil {
+		app.storeError(w, err, "could not get quota usage")
+		return
+	}
+	// Users without an override have the quota of their class.
+	var usernames []string
+	for _, u := range usage {
+		if !u.Override {
+			usernames = append(usernames, u.Username)
+		}
+	}
+	classes, err := app.userClasses(usernames)
+	if err != nil {
+		app.Errorf(w, http.StatusInternalServerError, err, "could not get user classes")
+		return
+	}
+	rows := make([]quotaRow, 0, len(usage))
+	for _, u := range usage {
+		row := quotaRow{Username: u.Username, Used: formatMB(u.Used), Override: u.Override}
+		if u.Override {
+			row.Limit = formatMB(u.Limit)
+		} else {
+			row.Class = classes[u.Username]
+			row.Limit = formatMB(app.Conf.Quotas.Limit(row.Class))
+		}
+		rows = append(rows, row)
+	}
+	data := struct {
+		Rows   []quotaRow
+		Quotas teian.ClassQuotas
+	}{
+		Rows:   rows,
+		Quotas: app.Conf.Quotas,
+	}
+	app.render(w, quotaTmpl, data)
+}
+
+// userClasses returns the shimmie class of each of usernames with a single
+// query. Users that do not exist are left out.
+func (app *App) userClasses(usernames []string) (map[string]string, error) {
+	classes := make(map[string]string, len(usernames))
+	if len(usernames) == 0 {
+		return classes, nil
+	}
+	args := make([]interface{}, len(usernames))
+	for i, username := range usernames {
+		args[i] = username
+	}
+	rows, err := app.Shimmie.SQLDB().Query(`SELECT name, class FROM users WHERE name IN (?`+strings.Repeat(", ?", len(usernames)-1)+`)`, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var name, class string
+		if err := rows.Scan(&name, &class); err != nil {
+			return nil, err
+		}
+		classes[name] = class
+	}
+	return classes, rows.Err()
+}
+
+var quotaTmpl = template.Must(template.New("quotaTmpl").Parse(baseTemplate + subnavTemplate + quotaTemplate))
+
+const quotaTemplate = `
+{{define "content"}}
+<div class="suggestion-form">
+	<p>Upload quota in MB per 24 hours of each class: {{.Data.Quotas}}</p>
+	<table>
+		<tr><th>User</th><th>Class</th><th>Used MB</th><th>Limit MB</th><th></th></tr>
+		{{ range .Data.Rows }}
+		<tr>
+			<td>{{.Username}}</td>
+			<td>{{if .Override}}override{{else}}{{.Class}}{{end}}</td>
+			<td>{{.Used}}</td>
+			<td>{{.Limit}}</td>
+			<td>
+				{{ if .Override }}
+				<form method="post" action="/suggest/admin/quota">
+					<input type="hidden" name="action" value="remove">
+					<input type="hidden" name="username" value="{{.Username}}">
+					<input type="submit" value="Use class quota">
+				</form>
+				{{ end }}
+			</td>
+		</tr>
+		{{ else }}
+		<tr><td colspan="5">No user has uploaded anything in the last 24 hours.</td></tr>
+		{{ end }}
+	</table>
+	<form method="post" action="/suggest/admin/quota">
+		<input type="hidden" name="action" value="set">
+		<label for="username">User</label>
+		<input type="text" id="username" name="username" required>
+		<label for="limit">Limit MB</label>
+		<input type="number" id="limit" name="limit" min="0" required>
+		<input type="submit" value="Override">
+	</form>
+</div>
+{{end}}
+`
@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/kusubooru/shimmie"
+	"github.com/kusubooru/teian/internal/mock"
+	"github.com/kusubooru/teian/teian"
+)
+
+func TestApp_quotaLimit(t *testing.T) {
+	s := &mock.SuggestionStore{}
+	s.QuotaOverrideFn = func(ctx context.Context, username string) (teian.Quota, error) {
+		if username == "jin" {
+			return 0, nil
+		}
+		return 0, teian.ErrNotFound
+	}
+	conf := teian.Conf{Quotas: teian.ClassQuotas{"admin": 1000 * teian.MB, "user": 200 * teian.MB}}
+	app := App{Log: discardLogger, Suggestions: s, Conf: conf}
+
+	tests := []struct {
+		user *shimmie.User
+		want teian.Quota
+	}{
+		{&shimmie.User{Name: "mary", Class: "admin"}, 1000 * teian.MB},
+		{&shimmie.User{Name: "john", Class: "user"}, 200 * teian.MB},
+		{&shimmie.User{Name: "ghost", Class: "ghost"}, 0},
+		// An override of zero stops a user of any class from uploading.
+		{&shimmie.User{Name: "jin", Class: "admin"}, 0},
+	}
+	for _, tt := range tests {
+		got, err := app.quotaLimit(context.Background(), tt.user)
+		if err != nil {
+			t.Fatal("quotaLimit failed:", err)
+		}
+		if got != tt.want {
+			t.Errorf("quotaLimit(%q of class %q) = %d, want %d", tt.user.Name, tt.user.Class, got, tt.want)
+		}
+	}
+}
+
+func TestApp_serveQuota(t *testing.T) {
+	s := &mock.SuggestionStore{}
+	s.QuotaUsageFn = func(ctx context.Context) ([]teian.QuotaUsage, error) {
+		return []teian.QuotaUsage{{Username: "mary", Used: 3 * teian.MB / 2, Limit: 20 * teian.MB, Override: true}}, nil
+	}
+	var set teian.Quota
+	s.SetQuotaOverrideFn = func(ctx context.Context, username string, limit teian.Quota) error {
+		if username == "" {
+			return teian.Errorf(teian.ErrInvalid, "invalid quota")
+		}
+		set = limit
+		return nil
+	}
+	app := App{Log: discardLogger, Suggestions: s}
+	admin := shimmie.NewContextWithUser(context.Background(), &shimmie.User{Name: "admin", Admin: "Y"})
+
+	w := httptest.NewRecorder()
+	app.serveQuota(w, httptest.NewRequest("GET", "/suggest/admin/quota", nil).WithContext(admin))
+	if got, want := w.Result().StatusCode, 200; got != want {
+		t.Fatalf("StatusCode = %d, want %d", got, want)
+	}
+	for _, want := range []string{"mary", "1.5", "20.0"} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("serveQuota body does not contain %q", want)
+		}
+	}
+
+	tests := []struct {
+		form   url.Values
+		status int
+	}{
+		{url.Values{"action": {"set"}, "username": {"mary"}, "limit": {"50"}}, 302},
+		{url.Values{"action": {"set"}, "username": {"mary"}, "limit": {"lots"}}, 400},
+		{url.Values{"action": {"set"}, "username": {""}, "limit": {"50"}}, 400},
+		{url.Values{"action": {"grant"}, "username": {"mary"}}, 400},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("POST", "/suggest/admin/quota", strings.NewReader(tt.form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		w := httptest.NewRecorder()
+		app.serveQuota(w, req.WithContext(admin))
+		if got := w.Result().StatusCode; got != tt.status {
+			t.Errorf("serveQuota POST %v StatusCode = %d, want %d", tt.form, got, tt.status)
+		}
+	}
+	if got, want := set, 50*teian.MB; got != want {
+		t.Errorf("serveQuota set override %d, want %d", got, want)
+	}
+}
+
+// sqlShimmie is a shimmie store of which only the database is used.
+type sqlShimmie struct {
+	shimmie.Store
+	db *sql.DB
+}
+
+func (s sqlShimmie) SQLDB() *sql.DB { return s.db }
+
+func TestApp_serveQuota_classes(t *testing.T) {
+	f, err := ioutil.TempFile("", "teian_shimmie_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+	db, err := sql.Open("sqlite3", f.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.Exec(`CREATE TABLE users (name TEXT, class TEXT)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Exec(`INSERT INTO users (name, class) VALUES ('john', 'user'), ('mary', 'admin')`); err != nil {
+		t.Fatal(err)
+	}
+
+	s := &mock.SuggestionStore{}
+	s.QuotaUsageFn = func(ctx context.Context) ([]teian.QuotaUsage, error) {
+		return []teian.QuotaUsage{{Username: "john", Used: teian.MB}, {Username: "mary", Used: teian.MB}, {Username: "ghost", Used: teian.MB}}, nil
+	}
+	conf := teian.Conf{Quotas: teian.ClassQuotas{"admin": 1000 * teian.MB, "user": 200 * teian.MB}}
+	app := App{Log: discardLogger, Suggestions: s, Conf: conf, Shimmie: shimmie.New("", "", sqlShimmie{db: db})}
+	admin := shimmie.NewContextWithUser(context.Background(), &shimmie.User{Name: "admin", Admin: "Y"})
+
+	w := httptest.NewRecorder()
+	app.serveQuota(w, httptest.NewRequest("GET", "/suggest/admin/quota", nil).WithContext(admin))
+	if got, want := w.Result().StatusCode, 200; got != want {
+		t.Fatalf("StatusCode = %d, want %d", got, want)
+	}
+	for _, want := range []string{"200.0", "1000.0"} {
+		if !strings.Contains(w.Body.String(), want) {
+			t.Errorf("serveQuota body does not contain class quota %q", want)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+
+	"github.com/kusubooru/shimmie"
+	"github.com/kusubooru/teian/teian"
+)
+
+// revisionView is a revision along with its differences from the previous
+// one.
+type revisionView struct {
+	teian.Revision
+	N    int
+	Diff []teian.DiffPart
+}
+
+// serveRevisions shows the revisions of the text of a suggestion to an admin
+// with the differences between each and the previous one, word by word or,
+// with mode=line, line by line.
+func (app *App) serveRevisions(w http.ResponseWriter, r *http.Request) {
+	user, ok := shimmie.FromContextGetUser(r.Context())
+	if !ok || user.Admin != "Y" {
+		http.Error(w, "You are not authorized to view this page.", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseUint(r.FormValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("bad id provided: %v", err), http.StatusBadRequest)
+		return
+	}
+	revs, err := app.Suggestions.Revisions(r.Context(), id)
+	if err != nil {
+		app.storeError(w, err, "could not get revisions")
+		return
+	}
+	diff := teian.DiffWords
+	mode := "word"
+	if r.FormValue("mode") == "line" {
+		diff = teian.DiffLines
+		mode = "line"
+	}
+	views := make([]revisionView, len(revs))
+	for i, rev := range revs {
+		old := ""
+		if i > 0 {
+			old = revs[i-1].Text
+		}
+		views[i] = revisionView{Revision: rev, N: i, Diff: diff(old, rev.Text)}
+	}
+	// newest first
+	for i, j := 0, len(views)-1; i < j; i, j = i+1, j-1 {
+		views[i], views[j] = views[j], views[i]
+	}
+	data := struct {
+		ID        uint64
+		Mode      string
+		Revisions []revisionView
+	}{
+		ID:        id,
+		Mode:      mode,
+		Revisions: views,
+	}
+	app.render(w, revisionsTmpl, data)
+}
+
+// handleRevert allows an admin to revert the text of a suggestion to one of
+// its revisions.
+func (app *App) handleRevert(w http.ResponseWriter, r *http.Request) {
+	// only accept POST method
+	if r.Method != "POST" {
+		http.Error(w, fmt.Sprintf("%v method not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := shimmie.FromContextGetUser(r.Context())
+	if !ok || user.Admin != "Y" {
+		http.Error(w, "You are not authorized to perform this action.", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseUint(r.PostFormValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("bad id provided: %v", err), http.StatusBadRequest)
+		return
+	}
+	rev, err := strconv.Atoi(r.PostFormValue("rev"))
+	if err != nil {
+		http.Error(w, fmt.Sprintf("bad revision provided: %v", err), http.StatusBadRequest)
+		return
+	}
+	if err := app.Suggestions.Revert(r.Context(), id, rev, user.Name); err != nil {
+		http.Error(w, fmt.Sprintf("revert suggestion failed: %v", err), storeStatus(err))
+		return
+	}
+	http.Redirect(w, r, fmt.Sprintf("/suggest/admin/revisions?id=%d", id), http.StatusFound)
+}
+
+var revisionsTmpl = template.Must(template.New("revisionsTmpl").Parse(baseTemplate + subnavTemplate + revisionsTemplate))
+
+const revisionsTemplate = `
+{{define "css"}}
+.diff {
+	white-space: pre-wrap;
+	font-family: monospace;
+	font-size: 110%;
+	max-width: 70em;
+}
+.diff del {
+	background: #f2dede;
+	color: #a94442;
+}
+.diff ins {
+	background: #dff0d8;
+	color: #3c763d;
+	text-decoration: none;
+}
+{{end}}
+{{define "toolbar"}}
+<div class="toolbar">
+	Suggestion #{{.Data.ID}}:
+	{{if eq .Data.Mode "line"}}
+	<a href="/suggest/admin/revisions?id={{.Data.ID}}">Word diff</a>
+	{{else}}
+	<a href="/suggest/admin/revisions?id={{.Data.ID}}&mode=line">Line diff</a>
+	{{end}}
+</div>
+{{end}}
+{{define "content"}}
+{{ $id := .Data.ID }}
+{{ range $i, $v := .Data.Revisions }}
+	<div class="suggestion">
+		<span>Revision {{$v.N}}, {{$v.FmtAt}} by <a href="/user/{{$v.By}}">{{$v.By}}</a></span>
+		{{if $i}}
+		<form method="post" action="/suggest/admin/revert">
+			<input type="hidden" name="id" value="{{$id}}">
+			<input type="hidden" name="rev" value="{{$v.N}}">
+			<input type="submit" value="Revert to this revision">
+		</form>
+		{{end}}
+		<div class="diff">{{range $v.Diff}}{{if .Delete}}<del>{{.Text}}</del>{{else if .Insert}}<ins>{{.Text}}</ins>{{else}}{{.Text}}{{end}}{{end}}</div>
+	</div>
+{{ end }}
+{{end}}
+`
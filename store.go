@@ -0,0 +1,43 @@
+package main
+
+import (
+	"log"
+
+	"github.com/kusubooru/teian/teian"
+	"github.com/kusubooru/teian/teian/boltstore"
+	"github.com/kusubooru/teian/teian/memstore"
+	"github.com/kusubooru/teian/teian/sqlstore"
+
+	// Driver of the sqlite3 -storedriver. The mysql driver is imported by
+	// the shimmie store.
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// suggestionStore is a teian.SuggestionStore that the program can close.
+type suggestionStore interface {
+	teian.SuggestionStore
+	Close()
+}
+
+// openSuggestionStore opens the suggestion store selected by -store. The
+// sql store uses the shimmie database unless -storedriver or -storeconfig
+// are given.
+func openSuggestionStore() suggestionStore {
+	switch *storeKind {
+	case "bolt":
+		return boltstore.NewSuggestionStore(*boltFile)
+	case "sql":
+		driver, config := *sqlDriver, *sqlConfig
+		if driver == "" {
+			driver = *dbDriver
+		}
+		if config == "" {
+			config = *dbConfig
+		}
+		return sqlstore.NewSuggestionStore(driver, config)
+	case "mem":
+		return memstore.NewSuggestionStore()
+	}
+	log.Fatalf("unknown -store %q, must be bolt, sql or mem", *storeKind)
+	return nil
+}
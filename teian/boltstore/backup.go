@@ -0,0 +1,170 @@
+package boltstore
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/boltdb/bolt"
+)
+
+// lockTimeout is how long to wait for other processes to release a
+// database before giving up.
+var lockTimeout = 5 * time.Second
+
+// knownBuckets are the buckets a teian database can have.
+var knownBuckets = map[string]bool{
+	suggestionsBucket:     true,
+	userSuggestionsBucket: true,
+	searchIndexBucket:     true,
+	votesBucket:           true,
+	voteScoresBucket:      true,
+	categoriesBucket:      true,
+	categoryIndexBucket:   true,
+	revisionsBucket:       true,
+	trashBucket:           true,
+	quotaBucket:           true,
+	quotaOverridesBucket:  true,
+	metaBucket:            true,
+}
+
+// Backup writes a consistent copy of the database to w. It runs in a read
+// transaction so the store can keep being used while the copy is written.
+func (db *Boltstore) Backup(w io.Writer) (n int64, err error) {
+	err = db.View(func(tx *bolt.Tx) error {
+		n, err = tx.WriteTo(w)
+		return err
+	})
+	return n, err
+}
+
+// BackupFile writes a copy of the database at path to w. The database must
+// not be in use by another process.
+func BackupFile(path string, w io.Writer) (int64, error) {
+	if _, err := os.Stat(path); err != nil {
+		return 0, err
+	}
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: lockTimeout, ReadOnly: true})
+	if err != nil {
+		return 0, fmt.Errorf("open %s: %v", path, err)
+	}
+	defer db.Close()
+	return (&Boltstore{DB: db}).Backup(w)
+}
+
+// Verify checks that the file at path is a teian database. It must only
+// have the buckets teian uses, at least the suggestions bucket, and every
+// suggestion in it must decode. Its schema version must not be newer than
+// LatestVersion. Databases of older versions are valid since they are
+// migrated when they are opened.
+func Verify(path string) error {
+	if _, err := os.Stat(path); err != nil {
+		return err
+	}
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: lockTimeout, ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("open %s: %v", path, err)
+	}
+	defer db.Close()
+	return db.View(func(tx *bolt.Tx) error {
+		err := tx.ForEach(func(name []byte, _ *bolt.Bucket) error {
+			if !knownBuckets[string(name)] {
+				return fmt.Errorf("unknown bucket %q", name)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		version := getVersion(tx)
+		if version > LatestVersion() {
+			return fmt.Errorf("database is at version %d but only versions up to %d are supported", version, LatestVersion())
+		}
+		for _, name := range []string{suggestionsBucket, trashBucket} {
+			b := tx.Bucket([]byte(name))
+			if b == nil {
+				if name == suggestionsBucket {
+					return fmt.Errorf("bucket %q is missing", name)
+				}
+				continue
+			}
+			err := b.ForEach(func(k, v []byte) error {
+				if len(k) != 8 {
+					// Suggestions of the first version are kept per user.
+					if version == 0 && name == suggestionsBucket && k[0] != 0 {
+						return nil
+					}
+					return fmt.Errorf("bucket %q: bad key %x", name, k)
+				}
+				if _, err := decodeSuggestion(v); err != nil {
+					return fmt.Errorf("bucket %q: suggestion %d: %v", name, btoi(k), err)
+				}
+				return nil
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Restore replaces the database at path with the backup at backup after
+// verifying it. The database must not be in use by another process. The
+// replaced database, if any, is kept next to it with a .before-restore
+// suffix.
+func Restore(backup, path string) error {
+	if err := Verify(backup); err != nil {
+		return fmt.Errorf("bad backup: %v", err)
+	}
+	if _, err := os.Stat(path); err == nil {
+		// Make sure no server is using the database while it is replaced.
+		db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: lockTimeout})
+		if err != nil {
+			return fmt.Errorf("open %s: %v", path, err)
+		}
+		if err := db.Close(); err != nil {
+			return err
+		}
+	}
+
+	// Copy the backup next to the database first so that the swap is a
+	// rename and the database is never left half written.
+	tmp, err := os.OpenFile(path+".restore", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	src, err := os.Open(backup)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	_, err = io.Copy(tmp, src)
+	src.Close()
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return fmt.Errorf("copy backup: %v", err)
+	}
+
+	if _, err := os.Stat(path); err == nil {
+		if err := os.Rename(path, path+".before-restore"); err != nil {
+			return err
+		}
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+	if d, err := os.Open(filepath.Dir(path)); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package boltstore
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/kusubooru/teian/teian"
+)
+
+func TestBackupRestore(t *testing.T) {
+	store, f := setup()
+	defer teardown(store, f)
+	ctx := context.Background()
+
+	if err := store.Create(ctx, "john", &teian.Suggestion{Text: "back me up"}); err != nil {
+		t.Fatal("store.Create failed:", err)
+	}
+	dir, err := ioutil.TempDir("", "teian_backup_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The backup is taken while the store is open.
+	var buf bytes.Buffer
+	n, err := store.Backup(&buf)
+	if err != nil {
+		t.Fatal("store.Backup failed:", err)
+	}
+	if n != int64(buf.Len()) || n == 0 {
+		t.Errorf("store.Backup reported %d bytes, wrote %d", n, buf.Len())
+	}
+	backup := filepath.Join(dir, "backup.db")
+	if err := ioutil.WriteFile(backup, buf.Bytes(), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := Verify(backup); err != nil {
+		t.Fatal("Verify of backup failed:", err)
+	}
+
+	path := filepath.Join(dir, "teian.db")
+	other := NewSuggestionStore(path)
+	if err := other.Create(ctx, "mary", &teian.Suggestion{Text: "replaced"}); err != nil {
+		t.Fatal("store.Create failed:", err)
+	}
+	defer func(d time.Duration) { lockTimeout = d }(lockTimeout)
+	lockTimeout = 100 * time.Millisecond
+	if err := Restore(backup, path); err == nil {
+		t.Error("Restore of a database in use expected to return error")
+	}
+	other.Close()
+
+	if err := Restore(backup, path); err != nil {
+		t.Fatal("Restore failed:", err)
+	}
+	restored := NewSuggestionStore(path)
+	defer restored.Close()
+	s, err := restored.Get(ctx, 1)
+	if err != nil {
+		t.Fatal("Get from restored store failed:", err)
+	}
+	if s.Text != "back me up" {
+		t.Errorf("restored suggestion has text %q, want %q", s.Text, "back me up")
+	}
+	if err := Verify(path + ".before-restore"); err != nil {
+		t.Error("replaced database was not kept:", err)
+	}
+}
+
+func TestVerify_invalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "teian_backup_")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	write := func(name string, fn func(tx *bolt.Tx) error) string {
+		path := filepath.Join(dir, name)
+		db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := db.Update(fn); err != nil {
+			t.Fatal(err)
+		}
+		db.Close()
+		return path
+	}
+	tests := map[string]string{
+		"other.db": write("other.db", func(tx *bolt.Tx) error {
+			_, err := tx.CreateBucket([]byte("images"))
+			return err
+		}),
+		"empty.db": write("empty.db", func(tx *bolt.Tx) error { return nil }),
+		"corrupt.db": write("corrupt.db", func(tx *bolt.Tx) error {
+			b, err := tx.CreateBucket([]byte(suggestionsBucket))
+			if err != nil {
+				return err
+			}
+			return b.Put(itob(1), []byte("not gob"))
+		}),
+	}
+	notBolt := filepath.Join(dir, "text.db")
+	if err := ioutil.WriteFile(notBolt, []byte("hello"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	tests["text.db"] = notBolt
+	tests["missing.db"] = filepath.Join(dir, "missing.db")
+
+	for name, path := range tests {
+		if err := Verify(path); err == nil {
+			t.Errorf("Verify(%s) expected to return error", name)
+		}
+		if err := Restore(path, filepath.Join(dir, "teian.db")); err == nil {
+			t.Errorf("Restore(%s) expected to return error", name)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "teian.db")); !os.IsNotExist(err) {
+		t.Error("Restore of invalid backups created the database")
+	}
+}
@@ -1,29 +1,36 @@
 package boltstore
 
 import (
+	"context"
 	"log"
-	"time"
 
 	"github.com/boltdb/bolt"
-	"github.com/kusubooru/teian/teian"
 )
 
 const (
-	suggestionsBucket = "suggestions"
-	quotaBucket       = "uploadQuota"
+	suggestionsBucket     = "suggestions"
+	userSuggestionsBucket = "userSuggestions"
+	searchIndexBucket     = "searchIndex"
+	votesBucket           = "votes"
+	voteScoresBucket      = "voteScores"
+	categoriesBucket      = "categories"
+	categoryIndexBucket   = "categorySuggestions"
+	revisionsBucket       = "revisions"
+	trashBucket           = "trash"
+	quotaBucket           = "uploadQuota"
+	quotaOverridesBucket  = "quotaOverrides"
 )
 
 type Boltstore struct {
 	*bolt.DB
-	userQuota teian.Quota
 }
 
 // NewSuggestionStore opens the bolt database file and returns an
 // implementation of teian.SuggestionStore. The bolt database file will be
-// created if it does not exist.
-func NewSuggestionStore(boltFile string, userQuota teian.Quota) *Boltstore {
-	boltdb := openBolt(boltFile)
-	return &Boltstore{boltdb, userQuota}
+// created if it does not exist and upgraded to the latest schema version if
+// it is older.
+func NewSuggestionStore(boltFile string) *Boltstore {
+	return &Boltstore{openBolt(boltFile)}
 }
 
 // Close releases all database resources.
@@ -33,24 +40,37 @@ func (db *Boltstore) Close() {
 	}
 }
 
+// viewTx runs fn in a read-only transaction unless ctx is already done.
+// Bolt transactions cannot be interrupted so ctx is only checked before one
+// starts.
+func (db *Boltstore) viewTx(ctx context.Context, fn func(*bolt.Tx) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return db.View(fn)
+}
+
+// updateTx runs fn in a read-write transaction unless ctx is already done.
+// The check happens before waiting for the write lock, which can take long
+// when another writer holds it.
+func (db *Boltstore) updateTx(ctx context.Context, fn func(*bolt.Tx) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return db.Update(fn)
+}
+
 // openBolt creates and opens a bolt database at the given path. If the file does
-// not exist then it will be created automatically. After opening it creates
-// all the needed buckets.
+// not exist then it will be created automatically. After opening it runs the
+// migrations the database is missing which also create all the needed
+// buckets.
 func openBolt(file string) *bolt.DB {
-	db, err := bolt.Open(file, 0600, &bolt.Options{Timeout: 5 * time.Second})
+	db, err := bolt.Open(file, 0600, &bolt.Options{Timeout: lockTimeout})
 	if err != nil {
 		log.Fatalln("bolt open failed:", err)
 	}
-	err = db.Update(func(tx *bolt.Tx) error {
-		_, err = tx.CreateBucketIfNotExists([]byte(suggestionsBucket))
-		if err != nil {
-			return err
-		}
-		_, err = tx.CreateBucketIfNotExists([]byte(quotaBucket))
-		return err
-	})
-	if err != nil {
-		log.Fatalln("bolt bucket creation failed:", err)
+	if err := migrate(db); err != nil {
+		log.Fatalln("bolt migration failed:", err)
 	}
 	return db
 }
@@ -0,0 +1,77 @@
+package boltstore
+
+import (
+	"context"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/kusubooru/teian/teian"
+)
+
+func (db *Boltstore) Bulk(ctx context.Context, ids []uint64, op teian.BulkOp) (map[uint64]error, error) {
+	var apply func(tx *bolt.Tx, id uint64) error
+	switch op.Action {
+	case teian.BulkDelete:
+		apply = func(tx *bolt.Tx, id uint64) error {
+			return trashSuggestion(tx, "", id, op.By)
+		}
+	case teian.BulkStatus:
+		now := time.Now()
+		apply = func(tx *bolt.Tx, id uint64) error {
+			return updateSuggestion(tx, "", id, func(s *teian.Suggestion) bool {
+				return s.SetStatus(op.Status, op.By, now)
+			})
+		}
+	case teian.BulkCategory:
+		apply = func(tx *bolt.Tx, id uint64) error {
+			return updateSuggestion(tx, "", id, func(s *teian.Suggestion) bool {
+				if s.Category == op.Category {
+					return false
+				}
+				s.Category = op.Category
+				return true
+			})
+		}
+	default:
+		return nil, teian.ErrBadBulkAction
+	}
+
+	failed := make(map[uint64]error)
+	err := db.updateTx(ctx, func(tx *bolt.Tx) error {
+		if op.Action == teian.BulkCategory {
+			if err := checkCategory(tx, op.Category); err != nil {
+				return err
+			}
+		}
+		for _, id := range ids {
+			if err := apply(tx, id); err != nil {
+				failed[id] = err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return failed, nil
+}
+
+func (db *Boltstore) GetMany(ctx context.Context, ids []uint64) ([]teian.Suggestion, map[uint64]error, error) {
+	var suggs []teian.Suggestion
+	failed := make(map[uint64]error)
+	err := db.viewTx(ctx, func(tx *bolt.Tx) error {
+		for _, id := range ids {
+			s, err := getSuggestion(tx, "", id)
+			if err != nil {
+				failed[id] = err
+				continue
+			}
+			suggs = append(suggs, *s)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	return suggs, failed, nil
+}
@@ -0,0 +1,68 @@
+package boltstore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+func TestBulk(t *testing.T) {
+	store, f := setup()
+	defer teardown(store, f)
+	ctx := context.Background()
+
+	for i := 0; i < 4; i++ {
+		if err := store.Create(ctx, "john", &teian.Suggestion{Text: "bulk test"}); err != nil {
+			t.Fatal("store.Create failed:", err)
+		}
+	}
+	if err := store.AddCategory(ctx, "uploads"); err != nil {
+		t.Fatal("store.AddCategory failed:", err)
+	}
+
+	failed, err := store.Bulk(ctx, []uint64{1, 2, 9}, teian.BulkOp{Action: teian.BulkStatus, Status: teian.StatusPlanned, By: "admin"})
+	if err != nil {
+		t.Fatal("store.Bulk status failed:", err)
+	}
+	if len(failed) != 1 || failed[9] == nil {
+		t.Errorf("store.Bulk status failed for %v, want only 9", failed)
+	}
+	failed, err = store.Bulk(ctx, []uint64{2, 3}, teian.BulkOp{Action: teian.BulkCategory, Category: "uploads", By: "admin"})
+	if err != nil || len(failed) != 0 {
+		t.Fatalf("store.Bulk category = %v, %v", failed, err)
+	}
+	if _, err := store.Bulk(ctx, []uint64{1}, teian.BulkOp{Action: teian.BulkCategory, Category: "nope"}); err != teian.ErrUnknownCategory {
+		t.Errorf("store.Bulk to unknown category returned %v, want %v", err, teian.ErrUnknownCategory)
+	}
+	failed, err = store.Bulk(ctx, []uint64{3, 4}, teian.BulkOp{Action: teian.BulkDelete, By: "admin"})
+	if err != nil || len(failed) != 0 {
+		t.Fatalf("store.Bulk delete = %v, %v", failed, err)
+	}
+	if _, err := store.Bulk(ctx, []uint64{1}, teian.BulkOp{}); err != teian.ErrBadBulkAction {
+		t.Errorf("store.Bulk without action returned %v, want %v", err, teian.ErrBadBulkAction)
+	}
+
+	all, err := store.All(ctx)
+	if err != nil {
+		t.Fatal("store.All failed:", err)
+	}
+	want := []struct {
+		status   teian.Status
+		category string
+	}{
+		{teian.StatusPlanned, ""},
+		{teian.StatusPlanned, "uploads"},
+	}
+	if len(all) != len(want) {
+		t.Fatalf("store.All after bulk delete returned %d suggestions, want %d", len(all), len(want))
+	}
+	for i, w := range want {
+		if all[i].Status != w.status || all[i].Category != w.category {
+			t.Errorf("suggestion %d has status %v and category %q, want %v and %q", all[i].ID, all[i].Status, all[i].Category, w.status, w.category)
+		}
+	}
+	if trash, err := store.Trash(ctx); err != nil || len(trash) != 2 || trash[0].DeletedBy != "admin" {
+		t.Errorf("store.Trash after bulk delete = %v, %v", trash, err)
+	}
+}
@@ -0,0 +1,117 @@
+package boltstore
+
+import (
+	"bytes"
+	"context"
+	"strings"
+
+	"github.com/boltdb/bolt"
+	"github.com/kusubooru/teian/teian"
+)
+
+// The categories bucket holds the defined category names as keys. The
+// categorySuggestions bucket indexes categorized suggestions with keys of
+// the form category + "\x00" + big-endian ID and empty values.
+
+func categoryKey(category string, id uint64) []byte {
+	return append(append([]byte(category), 0), itob(id)...)
+}
+
+func indexCategory(tx *bolt.Tx, id uint64, category string) error {
+	if category == "" {
+		return nil
+	}
+	return tx.Bucket([]byte(categoryIndexBucket)).Put(categoryKey(category, id), []byte{})
+}
+
+func unindexCategory(tx *bolt.Tx, id uint64, category string) error {
+	if category == "" {
+		return nil
+	}
+	return tx.Bucket([]byte(categoryIndexBucket)).Delete(categoryKey(category, id))
+}
+
+// checkCategory returns teian.ErrUnknownCategory if category is not empty
+// and has not been defined.
+func checkCategory(tx *bolt.Tx, category string) error {
+	if category != "" && tx.Bucket([]byte(categoriesBucket)).Get([]byte(category)) == nil {
+		return teian.ErrUnknownCategory
+	}
+	return nil
+}
+
+func (db *Boltstore) SetCategory(ctx context.Context, username string, id uint64, category string) error {
+	return db.updateTx(ctx, func(tx *bolt.Tx) error {
+		if err := checkCategory(tx, category); err != nil {
+			return err
+		}
+		return updateSuggestion(tx, username, id, func(s *teian.Suggestion) bool {
+			if s.Category == category {
+				return false
+			}
+			s.Category = category
+			return true
+		})
+	})
+}
+
+func (db *Boltstore) Categories(ctx context.Context) ([]string, error) {
+	var names []string
+	err := db.viewTx(ctx, func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(categoriesBucket)).ForEach(func(k, _ []byte) error {
+			names = append(names, string(k))
+			return nil
+		})
+	})
+	return names, err
+}
+
+func (db *Boltstore) AddCategory(ctx context.Context, name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" || strings.ContainsRune(name, 0) {
+		return teian.Errorf(teian.ErrInvalid, "invalid category name")
+	}
+	return db.updateTx(ctx, func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(categoriesBucket)).Put([]byte(name), []byte{})
+	})
+}
+
+// RemoveCategory deletes the category name and leaves the suggestions that
+// were in it uncategorized.
+func (db *Boltstore) RemoveCategory(ctx context.Context, name string) error {
+	return db.updateTx(ctx, func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(categoriesBucket))
+		if b.Get([]byte(name)) == nil {
+			return teian.ErrNotFound
+		}
+		var ids []uint64
+		prefix := append([]byte(name), 0)
+		c := tx.Bucket([]byte(categoryIndexBucket)).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			ids = append(ids, btoi(k[len(prefix):]))
+		}
+		for _, id := range ids {
+			err := updateSuggestion(tx, "", id, func(s *teian.Suggestion) bool {
+				s.Category = ""
+				return true
+			})
+			if err != nil {
+				return err
+			}
+		}
+		return b.Delete([]byte(name))
+	})
+}
+
+func (db *Boltstore) CategoryCounts(ctx context.Context) (map[string]int, error) {
+	counts := make(map[string]int)
+	err := db.viewTx(ctx, func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(categoryIndexBucket)).ForEach(func(k, _ []byte) error {
+			if i := bytes.LastIndexByte(k[:len(k)-8], 0); i != -1 {
+				counts[string(k[:i])]++
+			}
+			return nil
+		})
+	})
+	return counts, err
+}
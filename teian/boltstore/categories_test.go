@@ -0,0 +1,86 @@
+package boltstore
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+func TestCategories(t *testing.T) {
+	store, f := setup()
+	defer teardown(store, f)
+	ctx := context.Background()
+
+	for _, name := range []string{"uploads", " site bug ", "tagging"} {
+		if err := store.AddCategory(ctx, name); err != nil {
+			t.Fatalf("store.AddCategory(ctx, %q) failed: %v", name, err)
+		}
+	}
+	if err := store.AddCategory(ctx, "  "); err == nil {
+		t.Error("store.AddCategory with blank name expected to return error")
+	}
+	got, err := store.Categories(ctx)
+	if err != nil {
+		t.Fatal("store.Categories failed:", err)
+	}
+	if want := []string{"site bug", "tagging", "uploads"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("store.Categories = %q, want %q", got, want)
+	}
+
+	suggs := []*teian.Suggestion{
+		{Text: "more tags", Category: "tagging"},
+		{Text: "bigger files", Category: "uploads"},
+		{Text: "broken link", Category: "tagging"},
+		{Text: "hello"},
+	}
+	for _, s := range suggs {
+		if err := store.Create(ctx, "john", s); err != nil {
+			t.Fatal("store.Create failed:", err)
+		}
+	}
+	if err := store.Create(ctx, "john", &teian.Suggestion{Category: "nope"}); err != teian.ErrUnknownCategory {
+		t.Errorf("store.Create with unknown category returned %v, want %v", err, teian.ErrUnknownCategory)
+	}
+	if err := store.SetCategory(ctx, "john", 3, "nope"); err != teian.ErrUnknownCategory {
+		t.Errorf("store.SetCategory to unknown category returned %v, want %v", err, teian.ErrUnknownCategory)
+	}
+	if err := store.SetCategory(ctx, "john", 3, "site bug"); err != nil {
+		t.Fatal("store.SetCategory failed:", err)
+	}
+
+	counts, err := store.CategoryCounts(ctx)
+	if err != nil {
+		t.Fatal("store.CategoryCounts failed:", err)
+	}
+	if want := map[string]int{"tagging": 1, "uploads": 1, "site bug": 1}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("store.CategoryCounts = %v, want %v", counts, want)
+	}
+
+	page, err := store.Query(ctx, teian.Query{Category: "site bug"})
+	if err != nil {
+		t.Fatal("store.Query failed:", err)
+	}
+	if got, want := ids(page.Suggestions), []uint64{3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("store.Query by category = %v, want %v", got, want)
+	}
+
+	if err := store.RemoveCategory(ctx, "tagging"); err != nil {
+		t.Fatal("store.RemoveCategory failed:", err)
+	}
+	s, err := store.Get(ctx, 1)
+	if err != nil {
+		t.Fatal("store.Get failed:", err)
+	}
+	if s.Category != "" {
+		t.Errorf("suggestion of removed category has category %q, want none", s.Category)
+	}
+	counts, err = store.CategoryCounts(ctx)
+	if err != nil {
+		t.Fatal("store.CategoryCounts failed:", err)
+	}
+	if _, ok := counts["tagging"]; ok {
+		t.Errorf("store.CategoryCounts after removal = %v, still has %q", counts, "tagging")
+	}
+}
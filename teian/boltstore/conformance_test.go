@@ -0,0 +1,14 @@
+package boltstore
+
+import (
+	"testing"
+
+	"github.com/kusubooru/teian/internal/storetest"
+)
+
+func TestConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) (storetest.Store, func()) {
+		store, f := setup()
+		return store, func() { teardown(store, f) }
+	})
+}
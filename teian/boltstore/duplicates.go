@@ -0,0 +1,105 @@
+package boltstore
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/kusubooru/teian/teian"
+)
+
+func (db *Boltstore) Similar(ctx context.Context, text string, threshold float64) ([]teian.ScoredSuggestion, error) {
+	var suggs []teian.Suggestion
+	err := db.viewTx(ctx, func(tx *bolt.Tx) error {
+		// Only suggestions that share at least one token with text are
+		// considered. They are found through the search index.
+		ids := make(map[uint64]bool)
+		c := tx.Bucket([]byte(searchIndexBucket)).Cursor()
+		for token := range teian.IndexText(text) {
+			prefix := tokenPrefix(token)
+			for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+				ids[btoi(k[len(prefix):])] = true
+			}
+		}
+		for id := range ids {
+			s, err := getSuggestion(tx, "", id)
+			if err != nil {
+				return fmt.Errorf("suggestion %d: %v", id, err)
+			}
+			suggs = append(suggs, *s)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return teian.Similar(suggs, text, threshold), nil
+}
+
+func (db *Boltstore) Merge(ctx context.Context, into, from uint64, by string) error {
+	if into == from {
+		return teian.Errorf(teian.ErrInvalid, "cannot merge a suggestion into itself")
+	}
+	return db.updateTx(ctx, func(tx *bolt.Tx) error {
+		s, err := getSuggestion(tx, "", into)
+		if err != nil {
+			return err
+		}
+		d, err := getSuggestion(tx, "", from)
+		if err != nil {
+			return err
+		}
+		if s.MergedInto != 0 {
+			return teian.Errorf(teian.ErrConflict, "cannot merge into suggestion %d which is merged into %d", into, s.MergedInto)
+		}
+		if d.MergedInto != 0 {
+			return teian.Errorf(teian.ErrConflict, "suggestion %d is already merged into %d", from, d.MergedInto)
+		}
+		if len(d.Merged) != 0 {
+			return teian.Errorf(teian.ErrConflict, "cannot merge suggestion %d which has duplicates merged into it", from)
+		}
+
+		scores := tx.Bucket([]byte(voteScoresBucket))
+		if err := scores.Delete(scoreKey(s.Votes(), into)); err != nil {
+			return err
+		}
+		if err := scores.Delete(scoreKey(d.Votes(), from)); err != nil {
+			return err
+		}
+
+		// Move the votes of the duplicate.
+		votes := tx.Bucket([]byte(votesBucket))
+		keys, values, err := votesOn(tx, from)
+		if err != nil {
+			return err
+		}
+		for i, k := range keys {
+			vote := int(int8(values[i][0]))
+			if err := votes.Delete(k); err != nil {
+				return err
+			}
+			d.ApplyVote(vote, 0)
+			key := append(append([]byte{}, k[:len(k)-8]...), itob(into)...)
+			if votes.Get(key) != nil {
+				continue
+			}
+			if err := votes.Put(key, []byte{byte(int8(vote))}); err != nil {
+				return err
+			}
+			s.ApplyVote(0, vote)
+		}
+
+		s.Merge(d, by, time.Now())
+		for _, m := range []*teian.Suggestion{s, d} {
+			if err := putSuggestion(tx, m); err != nil {
+				return err
+			}
+			if err := scores.Put(scoreKey(m.Votes(), m.ID), []byte{}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
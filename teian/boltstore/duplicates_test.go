@@ -0,0 +1,105 @@
+package boltstore
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+func TestSimilar(t *testing.T) {
+	store, f := setup()
+	defer teardown(store, f)
+	ctx := context.Background()
+
+	for _, text := range []string{"add a dark theme", "more tags on uploads", "dark theme please"} {
+		if err := store.Create(ctx, "john", &teian.Suggestion{Text: text, Public: true}); err != nil {
+			t.Fatal("store.Create failed:", err)
+		}
+	}
+	results, err := store.Similar(ctx, "Dark theme", teian.DuplicateThreshold)
+	if err != nil {
+		t.Fatal("store.Similar failed:", err)
+	}
+	var got []uint64
+	for _, r := range results {
+		got = append(got, r.ID)
+	}
+	if want := []uint64{1, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("store.Similar = %v, want %v", got, want)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	store, f := setup()
+	defer teardown(store, f)
+	ctx := context.Background()
+
+	for _, text := range []string{"dark theme", "dark theme please"} {
+		if err := store.Create(ctx, "john", &teian.Suggestion{Text: text, Public: true}); err != nil {
+			t.Fatal("store.Create failed:", err)
+		}
+	}
+	votes := []struct {
+		id       uint64
+		username string
+		vote     int
+	}{
+		{1, "mary", 1},
+		{2, "mary", -1}, // mary already voted on 1 so this vote is dropped
+		{2, "bob", 1},
+		{2, "ann", -1},
+	}
+	for _, v := range votes {
+		if err := store.Vote(ctx, v.id, v.username, v.vote); err != nil {
+			t.Fatal("store.Vote failed:", err)
+		}
+	}
+	if err := store.AddReply(ctx, "john", 2, &teian.Reply{Username: "admin", Text: "noted"}); err != nil {
+		t.Fatal("store.AddReply failed:", err)
+	}
+
+	if err := store.Merge(ctx, 1, 2, "admin"); err != nil {
+		t.Fatal("store.Merge failed:", err)
+	}
+	if err := store.Merge(ctx, 1, 2, "admin"); err == nil {
+		t.Error("merging an already merged suggestion expected to return error")
+	}
+	if err := store.Merge(ctx, 1, 1, "admin"); err == nil {
+		t.Error("merging a suggestion into itself expected to return error")
+	}
+
+	s, err := store.Get(ctx, 1)
+	if err != nil {
+		t.Fatal("store.Get failed:", err)
+	}
+	if s.Upvotes != 2 || s.Downvotes != 1 {
+		t.Errorf("merged suggestion has +%d / -%d, want +2 / -1", s.Upvotes, s.Downvotes)
+	}
+	if len(s.Replies) != 1 || s.Replies[0].Text != "noted" {
+		t.Errorf("merged suggestion replies = %v, want the reply of the duplicate", s.Replies)
+	}
+	d, err := store.Get(ctx, 2)
+	if err != nil {
+		t.Fatal("store.Get failed:", err)
+	}
+	if d.Status != teian.StatusDuplicate || d.MergedInto != 1 || d.Votes() != 0 || d.Upvotes != 0 {
+		t.Errorf("duplicate = %+v, want duplicate status, merged into 1 and no votes", d)
+	}
+
+	got, err := store.VotesOf(ctx, "bob")
+	if err != nil {
+		t.Fatal("store.VotesOf failed:", err)
+	}
+	if want := map[uint64]int{1: 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("store.VotesOf(ctx, %q) = %v, want %v", "bob", got, want)
+	}
+	page, err := store.Query(ctx, teian.Query{Order: teian.OrderVotesDesc})
+	if err != nil {
+		t.Fatal("store.Query failed:", err)
+	}
+	if got, want := ids(page.Suggestions), []uint64{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("store.Query by votes = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,88 @@
+package boltstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/kusubooru/teian/teian"
+)
+
+// errDryRun rolls back the transaction of an import that is only a dry run.
+var errDryRun = errors.New("dry run")
+
+func (db *Boltstore) Import(ctx context.Context, suggs []teian.Suggestion, dryRun bool) ([]bool, error) {
+	added := make([]bool, len(suggs))
+	err := db.updateTx(ctx, func(tx *bolt.Tx) error {
+		// Advance the sequence past the largest imported ID first so that
+		// the suggestions without one do not take an ID used further on.
+		b := tx.Bucket([]byte(suggestionsBucket))
+		for _, s := range suggs {
+			if s.ID > b.Sequence() {
+				if err := b.SetSequence(s.ID); err != nil {
+					return err
+				}
+			}
+		}
+		for i := range suggs {
+			s := &suggs[i]
+			if err := checkCategory(tx, s.Category); err != nil {
+				return err
+			}
+			exists, err := imported(tx, s)
+			if err != nil {
+				return err
+			}
+			if exists {
+				continue
+			}
+			if s.ID == 0 {
+				if s.ID, err = b.NextSequence(); err != nil {
+					return err
+				}
+			}
+			if s.Created.IsZero() {
+				s.Created = time.Now()
+			}
+			if err := insertSuggestion(tx, s); err != nil {
+				return err
+			}
+			if err := putRevision(tx, s.ID, 0, &teian.Revision{Text: s.Text, By: s.Username, At: s.Created}); err != nil {
+				return err
+			}
+			added[i] = true
+		}
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && err != errDryRun {
+		return nil, err
+	}
+	return added, nil
+}
+
+// imported reports whether s is already in the store. Suggestions with an ID
+// are looked up by it, including in the trash, while suggestions without one
+// are considered imported if the user has one with the same text.
+func imported(tx *bolt.Tx, s *teian.Suggestion) (bool, error) {
+	if s.ID != 0 {
+		return tx.Bucket([]byte(suggestionsBucket)).Get(itob(s.ID)) != nil ||
+			tx.Bucket([]byte(trashBucket)).Get(itob(s.ID)) != nil, nil
+	}
+	prefix := userPrefix(s.Username)
+	c := tx.Bucket([]byte(userSuggestionsBucket)).Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		other, err := getSuggestion(tx, "", btoi(k[len(prefix):]))
+		if err != nil {
+			return false, err
+		}
+		if other.Text == s.Text {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,95 @@
+package boltstore
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+func TestImport(t *testing.T) {
+	store, f := setup()
+	defer teardown(store, f)
+	ctx := context.Background()
+
+	if err := store.Create(ctx, "john", &teian.Suggestion{Text: "already here"}); err != nil {
+		t.Fatal("store.Create failed:", err)
+	}
+	created := time.Date(2016, 3, 1, 10, 0, 0, 0, time.UTC)
+	suggs := func() []teian.Suggestion {
+		return []teian.Suggestion{
+			{Username: "mary", Text: "no id"},
+			{ID: 10, Username: "mary", Text: "legacy idea", Created: created},
+			{ID: 1, Username: "john", Text: "clashes with an existing id"},
+			{Username: "john", Text: "already here"},
+		}
+	}
+
+	dry := suggs()
+	added, err := store.Import(ctx, dry, true)
+	if err != nil {
+		t.Fatal("store.Import dry run failed:", err)
+	}
+	if want := []bool{true, true, false, false}; !reflect.DeepEqual(added, want) {
+		t.Errorf("store.Import dry run added %v, want %v", added, want)
+	}
+	if dry[0].ID != 11 {
+		t.Errorf("store.Import dry run gave suggestion without id #%d, want #11", dry[0].ID)
+	}
+	all, err := store.All(ctx)
+	if err != nil {
+		t.Fatal("store.All failed:", err)
+	}
+	if len(all) != 1 {
+		t.Fatalf("store.Import dry run changed the store: %v", ids(all))
+	}
+
+	if _, err := store.Import(ctx, suggs(), false); err != nil {
+		t.Fatal("store.Import failed:", err)
+	}
+	s, err := store.Get(ctx, 10)
+	if err != nil {
+		t.Fatal("store.Get of imported suggestion failed:", err)
+	}
+	if !s.Created.Equal(created) || s.Text != "legacy idea" {
+		t.Errorf("store.Import stored %+v, want original id and created time", s)
+	}
+	found, err := store.Search(ctx, "legacy")
+	if err != nil {
+		t.Fatal("store.Search failed:", err)
+	}
+	if got, want := ids(found), []uint64{10}; !reflect.DeepEqual(got, want) {
+		t.Errorf("store.Search of imported text = %v, want %v", got, want)
+	}
+	revs, err := store.Revisions(ctx, 10)
+	if err != nil || len(revs) != 1 {
+		t.Errorf("store.Revisions of imported suggestion = %v, %v, want the imported text", revs, err)
+	}
+
+	// New suggestions continue after the imported IDs.
+	if err := store.Create(ctx, "john", &teian.Suggestion{Text: "new"}); err != nil {
+		t.Fatal("store.Create failed:", err)
+	}
+	all, err = store.All(ctx)
+	if err != nil {
+		t.Fatal("store.All failed:", err)
+	}
+	if got, want := ids(all), []uint64{1, 10, 11, 12}; !reflect.DeepEqual(got, want) {
+		t.Errorf("store.All after import = %v, want %v", got, want)
+	}
+
+	// Running the import again changes nothing.
+	added, err = store.Import(ctx, suggs(), false)
+	if err != nil {
+		t.Fatal("store.Import again failed:", err)
+	}
+	if want := []bool{false, false, false, false}; !reflect.DeepEqual(added, want) {
+		t.Errorf("store.Import again added %v, want nothing", added)
+	}
+
+	if _, err := store.Import(ctx, []teian.Suggestion{{Username: "mary", Text: "x", Category: "nope"}}, false); err != teian.ErrUnknownCategory {
+		t.Errorf("store.Import with unknown category returned %v, want %v", err, teian.ErrUnknownCategory)
+	}
+}
@@ -0,0 +1,294 @@
+package boltstore
+
+import (
+	"bytes"
+	"encoding/gob"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/kusubooru/teian/teian"
+)
+
+// The meta bucket holds the schema version of the database under the
+// version key as a big-endian number. The version is the number of
+// migrations that have been applied to the database.
+const metaBucket = "meta"
+
+var versionKey = []byte("version")
+
+// A migration upgrades the database from the previous version to the next.
+// Most migrations run in a transaction along with the update of the version
+// so a failed migration leaves the database at the previous version. Those
+// that would be too large for one transaction, like those that go through
+// every user, set batched instead of run and use transactions of their own.
+// The version is only updated once batched has succeeded, so a batched
+// migration that failed must be able to continue from where it stopped.
+//
+// Databases from before versions were recorded have no meta bucket and are
+// at version 0 even though they may have been partly upgraded. For that
+// reason every migration must also work on a database that already has
+// its changes.
+type migration struct {
+	desc    string
+	run     func(tx *bolt.Tx) error
+	batched func(db *bolt.DB) error
+}
+
+// migrations holds every migration in order. Migrations can only be
+// appended and must not use code that depends on later versions of the
+// schema.
+var migrations = []migration{
+	{desc: "store each suggestion under its own key", batched: migrateUserSlices},
+	{desc: "build the search index", run: buildSearchIndex},
+	{desc: "add votes and the vote score index", run: buildScoreIndex},
+	{desc: "add categories", run: createBuckets(categoriesBucket, categoryIndexBucket)},
+	{desc: "add revisions", run: createBuckets(revisionsBucket)},
+	{desc: "add the trash", run: createBuckets(trashBucket)},
+	{desc: "add upload quota overrides", run: createBuckets(quotaOverridesBucket)},
+	{desc: "record the time of uploads", run: migrateQuotaUploads},
+	{desc: "count the suggestions of the search index", run: countSearchDocs},
+}
+
+// LatestVersion returns the schema version of the databases this version
+// of the store works with.
+func LatestVersion() int {
+	return len(migrations)
+}
+
+func getVersion(tx *bolt.Tx) int {
+	b := tx.Bucket([]byte(metaBucket))
+	if b == nil {
+		return 0
+	}
+	v := b.Get(versionKey)
+	if v == nil {
+		return 0
+	}
+	return int(btoi(v))
+}
+
+func setVersion(tx *bolt.Tx, version int) error {
+	b, err := tx.CreateBucketIfNotExists([]byte(metaBucket))
+	if err != nil {
+		return err
+	}
+	return b.Put(versionKey, itob(uint64(version)))
+}
+
+// SchemaVersion returns the schema version of the database.
+func (db *Boltstore) SchemaVersion() (int, error) {
+	var version int
+	err := db.View(func(tx *bolt.Tx) error {
+		version = getVersion(tx)
+		return nil
+	})
+	return version, err
+}
+
+// migrate applies the migrations the database is missing, each in its own
+// transaction. It refuses to work on databases of newer versions whose data
+// this version might not be able to decode.
+func migrate(db *bolt.DB) error {
+	for {
+		var version int
+		err := db.View(func(tx *bolt.Tx) error {
+			version = getVersion(tx)
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if version > len(migrations) {
+			return fmt.Errorf("database is at version %d but only versions up to %d are supported", version, len(migrations))
+		}
+		if version == len(migrations) {
+			return nil
+		}
+		m := migrations[version]
+		log.Printf("migrating database to version %d: %s", version+1, m.desc)
+		if m.batched != nil {
+			err = m.batched(db)
+		}
+		if err == nil {
+			err = db.Update(func(tx *bolt.Tx) error {
+				if m.run != nil {
+					if err := m.run(tx); err != nil {
+						return err
+					}
+				}
+				return setVersion(tx, version+1)
+			})
+		}
+		if err != nil {
+			return fmt.Errorf("migration to version %d (%s) failed: %v", version+1, m.desc, err)
+		}
+	}
+}
+
+// createBuckets returns a migration that creates buckets.
+func createBuckets(names ...string) func(tx *bolt.Tx) error {
+	return func(tx *bolt.Tx) error {
+		for _, name := range names {
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// migrateUserSlices converts the old layout of the suggestions bucket, where
+// each key was a username holding a gob encoded []teian.Suggestion, to one
+// key per suggestion plus the username index. It also creates the upload
+// quota bucket which was the only other bucket of the first version. Each
+// user is converted in a transaction of its own so that databases with many
+// users do not need one huge transaction and an interrupted migration goes
+// on with the users that are left.
+//
+// New keys are 8-byte big-endian IDs which start with a zero byte while
+// usernames never do, so every key at or after 0x01 is a legacy one.
+func migrateUserSlices(db *bolt.DB) error {
+	var usernames [][]byte
+	err := db.Update(func(tx *bolt.Tx) error {
+		if err := createBuckets(suggestionsBucket, userSuggestionsBucket, quotaBucket)(tx); err != nil {
+			return err
+		}
+		c := tx.Bucket([]byte(suggestionsBucket)).Cursor()
+		for k, _ := c.Seek([]byte{1}); k != nil; k, _ = c.Next() {
+			usernames = append(usernames, append([]byte(nil), k...))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, username := range usernames {
+		err := db.Update(func(tx *bolt.Tx) error {
+			return migrateUserSlice(tx, username)
+		})
+		if err != nil {
+			return fmt.Errorf("migrating suggestions of %q: %v", username, err)
+		}
+	}
+	return nil
+}
+
+func migrateUserSlice(tx *bolt.Tx, username []byte) error {
+	b := tx.Bucket([]byte(suggestionsBucket))
+	value := b.Get(username)
+	if value == nil {
+		return nil
+	}
+	var suggs []teian.Suggestion
+	if len(value) != 0 {
+		if err := gob.NewDecoder(bytes.NewReader(value)).Decode(&suggs); err != nil {
+			return fmt.Errorf("could not decode suggestions: %v", err)
+		}
+	}
+	for i := range suggs {
+		if suggs[i].Username == "" {
+			suggs[i].Username = string(username)
+		}
+		if err := putSuggestion(tx, &suggs[i]); err != nil {
+			return err
+		}
+	}
+	return b.Delete(username)
+}
+
+// eachSuggestion calls fn with every suggestion of the suggestions bucket.
+func eachSuggestion(tx *bolt.Tx, fn func(s *teian.Suggestion) error) error {
+	return tx.Bucket([]byte(suggestionsBucket)).ForEach(func(k, v []byte) error {
+		s, err := decodeSuggestion(v)
+		if err != nil {
+			return fmt.Errorf("suggestion %d: %v", btoi(k), err)
+		}
+		return fn(s)
+	})
+}
+
+// rebuildBucket replaces a bucket with an empty one.
+func rebuildBucket(tx *bolt.Tx, name string) error {
+	if tx.Bucket([]byte(name)) != nil {
+		if err := tx.DeleteBucket([]byte(name)); err != nil {
+			return err
+		}
+	}
+	_, err := tx.CreateBucket([]byte(name))
+	return err
+}
+
+// buildSearchIndex indexes the text of every suggestion, replacing the
+// index if it exists.
+func buildSearchIndex(tx *bolt.Tx) error {
+	if err := rebuildBucket(tx, searchIndexBucket); err != nil {
+		return err
+	}
+	return eachSuggestion(tx, func(s *teian.Suggestion) error {
+		return indexText(tx, s.ID, s.Text)
+	})
+}
+
+// countSearchDocs records the number of suggestions in the search index so
+// that searches do not count them every time.
+func countSearchDocs(tx *bolt.Tx) error {
+	return setDocCount(tx, tx.Bucket([]byte(suggestionsBucket)).Stats().KeyN)
+}
+
+// buildScoreIndex creates the votes bucket and adds every suggestion to the
+// vote score index, replacing the index if it exists.
+func buildScoreIndex(tx *bolt.Tx) error {
+	if err := createBuckets(votesBucket)(tx); err != nil {
+		return err
+	}
+	if err := rebuildBucket(tx, voteScoresBucket); err != nil {
+		return err
+	}
+	b := tx.Bucket([]byte(voteScoresBucket))
+	return eachSuggestion(tx, func(s *teian.Suggestion) error {
+		return b.Put(scoreKey(s.Votes(), s.ID), []byte{})
+	})
+}
+
+// migrateQuotaUploads converts the upload quota used by each user since the
+// last daily reset to a single upload made now, so that it keeps counting
+// for another day instead of being forgotten.
+func migrateQuotaUploads(tx *bolt.Tx) error {
+	b := tx.Bucket([]byte(quotaBucket))
+	now := time.Now()
+	converted := make(map[string][]byte)
+	err := b.ForEach(func(k, v []byte) error {
+		if _, err := decodeUploads(v); err == nil {
+			return nil
+		}
+		var used teian.Quota
+		if err := gob.NewDecoder(bytes.NewReader(v)).Decode(&used); err != nil {
+			return fmt.Errorf("could not decode quota of %q: %v", k, err)
+		}
+		var buf bytes.Buffer
+		if used != 0 {
+			uploads := teian.Uploads{{At: now, Size: used}}
+			if err := gob.NewEncoder(&buf).Encode(uploads); err != nil {
+				return err
+			}
+		}
+		converted[string(k)] = buf.Bytes()
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for username, v := range converted {
+		if len(v) == 0 {
+			err = b.Delete([]byte(username))
+		} else {
+			err = b.Put([]byte(username), v)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,291 @@
+package boltstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/kusubooru/teian/teian"
+)
+
+// The fixtures in testdata are databases written by the store of past
+// versions. See testdata/gen.sh for how they were made. Each of them holds
+// suggestions "one" and "three" of john and "two" of mary and 3 MB of upload
+// quota used by john.
+var fixtures = []struct {
+	file string
+	// quota is whether the quota used in the fixture still counts. Uploads
+	// that were timed when the fixture was made have long expired.
+	quota bool
+}{
+	// The first version, with a slice of suggestions per user.
+	{"version0.db", true},
+	// Databases from before versions were recorded.
+	{"version0-suggestion-keys.db", true},
+	{"version0-upgraded.db", true},
+	{"version6.db", true},
+	{"version7.db", true},
+	{"version8.db", false},
+	{"version9.db", false},
+}
+
+// copyFixture copies the fixture file to a temporary file so that tests can
+// change it and returns its path.
+func copyFixture(t *testing.T, file string) string {
+	t.Helper()
+	data, err := ioutil.ReadFile(filepath.Join("testdata", file))
+	if err != nil {
+		t.Fatal("could not read fixture:", err)
+	}
+	path := tempFile(t)
+	if err := ioutil.WriteFile(path, data, 0600); err != nil {
+		t.Fatal("could not copy fixture:", err)
+	}
+	return path
+}
+
+func tempFile(t *testing.T) string {
+	t.Helper()
+	f, err := ioutil.TempFile("", "teian_boltdb_tmpfile_")
+	if err != nil {
+		t.Fatal("could not create boltdb temp file:", err)
+	}
+	f.Close()
+	return f.Name()
+}
+
+func TestMigrate_fixtures(t *testing.T) {
+	for _, fx := range fixtures {
+		t.Run(fx.file, func(t *testing.T) {
+			path := copyFixture(t, fx.file)
+			defer os.Remove(path)
+			if err := Verify(path); err != nil {
+				t.Error("Verify of fixture failed:", err)
+			}
+
+			store := NewSuggestionStore(path)
+			defer store.Close()
+			if fx.quota {
+				testQuotaKept(t, store)
+			}
+			testUpgraded(t, store)
+		})
+	}
+}
+
+// testQuotaKept checks that the upload quota john used before the upgrade
+// still counts.
+func testQuotaKept(t *testing.T, store *Boltstore) {
+	t.Helper()
+	usage, err := store.QuotaUsage(context.Background())
+	if err != nil {
+		t.Fatal("store.QuotaUsage after migration failed:", err)
+	}
+	want := []teian.QuotaUsage{{Username: "john", Used: 3 << 20}}
+	if !reflect.DeepEqual(usage, want) {
+		t.Errorf("store.QuotaUsage after migration = %+v, want %+v", usage, want)
+	}
+}
+
+// testUpgraded checks that store has the legacy suggestions and that every
+// part of it works.
+func testUpgraded(t *testing.T, store *Boltstore) {
+	ctx := context.Background()
+	t.Helper()
+	version, err := store.SchemaVersion()
+	if err != nil {
+		t.Fatal("store.SchemaVersion failed:", err)
+	}
+	if version != LatestVersion() {
+		t.Errorf("store.SchemaVersion = %d, want %d", version, LatestVersion())
+	}
+	for username, want := range map[string][]string{"john": {"1 one", "3 three"}, "mary": {"2 two"}} {
+		suggs, err := store.OfUser(ctx, username)
+		if err != nil {
+			t.Fatalf("store.OfUser(ctx, %q) after migration failed: %v", username, err)
+		}
+		var got []string
+		for _, s := range suggs {
+			if s.Username != username || s.Created.IsZero() {
+				t.Errorf("store.OfUser(ctx, %q) after migration returned suggestion of %q created %v", username, s.Username, s.Created)
+			}
+			got = append(got, fmt.Sprintf("%d %s", s.ID, s.Text))
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("store.OfUser(ctx, %q) after migration = %q, want %q", username, got, want)
+		}
+	}
+	found, err := store.Search(ctx, "three")
+	if err != nil {
+		t.Fatal("store.Search after migration failed:", err)
+	}
+	if got, want := ids(found), []uint64{3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("store.Search after migration = %v, want %v", got, want)
+	}
+
+	// New suggestions must continue the old ID sequence.
+	sugg := &teian.Suggestion{Text: "four"}
+	if err := store.Create(ctx, "mary", sugg); err != nil {
+		t.Fatal("store.Create after migration failed:", err)
+	}
+	if got, want := sugg.ID, uint64(4); got != want {
+		t.Errorf("store.Create after migration assigned ID %d, want %d", got, want)
+	}
+	if err := store.Vote(ctx, 2, "mary", 1); err != nil {
+		t.Fatal("store.Vote after migration failed:", err)
+	}
+	page, err := store.Query(ctx, teian.Query{Order: teian.OrderVotesDesc, Limit: 1})
+	if err != nil {
+		t.Fatal("store.Query after migration failed:", err)
+	}
+	if got, want := ids(page.Suggestions), []uint64{2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("store.Query by votes after migration = %v, want %v", got, want)
+	}
+	if err := store.AddCategory(ctx, "ui"); err != nil {
+		t.Fatal("store.AddCategory after migration failed:", err)
+	}
+	if err := store.SetCategory(ctx, "john", 1, "ui"); err != nil {
+		t.Fatal("store.SetCategory after migration failed:", err)
+	}
+	if err := store.Edit(ctx, "john", 1, "one edited"); err != nil {
+		t.Fatal("store.Edit after migration failed:", err)
+	}
+	if revs, err := store.Revisions(ctx, 1); err != nil || len(revs) != 2 {
+		t.Errorf("store.Revisions after migration = %v, %v, want 2 revisions", revs, err)
+	}
+	if err := store.Delete(ctx, "john", 3, "john"); err != nil {
+		t.Fatal("store.Delete after migration failed:", err)
+	}
+	if trash, err := store.Trash(ctx); err != nil || len(trash) != 1 {
+		t.Errorf("store.Trash after migration = %v, %v, want 1 suggestion", trash, err)
+	}
+}
+
+func TestMigrateUserSlices(t *testing.T) {
+	path := copyFixture(t, "version0.db")
+	defer os.Remove(path)
+
+	// Simulate a migration that stopped after the suggestions of john had
+	// been converted and before those of mary.
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		t.Fatal("bolt open failed:", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		if err := createBuckets(userSuggestionsBucket)(tx); err != nil {
+			return err
+		}
+		return migrateUserSlice(tx, []byte("john"))
+	})
+	if err != nil {
+		t.Fatal("migrateUserSlice failed:", err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal("closing bolt failed:", err)
+	}
+
+	store := NewSuggestionStore(path)
+	defer store.Close()
+	all, err := store.All(context.Background())
+	if err != nil {
+		t.Fatal("store.All after migration failed:", err)
+	}
+	if len(all) != 3 {
+		t.Errorf("store.All after migration returned %d suggestions, want 3", len(all))
+	}
+	testUpgraded(t, store)
+}
+
+func TestMigrate_newerVersion(t *testing.T) {
+	path := tempFile(t)
+	defer os.Remove(path)
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		t.Fatal("bolt open failed:", err)
+	}
+	defer db.Close()
+	if err := db.Update(func(tx *bolt.Tx) error { return setVersion(tx, LatestVersion()+1) }); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrate(db); err == nil {
+		t.Error("migrate of a newer database expected to return error")
+	}
+}
+
+func TestMigrate_failure(t *testing.T) {
+	path := copyFixture(t, "version0.db")
+	defer os.Remove(path)
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		t.Fatal("bolt open failed:", err)
+	}
+	defer db.Close()
+
+	// A failed migration leaves the database at the previous version.
+	defer func(m []migration) { migrations = m }(migrations)
+	broken := []migration{
+		{desc: "break", run: func(tx *bolt.Tx) error {
+			if _, err := tx.CreateBucket([]byte("half done")); err != nil {
+				return err
+			}
+			return fmt.Errorf("boom")
+		}},
+		{desc: "break batched", batched: func(db *bolt.DB) error {
+			return fmt.Errorf("boom")
+		}},
+	}
+	for _, m := range broken {
+		migrations = append(migrations[:2:2], m)
+		if err := migrate(db); err == nil {
+			t.Fatalf("migrate with failing migration %q expected to return error", m.desc)
+		}
+		err = db.View(func(tx *bolt.Tx) error {
+			if v := getVersion(tx); v != 2 {
+				t.Errorf("version after failed migration %q = %d, want 2", m.desc, v)
+			}
+			if tx.Bucket([]byte("half done")) != nil {
+				t.Errorf("failed migration %q was not rolled back", m.desc)
+			}
+			return nil
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestMigrateQuotaUploads(t *testing.T) {
+	path := copyFixture(t, "version7.db")
+	defer os.Remove(path)
+
+	// Users without quota used are dropped.
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: time.Second})
+	if err != nil {
+		t.Fatal("bolt open failed:", err)
+	}
+	err = db.Update(func(tx *bolt.Tx) error {
+		var buf bytes.Buffer
+		if err := gob.NewEncoder(&buf).Encode(teian.Quota(0)); err != nil {
+			return err
+		}
+		return tx.Bucket([]byte(quotaBucket)).Put([]byte("mary"), buf.Bytes())
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Close(); err != nil {
+		t.Fatal("closing bolt failed:", err)
+	}
+
+	store := NewSuggestionStore(path)
+	defer store.Close()
+	testQuotaKept(t, store)
+}
@@ -0,0 +1,148 @@
+package boltstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+
+	"github.com/boltdb/bolt"
+	"github.com/kusubooru/teian/teian"
+)
+
+var errBadCursor = teian.Errorf(teian.ErrInvalid, "invalid cursor")
+
+// A cursor is the base64 encoded key, in the bucket that is being walked,
+// of the first suggestion of a page.
+
+func encodeCursor(k []byte) string {
+	return base64.RawURLEncoding.EncodeToString(k)
+}
+
+func decodeCursor(c string) ([]byte, error) {
+	k, err := base64.RawURLEncoding.DecodeString(c)
+	if err != nil || len(k) < 8 {
+		return nil, errBadCursor
+	}
+	return k, nil
+}
+
+// walker moves a bolt cursor forwards or backwards depending on the order of
+// the query.
+type walker struct {
+	c    *bolt.Cursor
+	desc bool
+}
+
+// seek positions the cursor at k or, if k does not exist, at the key that
+// would follow it in the walking direction. A nil k means the beginning.
+func (w walker) seek(k []byte) ([]byte, []byte) {
+	if k == nil {
+		if w.desc {
+			return w.c.Last()
+		}
+		return w.c.First()
+	}
+	key, value := w.c.Seek(k)
+	if w.desc {
+		if key == nil {
+			return w.c.Last()
+		}
+		if !bytes.Equal(key, k) {
+			return w.c.Prev()
+		}
+	}
+	return key, value
+}
+
+func (w walker) next() ([]byte, []byte) {
+	if w.desc {
+		return w.c.Prev()
+	}
+	return w.c.Next()
+}
+
+func (w walker) prev() ([]byte, []byte) {
+	if w.desc {
+		return w.c.Next()
+	}
+	return w.c.Prev()
+}
+
+func (db *Boltstore) Query(ctx context.Context, q teian.Query) (*teian.Page, error) {
+	var start []byte
+	if q.Cursor != "" {
+		var err error
+		if start, err = decodeCursor(q.Cursor); err != nil {
+			return nil, err
+		}
+	}
+	page := &teian.Page{}
+	err := db.viewTx(ctx, func(tx *bolt.Tx) error {
+		suggestions := tx.Bucket([]byte(suggestionsBucket))
+
+		// By date we walk the suggestions themselves since their keys are
+		// in creation order. By user or votes we walk the respective index.
+		var w walker
+		var lookup func(k, v []byte) (*teian.Suggestion, error)
+		switch q.Order {
+		case teian.OrderUserAsc, teian.OrderUserDesc:
+			w = walker{tx.Bucket([]byte(userSuggestionsBucket)).Cursor(), q.Order == teian.OrderUserDesc}
+			lookup = func(k, _ []byte) (*teian.Suggestion, error) {
+				return decodeSuggestion(suggestions.Get(k[len(k)-8:]))
+			}
+		case teian.OrderVotesAsc, teian.OrderVotesDesc:
+			w = walker{tx.Bucket([]byte(voteScoresBucket)).Cursor(), q.Order == teian.OrderVotesDesc}
+			lookup = func(k, _ []byte) (*teian.Suggestion, error) {
+				return decodeSuggestion(suggestions.Get(k[len(k)-8:]))
+			}
+		default:
+			w = walker{suggestions.Cursor(), q.Order != teian.OrderDateAsc}
+			lookup = func(_, v []byte) (*teian.Suggestion, error) {
+				return decodeSuggestion(v)
+			}
+		}
+
+		// Collect the page and find the first match after it.
+		for k, v := w.seek(start); k != nil; k, v = w.next() {
+			s, err := lookup(k, v)
+			if err != nil {
+				return err
+			}
+			if !q.Match(s) {
+				continue
+			}
+			if q.Limit > 0 && len(page.Suggestions) == q.Limit {
+				page.Next = encodeCursor(k)
+				break
+			}
+			page.Suggestions = append(page.Suggestions, *s)
+		}
+		if start == nil || q.Limit <= 0 {
+			return nil
+		}
+
+		// Walk back from the start of this page to find where the
+		// previous one starts.
+		var prev []byte
+		n := 0
+		w.seek(start)
+		for k, v := w.prev(); k != nil && n < q.Limit; k, v = w.prev() {
+			s, err := lookup(k, v)
+			if err != nil {
+				return err
+			}
+			if q.Match(s) {
+				prev = k
+				n++
+			}
+		}
+		if prev != nil {
+			page.Prev = encodeCursor(prev)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return page, nil
+}
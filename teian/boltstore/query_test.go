@@ -0,0 +1,129 @@
+package boltstore
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+func ids(suggs []teian.Suggestion) []uint64 {
+	var ids []uint64
+	for _, s := range suggs {
+		ids = append(ids, s.ID)
+	}
+	return ids
+}
+
+func TestQuery(t *testing.T) {
+	store, f := setup()
+	defer teardown(store, f)
+	ctx := context.Background()
+
+	// IDs 1-6 alternate between mary and john.
+	for i := 1; i <= 6; i++ {
+		username := "mary"
+		if i%2 == 0 {
+			username = "john"
+		}
+		err := store.Create(ctx, username, &teian.Suggestion{Text: fmt.Sprintf("suggestion #%d", i)})
+		if err != nil {
+			t.Fatal("store.Create failed:", err)
+		}
+	}
+	if err := store.SetStatus(ctx, "john", 4, teian.StatusDone, "admin"); err != nil {
+		t.Fatal("store.SetStatus failed:", err)
+	}
+
+	tests := []struct {
+		q    teian.Query
+		want []uint64
+	}{
+		{teian.Query{}, []uint64{6, 5, 4, 3, 2, 1}},
+		{teian.Query{Order: teian.OrderDateAsc}, []uint64{1, 2, 3, 4, 5, 6}},
+		{teian.Query{Order: teian.OrderUserAsc}, []uint64{2, 4, 6, 1, 3, 5}},
+		{teian.Query{Order: teian.OrderUserDesc}, []uint64{5, 3, 1, 6, 4, 2}},
+		{teian.Query{Username: "ar"}, []uint64{5, 3, 1}},
+		{teian.Query{Text: "#2"}, []uint64{2}},
+		{teian.Query{Statuses: []teian.Status{teian.StatusDone}}, []uint64{4}},
+		{teian.Query{Limit: 2}, []uint64{6, 5}},
+	}
+	for _, tt := range tests {
+		page, err := store.Query(ctx, tt.q)
+		if err != nil {
+			t.Fatalf("store.Query(ctx, %+v) failed: %v", tt.q, err)
+		}
+		if got := ids(page.Suggestions); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("store.Query(ctx, %+v) returned IDs %v, want %v", tt.q, got, tt.want)
+		}
+	}
+}
+
+func TestQuery_pagination(t *testing.T) {
+	store, f := setup()
+	defer teardown(store, f)
+	ctx := context.Background()
+
+	for i := 1; i <= 7; i++ {
+		err := store.Create(ctx, "john", &teian.Suggestion{Text: fmt.Sprintf("suggestion #%d", i)})
+		if err != nil {
+			t.Fatal("store.Create failed:", err)
+		}
+	}
+	for _, order := range []teian.Order{teian.OrderDateDesc, teian.OrderDateAsc, teian.OrderUserAsc, teian.OrderUserDesc} {
+		q := teian.Query{Order: order, Limit: 3, Username: "john"}
+
+		// walk forwards
+		var pages [][]uint64
+		var cursors []string
+		for {
+			page, err := store.Query(ctx, q)
+			if err != nil {
+				t.Fatalf("store.Query(ctx, %+v) failed: %v", q, err)
+			}
+			pages = append(pages, ids(page.Suggestions))
+			cursors = append(cursors, q.Cursor)
+			if page.Next == "" {
+				break
+			}
+			q.Cursor = page.Next
+		}
+		if got, want := len(pages), 3; got != want {
+			t.Fatalf("order %q: walking forward returned %d pages, want %d", order, got, want)
+		}
+		if got, want := len(pages[2]), 1; got != want {
+			t.Errorf("order %q: last page has %d suggestions, want %d", order, got, want)
+		}
+
+		// walk backwards and expect the same pages
+		for i := len(pages) - 1; i > 0; i-- {
+			q.Cursor = cursors[i]
+			page, err := store.Query(ctx, q)
+			if err != nil {
+				t.Fatalf("store.Query(ctx, %+v) failed: %v", q, err)
+			}
+			q.Cursor = page.Prev
+			prev, err := store.Query(ctx, q)
+			if err != nil {
+				t.Fatalf("store.Query(ctx, %+v) failed: %v", q, err)
+			}
+			if got, want := ids(prev.Suggestions), pages[i-1]; !reflect.DeepEqual(got, want) {
+				t.Errorf("order %q: previous of page %d = %v, want %v", order, i, got, want)
+			}
+		}
+		q.Cursor = ""
+		first, err := store.Query(ctx, q)
+		if err != nil {
+			t.Fatalf("store.Query(ctx, %+v) failed: %v", q, err)
+		}
+		if first.Prev != "" {
+			t.Errorf("order %q: first page has previous cursor %q", order, first.Prev)
+		}
+	}
+
+	if _, err := store.Query(ctx, teian.Query{Cursor: "!"}); err == nil {
+		t.Error("store.Query with invalid cursor expected to return error")
+	}
+}
@@ -2,67 +2,148 @@ package boltstore
 
 import (
 	"bytes"
+	"context"
 	"encoding/gob"
 	"fmt"
-	"io"
+	"sort"
+	"time"
 
 	"github.com/boltdb/bolt"
 	"github.com/kusubooru/teian/teian"
 )
 
-func (db *Boltstore) CheckQuota(username string, n teian.Quota) (teian.Quota, error) {
+// The upload quota bucket holds the gob encoded teian.Uploads of each user
+// keyed by username. Uploads that are older than teian.QuotaWindow are
+// dropped when the user uploads again.
 
+func (db *Boltstore) CheckQuota(ctx context.Context, username string, n, limit teian.Quota) (teian.Quota, error) {
 	var remain teian.Quota
-
-	err := db.Update(func(tx *bolt.Tx) error {
-		var usage teian.Quota
+	err := db.updateTx(ctx, func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(quotaBucket))
+		uploads, err := decodeUploads(b.Get([]byte(username)))
+		if err != nil {
+			return fmt.Errorf("could not decode uploads of %q: %v", username, err)
+		}
+		uploads, remain, err = uploads.Add(time.Now(), n, limit)
+		if err != nil {
+			return err
+		}
+		if len(uploads) == 0 {
+			return b.Delete([]byte(username))
+		}
 		buf := bytes.Buffer{}
+		if err := gob.NewEncoder(&buf).Encode(uploads); err != nil {
+			return fmt.Errorf("could not encode uploads: %v", err)
+		}
+		return b.Put([]byte(username), buf.Bytes())
+	})
+	return remain, err
+}
 
-		// get bucket
+func (db *Boltstore) RefundQuota(ctx context.Context, username string, n teian.Quota) error {
+	return db.updateTx(ctx, func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(quotaBucket))
-
-		// get current value and decode
-		value := b.Get([]byte(username))
-
-		if _, werr := buf.Write(value); werr != nil {
-			return fmt.Errorf("could not write 'Create value' to buffer: %v", werr)
+		uploads, err := decodeUploads(b.Get([]byte(username)))
+		if err != nil {
+			return fmt.Errorf("could not decode uploads of %q: %v", username, err)
 		}
-
-		if err := gob.NewDecoder(&buf).Decode(&usage); err != nil {
-			// If err is anything else except io.EOF (empty value) we return.
-			if err != io.EOF {
-				return fmt.Errorf("could not decode current bucket value: %v", err)
-			}
+		uploads = uploads.Refund(n)
+		if len(uploads) == 0 {
+			return b.Delete([]byte(username))
 		}
+		buf := bytes.Buffer{}
+		if err := gob.NewEncoder(&buf).Encode(uploads); err != nil {
+			return fmt.Errorf("could not encode uploads: %v", err)
+		}
+		return b.Put([]byte(username), buf.Bytes())
+	})
+}
 
-		max := int64(db.userQuota)
-		newUsageInt64 := int64(usage) + int64(n)
+// decodeUploads decodes a value of the upload quota bucket. Empty values
+// have no uploads.
+func decodeUploads(v []byte) (teian.Uploads, error) {
+	var uploads teian.Uploads
+	if len(v) == 0 {
+		return nil, nil
+	}
+	if err := gob.NewDecoder(bytes.NewReader(v)).Decode(&uploads); err != nil {
+		return nil, err
+	}
+	return uploads, nil
+}
 
-		if newUsageInt64 > max {
-			return teian.ErrOverQuota
+func (db *Boltstore) QuotaOverride(ctx context.Context, username string) (teian.Quota, error) {
+	var limit teian.Quota
+	err := db.viewTx(ctx, func(tx *bolt.Tx) error {
+		v := tx.Bucket([]byte(quotaOverridesBucket)).Get([]byte(username))
+		if v == nil {
+			return teian.ErrNotFound
 		}
-		newUsage := teian.Quota(newUsageInt64)
-		remain = teian.Quota(max - newUsageInt64)
+		limit = teian.Quota(btoi(v))
+		return nil
+	})
+	return limit, err
+}
 
-		// encode new value and store to bucket
-		buf.Reset()
-		if err := gob.NewEncoder(&buf).Encode(newUsage); err != nil {
-			return fmt.Errorf("could not encode new bucket value: %v", err)
+func (db *Boltstore) SetQuotaOverride(ctx context.Context, username string, limit teian.Quota) error {
+	if username == "" || limit < 0 {
+		return teian.Errorf(teian.ErrInvalid, "invalid quota %d for user %q", limit, username)
+	}
+	return db.updateTx(ctx, func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(quotaOverridesBucket)).Put([]byte(username), itob(uint64(limit)))
+	})
+}
+
+func (db *Boltstore) RemoveQuotaOverride(ctx context.Context, username string) error {
+	return db.updateTx(ctx, func(tx *bolt.Tx) error {
+		b := tx.Bucket([]byte(quotaOverridesBucket))
+		if b.Get([]byte(username)) == nil {
+			return teian.ErrNotFound
 		}
-		_ = b.Put([]byte(username), buf.Bytes())
-		return nil
+		return b.Delete([]byte(username))
 	})
-	return remain, err
 }
 
-func (db *Boltstore) CleanQuota() error {
-	return db.Update(func(tx *bolt.Tx) error {
-		b := tx.Bucket([]byte(quotaBucket))
-		return b.ForEach(func(k, v []byte) error {
-			if err := b.Delete(k); err != nil {
-				return err
+func (db *Boltstore) QuotaUsage(ctx context.Context) ([]teian.QuotaUsage, error) {
+	var usage []teian.QuotaUsage
+	err := db.viewTx(ctx, func(tx *bolt.Tx) error {
+		users := make(map[string]*teian.QuotaUsage)
+		get := func(username string) *teian.QuotaUsage {
+			u, ok := users[username]
+			if !ok {
+				u = &teian.QuotaUsage{Username: username}
+				users[username] = u
+			}
+			return u
+		}
+		now := time.Now()
+		err := tx.Bucket([]byte(quotaBucket)).ForEach(func(k, v []byte) error {
+			uploads, err := decodeUploads(v)
+			if err != nil {
+				return fmt.Errorf("could not decode uploads of %q: %v", k, err)
+			}
+			if uploads = uploads.Expire(now); len(uploads) != 0 {
+				get(string(k)).Used = uploads.Used()
 			}
 			return nil
 		})
+		if err != nil {
+			return err
+		}
+		err = tx.Bucket([]byte(quotaOverridesBucket)).ForEach(func(k, v []byte) error {
+			u := get(string(k))
+			u.Limit = teian.Quota(btoi(v))
+			u.Override = true
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		for _, u := range users {
+			usage = append(usage, *u)
+		}
+		return nil
 	})
+	sort.Slice(usage, func(i, j int) bool { return usage[i].Username < usage[j].Username })
+	return usage, err
 }
@@ -1,24 +1,30 @@
 package boltstore
 
 import (
+	"bytes"
+	"context"
+	"encoding/gob"
 	"testing"
+	"time"
 
+	"github.com/boltdb/bolt"
 	"github.com/kusubooru/teian/teian"
 )
 
 func TestQuota(t *testing.T) {
 	store, f := setup()
 	defer teardown(store, f)
+	ctx := context.Background()
 
 	username := "john"
 
 	// add 5 out of 10 MB quota
-	_, err := store.CheckQuota(username, teian.Quota(5<<20))
+	_, err := store.CheckQuota(ctx, username, teian.Quota(5<<20), testQuota)
 	if err != nil {
 		t.Fatal("store.CheckQuota failed:", err)
 	}
 	// add 5 + 2 out of 10 MB quota
-	remain, err := store.CheckQuota(username, teian.Quota(2<<20))
+	remain, err := store.CheckQuota(ctx, username, teian.Quota(2<<20), testQuota)
 	if err != nil {
 		t.Fatal("store.CheckQuota failed:", err)
 	}
@@ -28,18 +34,24 @@ func TestQuota(t *testing.T) {
 		t.Fatalf("store.CheckQuota should return remain %v, got %v", got, want)
 	}
 	// try to add 4 MB more while only 3 MB remain
-	_, err = store.CheckQuota(username, teian.Quota(4<<20))
+	_, err = store.CheckQuota(ctx, username, teian.Quota(4<<20), testQuota)
 	if err != teian.ErrOverQuota {
 		t.Error("store.CheckQuota expected to return ErrOverQuota error, got:", err)
 	}
 
-	// clean quota
-	if err := store.CleanQuota(); err != nil {
-		t.Fatal("store.CleanQuota failed:", err)
+	// An upload that has left the window no longer counts and is dropped.
+	var buf bytes.Buffer
+	old := teian.Uploads{{At: time.Now().Add(-teian.QuotaWindow - time.Minute), Size: testQuota}}
+	if err := gob.NewEncoder(&buf).Encode(old); err != nil {
+		t.Fatal(err)
 	}
-
-	// Add 10 out of 10 MB quota which should succeed after the clean.
-	remain, err = store.CheckQuota(username, teian.Quota(10<<20))
+	err = store.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(quotaBucket)).Put([]byte("mary"), buf.Bytes())
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	remain, err = store.CheckQuota(ctx, "mary", teian.Quota(10<<20), testQuota)
 	if err != nil {
 		t.Fatal("store.CheckQuota failed:", err)
 	}
@@ -48,4 +60,14 @@ func TestQuota(t *testing.T) {
 	if got != want {
 		t.Fatalf("store.CheckQuota should return remain %v, got %v", got, want)
 	}
+	err = store.View(func(tx *bolt.Tx) error {
+		uploads, err := decodeUploads(tx.Bucket([]byte(quotaBucket)).Get([]byte("mary")))
+		if err != nil || len(uploads) != 1 {
+			t.Errorf("uploads after CheckQuota = %v, %v, want only the new one", uploads, err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
 }
@@ -0,0 +1,129 @@
+package boltstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"fmt"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/kusubooru/teian/teian"
+)
+
+// The revisions bucket holds every version of the text of the suggestions
+// with keys of the form big-endian ID + big-endian revision number and gob
+// encoded teian.Revision values. Suggestions created before revisions were
+// recorded get their first revision when they are first edited.
+
+func revisionKey(id uint64, n int) []byte {
+	return append(itob(id), itob(uint64(n))...)
+}
+
+func putRevision(tx *bolt.Tx, id uint64, n int, r *teian.Revision) error {
+	buf := bytes.Buffer{}
+	if err := gob.NewEncoder(&buf).Encode(r); err != nil {
+		return fmt.Errorf("could not encode revision: %v", err)
+	}
+	return tx.Bucket([]byte(revisionsBucket)).Put(revisionKey(id, n), buf.Bytes())
+}
+
+func getRevisions(tx *bolt.Tx, id uint64) ([]teian.Revision, error) {
+	var revs []teian.Revision
+	prefix := itob(id)
+	c := tx.Bucket([]byte(revisionsBucket)).Cursor()
+	for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+		var r teian.Revision
+		if err := gob.NewDecoder(bytes.NewReader(v)).Decode(&r); err != nil {
+			return nil, fmt.Errorf("could not decode revision: %v", err)
+		}
+		revs = append(revs, r)
+	}
+	return revs, nil
+}
+
+func deleteRevisions(tx *bolt.Tx, id uint64) error {
+	var keys [][]byte
+	prefix := itob(id)
+	b := tx.Bucket([]byte(revisionsBucket))
+	c := b.Cursor()
+	for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+		keys = append(keys, k)
+	}
+	for _, k := range keys {
+		if err := b.Delete(k); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setText changes the text of the suggestion with id of username and
+// records the new text as a revision by the given author. An empty username
+// matches any suggestion.
+func setText(tx *bolt.Tx, username string, id uint64, text, by string) error {
+	var old teian.Suggestion
+	changed := false
+	err := updateSuggestion(tx, username, id, func(s *teian.Suggestion) bool {
+		if s.Text == text {
+			return false
+		}
+		old = *s
+		s.Text = text
+		changed = true
+		return true
+	})
+	if err != nil || !changed {
+		return err
+	}
+	revs, err := getRevisions(tx, id)
+	if err != nil {
+		return err
+	}
+	if len(revs) == 0 {
+		orig := &teian.Revision{Text: old.Text, By: old.Username, At: old.Created}
+		if err := putRevision(tx, id, 0, orig); err != nil {
+			return err
+		}
+		revs = append(revs, *orig)
+	}
+	return putRevision(tx, id, len(revs), &teian.Revision{Text: text, By: by, At: time.Now()})
+}
+
+func (db *Boltstore) Revisions(ctx context.Context, id uint64) ([]teian.Revision, error) {
+	var revs []teian.Revision
+	err := db.viewTx(ctx, func(tx *bolt.Tx) error {
+		s, err := getSuggestion(tx, "", id)
+		if err != nil {
+			return err
+		}
+		if revs, err = getRevisions(tx, id); err != nil {
+			return err
+		}
+		if len(revs) == 0 {
+			revs = []teian.Revision{{Text: s.Text, By: s.Username, At: s.Created}}
+		}
+		return nil
+	})
+	return revs, err
+}
+
+func (db *Boltstore) Revert(ctx context.Context, id uint64, revision int, by string) error {
+	return db.updateTx(ctx, func(tx *bolt.Tx) error {
+		s, err := getSuggestion(tx, "", id)
+		if err != nil {
+			return err
+		}
+		revs, err := getRevisions(tx, id)
+		if err != nil {
+			return err
+		}
+		if len(revs) == 0 {
+			revs = []teian.Revision{{Text: s.Text}}
+		}
+		if revision < 0 || revision >= len(revs) {
+			return teian.ErrNotFound
+		}
+		return setText(tx, "", id, revs[revision].Text, by)
+	})
+}
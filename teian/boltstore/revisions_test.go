@@ -0,0 +1,62 @@
+package boltstore
+
+import (
+	"context"
+	"testing"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+func TestRevisions(t *testing.T) {
+	store, f := setup()
+	defer teardown(store, f)
+	ctx := context.Background()
+
+	if err := store.Create(ctx, "john", &teian.Suggestion{Text: "first"}); err != nil {
+		t.Fatal("store.Create failed:", err)
+	}
+	for _, text := range []string{"second", "second", "third"} {
+		if err := store.Edit(ctx, "john", 1, text); err != nil {
+			t.Fatal("store.Edit failed:", err)
+		}
+	}
+	if err := store.Revert(ctx, 1, 0, "admin"); err != nil {
+		t.Fatal("store.Revert failed:", err)
+	}
+	if err := store.Revert(ctx, 1, 9, "admin"); err == nil {
+		t.Error("store.Revert to missing revision expected to return error")
+	}
+
+	revs, err := store.Revisions(ctx, 1)
+	if err != nil {
+		t.Fatal("store.Revisions failed:", err)
+	}
+	want := []struct{ text, by string }{
+		{"first", "john"},
+		{"second", "john"},
+		{"third", "john"},
+		{"first", "admin"},
+	}
+	if len(revs) != len(want) {
+		t.Fatalf("store.Revisions returned %d revisions, want %d", len(revs), len(want))
+	}
+	for i, w := range want {
+		if revs[i].Text != w.text || revs[i].By != w.by {
+			t.Errorf("revision %d = %q by %q, want %q by %q", i, revs[i].Text, revs[i].By, w.text, w.by)
+		}
+	}
+	s, err := store.Get(ctx, 1)
+	if err != nil {
+		t.Fatal("store.Get failed:", err)
+	}
+	if s.Text != "first" {
+		t.Errorf("reverted suggestion text = %q, want %q", s.Text, "first")
+	}
+
+	if err := store.Delete(ctx, "john", 1, "john"); err != nil {
+		t.Fatal("store.Delete failed:", err)
+	}
+	if _, err := store.Revisions(ctx, 1); err == nil {
+		t.Error("store.Revisions of deleted suggestion expected to return error")
+	}
+}
@@ -0,0 +1,136 @@
+package boltstore
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/boltdb/bolt"
+	"github.com/kusubooru/teian/teian"
+)
+
+// The searchIndex bucket is an inverted index of the suggestion texts. Keys
+// are token + "\x00" + big-endian ID and values are the positions of the
+// token in the text encoded as uvarints. The number of indexed suggestions,
+// which ranking needs, is kept under docCountKey; tokens are never empty so
+// it is no posting.
+
+var docCountKey = []byte{0}
+
+// docCount returns the number of suggestions in the search index.
+func docCount(tx *bolt.Tx) int {
+	v := tx.Bucket([]byte(searchIndexBucket)).Get(docCountKey)
+	if v == nil {
+		return 0
+	}
+	return int(btoi(v))
+}
+
+// setDocCount sets the number of suggestions in the search index.
+func setDocCount(tx *bolt.Tx, n int) error {
+	return tx.Bucket([]byte(searchIndexBucket)).Put(docCountKey, itob(uint64(n)))
+}
+
+func tokenPrefix(token string) []byte {
+	return append([]byte(token), 0)
+}
+
+func encodePositions(positions []int) []byte {
+	buf := make([]byte, 0, len(positions))
+	tmp := make([]byte, binary.MaxVarintLen64)
+	for _, p := range positions {
+		n := binary.PutUvarint(tmp, uint64(p))
+		buf = append(buf, tmp[:n]...)
+	}
+	return buf
+}
+
+func decodePositions(v []byte) ([]int, error) {
+	var positions []int
+	for len(v) != 0 {
+		p, n := binary.Uvarint(v)
+		if n <= 0 {
+			return nil, fmt.Errorf("bad search index positions")
+		}
+		positions = append(positions, int(p))
+		v = v[n:]
+	}
+	return positions, nil
+}
+
+// indexText adds the tokens of text to the search index for suggestion id.
+func indexText(tx *bolt.Tx, id uint64, text string) error {
+	b := tx.Bucket([]byte(searchIndexBucket))
+	for token, positions := range teian.IndexText(text) {
+		if err := b.Put(append(tokenPrefix(token), itob(id)...), encodePositions(positions)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// unindexText removes the tokens of text from the search index for
+// suggestion id.
+func unindexText(tx *bolt.Tx, id uint64, text string) error {
+	b := tx.Bucket([]byte(searchIndexBucket))
+	for token := range teian.IndexText(text) {
+		if err := b.Delete(append(tokenPrefix(token), itob(id)...)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (db *Boltstore) Search(ctx context.Context, query string) ([]teian.Suggestion, error) {
+	q := teian.ParseSearch(query)
+	if len(q.Include) == 0 {
+		return nil, nil
+	}
+	var results []teian.ScoredSuggestion
+	err := db.viewTx(ctx, func(tx *bolt.Tx) error {
+		// Load the postings of the query tokens for every suggestion
+		// they appear in.
+		postings := make(map[uint64]teian.Postings)
+		df := make(map[string]int)
+		c := tx.Bucket([]byte(searchIndexBucket)).Cursor()
+		for _, token := range q.Tokens() {
+			prefix := tokenPrefix(token)
+			for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+				id := btoi(k[len(prefix):])
+				positions, err := decodePositions(v)
+				if err != nil {
+					return err
+				}
+				if postings[id] == nil {
+					postings[id] = make(teian.Postings)
+				}
+				postings[id][token] = positions
+				df[token]++
+			}
+		}
+
+		suggestions := tx.Bucket([]byte(suggestionsBucket))
+		n := docCount(tx)
+		for id, p := range postings {
+			if !q.Match(p) {
+				continue
+			}
+			s, err := decodeSuggestion(suggestions.Get(itob(id)))
+			if err != nil {
+				return fmt.Errorf("suggestion %d: %v", id, err)
+			}
+			results = append(results, teian.ScoredSuggestion{Suggestion: *s, Score: q.Score(p, df, n)})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	teian.SortByScore(results)
+	suggs := make([]teian.Suggestion, len(results))
+	for i := range results {
+		suggs[i] = results[i].Suggestion
+	}
+	return suggs, nil
+}
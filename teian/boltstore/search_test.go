@@ -0,0 +1,75 @@
+package boltstore
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/boltdb/bolt"
+	"github.com/kusubooru/teian/teian"
+)
+
+func TestSearch(t *testing.T) {
+	store, f := setup()
+	defer teardown(store, f)
+	ctx := context.Background()
+
+	texts := []string{
+		"Please add a tag list",
+		"tag tag tag, the tag page is slow",
+		"list of uploads",
+		"The list tag is broken",
+	}
+	for _, text := range texts {
+		if err := store.Create(ctx, "john", &teian.Suggestion{Text: text}); err != nil {
+			t.Fatal("store.Create failed:", err)
+		}
+	}
+	search := func(query string) []uint64 {
+		t.Helper()
+		suggs, err := store.Search(ctx, query)
+		if err != nil {
+			t.Fatalf("store.Search(ctx, %q) failed: %v", query, err)
+		}
+		return ids(suggs)
+	}
+
+	// The index must rank the same way as searching in memory.
+	all, err := store.All(ctx)
+	if err != nil {
+		t.Fatal("store.All failed:", err)
+	}
+	for _, query := range []string{"TAG", `"tag list"`, "tag -slow", `list -"tag list"`, "-tag", "missing"} {
+		if got, want := search(query), ids(teian.Search(all, query)); !reflect.DeepEqual(got, want) {
+			t.Errorf("store.Search(ctx, %q) returned IDs %v, want %v", query, got, want)
+		}
+	}
+
+	// Edits and deletes must update the index.
+	if err := store.Edit(ctx, "john", 3, "list of broken uploads"); err != nil {
+		t.Fatal("store.Edit failed:", err)
+	}
+	if got, want := search("broken"), []uint64{4, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("store.Search after edit returned IDs %v, want %v", got, want)
+	}
+	if err := store.Delete(ctx, "john", 4, "john"); err != nil {
+		t.Fatal("store.Delete failed:", err)
+	}
+	if got, want := search("broken"), []uint64{3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("store.Search after delete returned IDs %v, want %v", got, want)
+	}
+	if got := search("slow"); len(got) != 1 {
+		t.Errorf("store.Search(ctx, %q) returned IDs %v, want one result", "slow", got)
+	}
+
+	// The index keeps the number of suggestions that ranking needs.
+	err = store.View(func(tx *bolt.Tx) error {
+		if got, want := docCount(tx), tx.Bucket([]byte(suggestionsBucket)).Stats().KeyN; got != want {
+			t.Errorf("search index counts %d suggestions, want %d", got, want)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -2,128 +2,181 @@ package boltstore
 
 import (
 	"bytes"
+	"context"
+	"encoding/binary"
 	"encoding/gob"
-	"errors"
 	"fmt"
-	"io"
 	"time"
 
 	"github.com/boltdb/bolt"
 	"github.com/kusubooru/teian/teian"
 )
 
-func (db *Boltstore) Create(username string, sugg *teian.Suggestion) error {
+// Suggestions are stored one per key in the suggestions bucket. The key is
+// the big-endian ID so that iterating the bucket returns them in creation
+// order. The userSuggestions bucket indexes them by username with keys of the
+// form username + "\x00" + big-endian ID and empty values.
 
-	err := db.Update(func(tx *bolt.Tx) error {
-		var suggs []teian.Suggestion
-		buf := bytes.Buffer{}
+// itob returns an 8-byte big-endian representation of v.
+func itob(v uint64) []byte {
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint64(b, v)
+	return b
+}
 
-		// get bucket
-		b := tx.Bucket([]byte(suggestionsBucket))
+// btoi is the inverse of itob.
+func btoi(b []byte) uint64 {
+	return binary.BigEndian.Uint64(b)
+}
 
-		// get current value and decode
-		value := b.Get([]byte(username))
+// userPrefix returns the prefix of all the index keys of username.
+func userPrefix(username string) []byte {
+	return append([]byte(username), 0)
+}
 
-		if _, werr := buf.Write(value); werr != nil {
-			return fmt.Errorf("could not write 'Create value' to buffer: %v", werr)
-		}
+// userKey returns the index key of the suggestion with id of username.
+func userKey(username string, id uint64) []byte {
+	return append(userPrefix(username), itob(id)...)
+}
 
-		if err := gob.NewDecoder(&buf).Decode(&suggs); err != nil {
-			// If err is anything else except io.EOF (empty value) we return.
-			if err != io.EOF {
-				return fmt.Errorf("could not decode current bucket value: %v", err)
-			}
-		}
+func encodeSuggestion(s *teian.Suggestion) ([]byte, error) {
+	buf := bytes.Buffer{}
+	if err := gob.NewEncoder(&buf).Encode(s); err != nil {
+		return nil, fmt.Errorf("could not encode suggestion: %v", err)
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeSuggestion(v []byte) (*teian.Suggestion, error) {
+	s := new(teian.Suggestion)
+	if err := gob.NewDecoder(bytes.NewReader(v)).Decode(s); err != nil {
+		return nil, fmt.Errorf("could not decode suggestion: %v", err)
+	}
+	return s, nil
+}
+
+// putSuggestion stores s under its ID and adds it to the username index.
+func putSuggestion(tx *bolt.Tx, s *teian.Suggestion) error {
+	value, err := encodeSuggestion(s)
+	if err != nil {
+		return err
+	}
+	if err := tx.Bucket([]byte(suggestionsBucket)).Put(itob(s.ID), value); err != nil {
+		return err
+	}
+	return tx.Bucket([]byte(userSuggestionsBucket)).Put(userKey(s.Username, s.ID), []byte{})
+}
+
+// insertSuggestion stores a new suggestion s and adds it to every index.
+func insertSuggestion(tx *bolt.Tx, s *teian.Suggestion) error {
+	if err := putSuggestion(tx, s); err != nil {
+		return err
+	}
+	if err := indexText(tx, s.ID, s.Text); err != nil {
+		return err
+	}
+	if err := setDocCount(tx, docCount(tx)+1); err != nil {
+		return err
+	}
+	if err := indexCategory(tx, s.ID, s.Category); err != nil {
+		return err
+	}
+	return tx.Bucket([]byte(voteScoresBucket)).Put(scoreKey(s.Votes(), s.ID), []byte{})
+}
+
+// removeSuggestion deletes suggestion s and removes it from every index.
+func removeSuggestion(tx *bolt.Tx, s *teian.Suggestion) error {
+	if err := tx.Bucket([]byte(userSuggestionsBucket)).Delete(userKey(s.Username, s.ID)); err != nil {
+		return err
+	}
+	if err := tx.Bucket([]byte(suggestionsBucket)).Delete(itob(s.ID)); err != nil {
+		return err
+	}
+	if err := unindexText(tx, s.ID, s.Text); err != nil {
+		return err
+	}
+	if err := setDocCount(tx, docCount(tx)-1); err != nil {
+		return err
+	}
+	if err := unindexCategory(tx, s.ID, s.Category); err != nil {
+		return err
+	}
+	return tx.Bucket([]byte(voteScoresBucket)).Delete(scoreKey(s.Votes(), s.ID))
+}
+
+// getSuggestion returns the suggestion with id. If username is not empty
+// then the suggestion must also belong to username.
+func getSuggestion(tx *bolt.Tx, username string, id uint64) (*teian.Suggestion, error) {
+	if username != "" && tx.Bucket([]byte(userSuggestionsBucket)).Get(userKey(username, id)) == nil {
+		return nil, teian.ErrNotFound
+	}
+	value := tx.Bucket([]byte(suggestionsBucket)).Get(itob(id))
+	if value == nil {
+		return nil, teian.ErrNotFound
+	}
+	return decodeSuggestion(value)
+}
 
-		// prepare new value
-		id, err := b.NextSequence()
+func (db *Boltstore) Create(ctx context.Context, username string, sugg *teian.Suggestion) error {
+	return db.updateTx(ctx, func(tx *bolt.Tx) error {
+		if err := checkCategory(tx, sugg.Category); err != nil {
+			return err
+		}
+		id, err := tx.Bucket([]byte(suggestionsBucket)).NextSequence()
 		if err != nil {
 			return err
 		}
 		sugg.ID = id
 		sugg.Username = username
 		sugg.Created = time.Now()
-		suggs = append(suggs, *sugg)
-
-		// encode new value and store to bucket
-		buf.Reset()
-		if err := gob.NewEncoder(&buf).Encode(suggs); err != nil {
-			return fmt.Errorf("could not encode new bucket value: %v", err)
+		if err := insertSuggestion(tx, sugg); err != nil {
+			return err
 		}
-		_ = b.Put([]byte(username), buf.Bytes())
-		return nil
+		return putRevision(tx, id, 0, &teian.Revision{Text: sugg.Text, By: username, At: sugg.Created})
 	})
-	return err
 }
 
-func (db *Boltstore) OfUser(username string) ([]teian.Suggestion, error) {
-	var suggs []teian.Suggestion
-	buf := bytes.Buffer{}
-	err := db.View(func(tx *bolt.Tx) error {
-		value := tx.Bucket([]byte(suggestionsBucket)).Get([]byte(username))
-
-		if _, werr := buf.Write(value); werr != nil {
-			return fmt.Errorf("could not write 'OfUser value' to buffer: %v", werr)
-		}
-
-		if err := gob.NewDecoder(&buf).Decode(&suggs); err != nil {
-			return fmt.Errorf("could not decode suggestions %v", err)
-		}
-		return nil
+func (db *Boltstore) Get(ctx context.Context, id uint64) (*teian.Suggestion, error) {
+	var sugg *teian.Suggestion
+	err := db.viewTx(ctx, func(tx *bolt.Tx) error {
+		var err error
+		sugg, err = getSuggestion(tx, "", id)
+		return err
 	})
-	return suggs, err
+	return sugg, err
 }
 
-func (db *Boltstore) Delete(username string, id uint64) error {
+func (db *Boltstore) OfUser(ctx context.Context, username string) ([]teian.Suggestion, error) {
 	var suggs []teian.Suggestion
-	buf := bytes.Buffer{}
-	err := db.Update(func(tx *bolt.Tx) error {
+	err := db.viewTx(ctx, func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(suggestionsBucket))
-		value := b.Get([]byte(username))
-
-		if _, werr := buf.Write(value); werr != nil {
-			return fmt.Errorf("could not write 'Delete value' to buffer: %v", werr)
-		}
-
-		if err := gob.NewDecoder(&buf).Decode(&suggs); err != nil {
-			return fmt.Errorf("could not decode suggestions %v", err)
-		}
-		i, s := teian.FindByID(suggs, id)
-		if s == nil {
-			return errors.New("entry does not exit")
-		}
-		// delete from slice
-		suggs = append(suggs[:i], suggs[i+1:]...)
-
-		// encode remains and store to bucket
-		buf.Reset()
-		if err := gob.NewEncoder(&buf).Encode(suggs); err != nil {
-			return fmt.Errorf("could not encode new value after delete: %v", err)
+		prefix := userPrefix(username)
+		c := tx.Bucket([]byte(userSuggestionsBucket)).Cursor()
+		for k, _ := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, _ = c.Next() {
+			id := k[len(prefix):]
+			s, err := decodeSuggestion(b.Get(id))
+			if err != nil {
+				return fmt.Errorf("suggestion %d of %q: %v", btoi(id), username, err)
+			}
+			suggs = append(suggs, *s)
 		}
-		_ = b.Put([]byte(username), buf.Bytes())
 		return nil
 	})
-	return err
+	return suggs, err
 }
 
-func (db *Boltstore) All() ([]teian.Suggestion, error) {
+func (db *Boltstore) All(ctx context.Context) ([]teian.Suggestion, error) {
 	var suggs []teian.Suggestion
-	buf := bytes.Buffer{}
-	err := db.View(func(tx *bolt.Tx) error {
+	err := db.viewTx(ctx, func(tx *bolt.Tx) error {
 		b := tx.Bucket([]byte(suggestionsBucket))
 
 		// Iterate over items in sorted key order.
 		return b.ForEach(func(k, v []byte) error {
-			var userSuggs []teian.Suggestion
-			buf.Reset()
-			if _, werr := buf.Write(v); werr != nil {
-				return fmt.Errorf("could not write 'All value' to buffer: %v", werr)
-			}
-
-			if err := gob.NewDecoder(&buf).Decode(&userSuggs); err != nil {
-				return fmt.Errorf("could not decode suggestions of %q: %v", k, err)
+			s, err := decodeSuggestion(v)
+			if err != nil {
+				return fmt.Errorf("suggestion %d: %v", btoi(k), err)
 			}
-			suggs = append(suggs, userSuggs...)
+			suggs = append(suggs, *s)
 			return nil
 		})
 	})
@@ -132,3 +185,62 @@ func (db *Boltstore) All() ([]teian.Suggestion, error) {
 	}
 	return suggs, nil
 }
+
+func (db *Boltstore) SetStatus(ctx context.Context, username string, id uint64, status teian.Status, by string) error {
+	return db.update(ctx, username, id, func(s *teian.Suggestion) bool {
+		return s.SetStatus(status, by, time.Now())
+	})
+}
+
+func (db *Boltstore) Edit(ctx context.Context, username string, id uint64, text string) error {
+	return db.updateTx(ctx, func(tx *bolt.Tx) error {
+		return setText(tx, username, id, text, username)
+	})
+}
+
+func (db *Boltstore) AddReply(ctx context.Context, username string, id uint64, reply *teian.Reply) error {
+	return db.update(ctx, username, id, func(s *teian.Suggestion) bool {
+		reply.Created = time.Now()
+		s.Replies = append(s.Replies, *reply)
+		return true
+	})
+}
+
+// update runs updateSuggestion in its own transaction.
+func (db *Boltstore) update(ctx context.Context, username string, id uint64, fn func(*teian.Suggestion) bool) error {
+	return db.updateTx(ctx, func(tx *bolt.Tx) error {
+		return updateSuggestion(tx, username, id, fn)
+	})
+}
+
+// updateSuggestion finds the suggestion with id of username and calls fn to
+// modify it. The changes are stored, and the indexes updated, only if fn
+// returns true.
+func updateSuggestion(tx *bolt.Tx, username string, id uint64, fn func(*teian.Suggestion) bool) error {
+	s, err := getSuggestion(tx, username, id)
+	if err != nil {
+		return err
+	}
+	text, category := s.Text, s.Category
+	if !fn(s) {
+		return nil
+	}
+	if err := putSuggestion(tx, s); err != nil {
+		return err
+	}
+	if s.Category != category {
+		if err := unindexCategory(tx, id, category); err != nil {
+			return err
+		}
+		if err := indexCategory(tx, id, s.Category); err != nil {
+			return err
+		}
+	}
+	if s.Text == text {
+		return nil
+	}
+	if err := unindexText(tx, id, text); err != nil {
+		return err
+	}
+	return indexText(tx, id, s.Text)
+}
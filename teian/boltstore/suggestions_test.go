@@ -1,6 +1,7 @@
 package boltstore
 
 import (
+	"context"
 	"fmt"
 	"io/ioutil"
 	"log"
@@ -18,7 +19,7 @@ func setup() (*Boltstore, *os.File) {
 	if err != nil {
 		log.Fatal("could not create boltdb temp file for tests:", err)
 	}
-	return NewSuggestionStore(f.Name(), testQuota), f
+	return NewSuggestionStore(f.Name()), f
 }
 
 func teardown(store *Boltstore, tmpfile *os.File) {
@@ -31,15 +32,16 @@ func teardown(store *Boltstore, tmpfile *os.File) {
 func TestCreate(t *testing.T) {
 	store, f := setup()
 	defer teardown(store, f)
+	ctx := context.Background()
 
 	username := "john"
 	text := "my first suggestion"
 
-	err := store.Create(username, &teian.Suggestion{Text: text})
+	err := store.Create(ctx, username, &teian.Suggestion{Text: text})
 	if err != nil {
 		t.Error("store.Create failed:", err)
 	}
-	out, err := store.OfUser(username)
+	out, err := store.OfUser(ctx, username)
 	if err != nil {
 		t.Fatal("store.All failed:", err)
 	}
@@ -49,18 +51,19 @@ func TestCreate(t *testing.T) {
 	got := out[0]
 	want := teian.Suggestion{ID: 1, Username: username, Text: text, Created: got.Created}
 	if !reflect.DeepEqual(got, want) {
-		t.Errorf("store.Create(%q, &teian.Suggestion{Test: %q}) lead to \n%#v, want \n%#v", username, text, got, want)
+		t.Errorf("store.Create(ctx, %q, &teian.Suggestion{Test: %q}) lead to \n%#v, want \n%#v", username, text, got, want)
 	}
 }
 
 func TestAll_Delete(t *testing.T) {
 	store, f := setup()
 	defer teardown(store, f)
+	ctx := context.Background()
 
 	username1 := "john"
 	text1 := username1 + "'s suggestion text"
 	for i := 0; i < 10; i++ {
-		err := store.Create(username1, &teian.Suggestion{Text: fmt.Sprintf("%s #%d", text1, i)})
+		err := store.Create(ctx, username1, &teian.Suggestion{Text: fmt.Sprintf("%s #%d", text1, i)})
 		if err != nil {
 			t.Error("store.Create failed:", err)
 		}
@@ -68,13 +71,13 @@ func TestAll_Delete(t *testing.T) {
 	username2 := "mary"
 	text2 := username2 + "'s suggestion text "
 	for i := 0; i < 10; i++ {
-		err := store.Create(username2, &teian.Suggestion{Text: fmt.Sprintf("%s #%d", text2, i)})
+		err := store.Create(ctx, username2, &teian.Suggestion{Text: fmt.Sprintf("%s #%d", text2, i)})
 		if err != nil {
 			t.Error("store.Create failed:", err)
 		}
 	}
 
-	out, err := store.All()
+	out, err := store.All(ctx)
 	if err != nil {
 		t.Fatal("store.All failed:", err)
 	}
@@ -84,12 +87,12 @@ func TestAll_Delete(t *testing.T) {
 
 	// test delete
 	for i := 1; i <= 10; i++ {
-		err := store.Delete(username1, uint64(i))
+		err := store.Delete(ctx, username1, uint64(i), username1)
 		if err != nil {
-			t.Errorf("store.Delete(%q, %d) failed: %v", username1, i, err)
+			t.Errorf("store.Delete(ctx, %q, %d) failed: %v", username1, i, err)
 		}
 	}
-	out, err = store.All()
+	out, err = store.All(ctx)
 	if err != nil {
 		t.Fatal("store.All failed:", err)
 	}
@@ -97,3 +100,132 @@ func TestAll_Delete(t *testing.T) {
 		t.Fatal("store.All should return result with length of 10")
 	}
 }
+
+func TestSetStatus(t *testing.T) {
+	store, f := setup()
+	defer teardown(store, f)
+	ctx := context.Background()
+
+	username := "john"
+	if err := store.Create(ctx, username, &teian.Suggestion{Text: "status test"}); err != nil {
+		t.Fatal("store.Create failed:", err)
+	}
+	if err := store.SetStatus(ctx, username, 1, teian.StatusPlanned, "admin"); err != nil {
+		t.Fatal("store.SetStatus failed:", err)
+	}
+	if err := store.SetStatus(ctx, username, 1, teian.StatusDone, "admin"); err != nil {
+		t.Fatal("store.SetStatus failed:", err)
+	}
+	out, err := store.OfUser(ctx, username)
+	if err != nil {
+		t.Fatal("store.OfUser failed:", err)
+	}
+	got := out[0]
+	if got.Status != teian.StatusDone {
+		t.Errorf("store.SetStatus lead to status %v, want %v", got.Status, teian.StatusDone)
+	}
+	if len(got.History) != 2 {
+		t.Fatalf("store.SetStatus twice lead to history of length %d, want 2", len(got.History))
+	}
+	h := got.History[1]
+	if h.From != teian.StatusPlanned || h.To != teian.StatusDone || h.By != "admin" {
+		t.Errorf("store.SetStatus recorded %#v", h)
+	}
+
+	if err := store.SetStatus(ctx, username, 2, teian.StatusDone, "admin"); err == nil {
+		t.Error("store.SetStatus on missing entry expected to return error")
+	}
+}
+
+func TestAddReply(t *testing.T) {
+	store, f := setup()
+	defer teardown(store, f)
+	ctx := context.Background()
+
+	username := "john"
+	if err := store.Create(ctx, username, &teian.Suggestion{Text: "reply test"}); err != nil {
+		t.Fatal("store.Create failed:", err)
+	}
+	if err := store.AddReply(ctx, username, 1, &teian.Reply{Username: "admin", Text: "thanks"}); err != nil {
+		t.Fatal("store.AddReply failed:", err)
+	}
+	out, err := store.OfUser(ctx, username)
+	if err != nil {
+		t.Fatal("store.OfUser failed:", err)
+	}
+	replies := out[0].Replies
+	if len(replies) != 1 {
+		t.Fatalf("store.AddReply lead to %d replies, want 1", len(replies))
+	}
+	got := replies[0]
+	want := teian.Reply{Username: "admin", Text: "thanks", Created: got.Created}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("store.AddReply lead to \n%#v, want \n%#v", got, want)
+	}
+	if got.Created.IsZero() {
+		t.Error("store.AddReply should set reply creation time")
+	}
+}
+
+func TestOfUser_noSuggestions(t *testing.T) {
+	store, f := setup()
+	defer teardown(store, f)
+	ctx := context.Background()
+
+	out, err := store.OfUser(ctx, "nobody")
+	if err != nil {
+		t.Fatal("store.OfUser for user without suggestions failed:", err)
+	}
+	if len(out) != 0 {
+		t.Errorf("store.OfUser for user without suggestions returned %d results", len(out))
+	}
+}
+
+func TestEdit(t *testing.T) {
+	store, f := setup()
+	defer teardown(store, f)
+	ctx := context.Background()
+
+	username := "john"
+	if err := store.Create(ctx, username, &teian.Suggestion{Text: "frist"}); err != nil {
+		t.Fatal("store.Create failed:", err)
+	}
+	if err := store.Edit(ctx, username, 1, "first"); err != nil {
+		t.Fatal("store.Edit failed:", err)
+	}
+	out, err := store.OfUser(ctx, username)
+	if err != nil {
+		t.Fatal("store.OfUser failed:", err)
+	}
+	if got, want := out[0].Text, "first"; got != want {
+		t.Errorf("store.Edit lead to text %q, want %q", got, want)
+	}
+	if err := store.Edit(ctx, "mary", 1, "not mine"); err == nil {
+		t.Error("store.Edit of another user's suggestion expected to return error")
+	}
+}
+
+func TestGet(t *testing.T) {
+	store, f := setup()
+	defer teardown(store, f)
+	ctx := context.Background()
+
+	username := "john"
+	if err := store.Create(ctx, username, &teian.Suggestion{Text: "get test"}); err != nil {
+		t.Fatal("store.Create failed:", err)
+	}
+	got, err := store.Get(ctx, 1)
+	if err != nil {
+		t.Fatal("store.Get failed:", err)
+	}
+	want := &teian.Suggestion{ID: 1, Username: username, Text: "get test", Created: got.Created}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("store.Get(ctx, 1) = \n%#v, want \n%#v", got, want)
+	}
+	if _, err := store.Get(ctx, 2); err == nil {
+		t.Error("store.Get on missing entry expected to return error")
+	}
+	if err := store.Delete(ctx, "mary", 1, "mary"); err == nil {
+		t.Error("store.Delete of another user's suggestion expected to return error")
+	}
+}
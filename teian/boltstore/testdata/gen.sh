@@ -0,0 +1,115 @@
+#!/bin/sh
+# gen.sh recreates the database fixtures of the migration tests. Each
+# fixture is written by the store of the commit that last used its schema so
+# that the tests check the migrations against real databases of the past
+# rather than against databases built with the current code.
+#
+# Every fixture holds the same data: suggestions "one" and "three" of john
+# and "two" of mary, with IDs 1 to 3, and 3 MB of upload quota used by john.
+#
+# Run it from the root of the repository:
+#
+#	sh teian/boltstore/testdata/gen.sh
+set -e
+
+out=$(pwd)/teian/boltstore/testdata
+
+# gen writes fixture $2 with the store of commit $1 using the program of API
+# era $3.
+gen() {
+	wt=$(mktemp -d)
+	git worktree add --detach "$wt" "$1" >/dev/null
+	mkdir "$wt/genfixture"
+	sed -n "/^# begin $3\$/,/^# end $3\$/p" "$0" | sed '1d;$d' >"$wt/genfixture/main.go"
+	rm -f "$out/$2"
+	(cd "$wt" && go run ./genfixture "$out/$2")
+	git worktree remove --force "$wt"
+}
+
+gen 86fcbe6 version0.db nocontext
+gen 113dcba version0-suggestion-keys.db nocontext
+gen 184c7f6 version0-upgraded.db nocontext
+gen a0dda9f version6.db context
+gen f19719e version7.db limit
+gen 90613c5 version8.db limit
+gen 85c786c version9.db limit
+exit 0
+
+# begin nocontext
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/kusubooru/teian/teian"
+	"github.com/kusubooru/teian/teian/boltstore"
+)
+
+func main() {
+	store := boltstore.NewSuggestionStore(os.Args[1], 10<<20)
+	defer store.Close()
+	for _, s := range []struct{ username, text string }{{"john", "one"}, {"mary", "two"}, {"john", "three"}} {
+		if err := store.Create(s.username, &teian.Suggestion{Text: s.text}); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if _, err := store.CheckQuota("john", 3<<20); err != nil {
+		log.Fatal(err)
+	}
+}
+# end nocontext
+
+# begin context
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/kusubooru/teian/teian"
+	"github.com/kusubooru/teian/teian/boltstore"
+)
+
+func main() {
+	ctx := context.Background()
+	store := boltstore.NewSuggestionStore(os.Args[1], 10<<20)
+	defer store.Close()
+	for _, s := range []struct{ username, text string }{{"john", "one"}, {"mary", "two"}, {"john", "three"}} {
+		if err := store.Create(ctx, s.username, &teian.Suggestion{Text: s.text}); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if _, err := store.CheckQuota(ctx, "john", 3<<20); err != nil {
+		log.Fatal(err)
+	}
+}
+# end context
+
+# begin limit
+package main
+
+import (
+	"context"
+	"log"
+	"os"
+
+	"github.com/kusubooru/teian/teian"
+	"github.com/kusubooru/teian/teian/boltstore"
+)
+
+func main() {
+	ctx := context.Background()
+	store := boltstore.NewSuggestionStore(os.Args[1])
+	defer store.Close()
+	for _, s := range []struct{ username, text string }{{"john", "one"}, {"mary", "two"}, {"john", "three"}} {
+		if err := store.Create(ctx, s.username, &teian.Suggestion{Text: s.text}); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if _, err := store.CheckQuota(ctx, "john", 3<<20, 10<<20); err != nil {
+		log.Fatal(err)
+	}
+}
+# end limit
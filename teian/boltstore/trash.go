@@ -0,0 +1,118 @@
+package boltstore
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/boltdb/bolt"
+	"github.com/kusubooru/teian/teian"
+)
+
+// The trash bucket holds the deleted suggestions keyed by big-endian ID. A
+// deleted suggestion is removed from the suggestions bucket and every index
+// so that no other method sees it. Its revisions and votes are kept until it
+// is purged.
+
+func (db *Boltstore) Delete(ctx context.Context, username string, id uint64, by string) error {
+	return db.updateTx(ctx, func(tx *bolt.Tx) error {
+		return trashSuggestion(tx, username, id, by)
+	})
+}
+
+// trashSuggestion moves the suggestion with id of username to the trash. An
+// empty username matches any suggestion.
+func trashSuggestion(tx *bolt.Tx, username string, id uint64, by string) error {
+	s, err := getSuggestion(tx, username, id)
+	if err != nil {
+		return err
+	}
+	if err := removeSuggestion(tx, s); err != nil {
+		return err
+	}
+	s.DeletedBy = by
+	s.DeletedAt = time.Now()
+	value, err := encodeSuggestion(s)
+	if err != nil {
+		return err
+	}
+	return tx.Bucket([]byte(trashBucket)).Put(itob(id), value)
+}
+
+func (db *Boltstore) Trash(ctx context.Context) ([]teian.Suggestion, error) {
+	var suggs []teian.Suggestion
+	err := db.viewTx(ctx, func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(trashBucket)).ForEach(func(k, v []byte) error {
+			s, err := decodeSuggestion(v)
+			if err != nil {
+				return fmt.Errorf("deleted suggestion %d: %v", btoi(k), err)
+			}
+			suggs = append(suggs, *s)
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(suggs, func(i, j int) bool {
+		return suggs[i].DeletedAt.After(suggs[j].DeletedAt)
+	})
+	return suggs, nil
+}
+
+// getTrashed returns the deleted suggestion with id.
+func getTrashed(tx *bolt.Tx, id uint64) (*teian.Suggestion, error) {
+	value := tx.Bucket([]byte(trashBucket)).Get(itob(id))
+	if value == nil {
+		return nil, teian.ErrNotFound
+	}
+	return decodeSuggestion(value)
+}
+
+func (db *Boltstore) Trashed(ctx context.Context, id uint64) (*teian.Suggestion, error) {
+	var sugg *teian.Suggestion
+	err := db.viewTx(ctx, func(tx *bolt.Tx) error {
+		var err error
+		sugg, err = getTrashed(tx, id)
+		return err
+	})
+	return sugg, err
+}
+
+func (db *Boltstore) Restore(ctx context.Context, id uint64) error {
+	return db.updateTx(ctx, func(tx *bolt.Tx) error {
+		s, err := getTrashed(tx, id)
+		if err != nil {
+			return err
+		}
+		s.DeletedBy = ""
+		s.DeletedAt = time.Time{}
+		if err := insertSuggestion(tx, s); err != nil {
+			return err
+		}
+		return tx.Bucket([]byte(trashBucket)).Delete(itob(id))
+	})
+}
+
+func (db *Boltstore) Purge(ctx context.Context, id uint64) error {
+	return db.updateTx(ctx, func(tx *bolt.Tx) error {
+		if _, err := getTrashed(tx, id); err != nil {
+			return err
+		}
+		if err := deleteRevisions(tx, id); err != nil {
+			return err
+		}
+		keys, _, err := votesOn(tx, id)
+		if err != nil {
+			return err
+		}
+		votes := tx.Bucket([]byte(votesBucket))
+		for _, k := range keys {
+			if err := votes.Delete(k); err != nil {
+				return err
+			}
+		}
+		return tx.Bucket([]byte(trashBucket)).Delete(itob(id))
+	})
+}
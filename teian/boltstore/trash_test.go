@@ -0,0 +1,91 @@
+package boltstore
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+func TestTrash(t *testing.T) {
+	store, f := setup()
+	defer teardown(store, f)
+	ctx := context.Background()
+
+	for _, text := range []string{"first idea", "second idea", "third idea"} {
+		if err := store.Create(ctx, "john", &teian.Suggestion{Text: text, Public: true}); err != nil {
+			t.Fatal("store.Create failed:", err)
+		}
+	}
+	if err := store.Vote(ctx, 2, "mary", 1); err != nil {
+		t.Fatal("store.Vote failed:", err)
+	}
+	if err := store.Delete(ctx, "john", 2, "admin"); err != nil {
+		t.Fatal("store.Delete failed:", err)
+	}
+	if err := store.Delete(ctx, "john", 3, "john"); err != nil {
+		t.Fatal("store.Delete failed:", err)
+	}
+
+	// Deleted suggestions are hidden everywhere else.
+	if _, err := store.Get(ctx, 2); err == nil {
+		t.Error("store.Get of deleted suggestion expected to return error")
+	}
+	all, err := store.All(ctx)
+	if err != nil {
+		t.Fatal("store.All failed:", err)
+	}
+	if got, want := ids(all), []uint64{1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("store.All after delete = %v, want %v", got, want)
+	}
+	found, err := store.Search(ctx, "idea")
+	if err != nil {
+		t.Fatal("store.Search failed:", err)
+	}
+	if got, want := ids(found), []uint64{1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("store.Search after delete = %v, want %v", got, want)
+	}
+
+	trash, err := store.Trash(ctx)
+	if err != nil {
+		t.Fatal("store.Trash failed:", err)
+	}
+	if got, want := ids(trash), []uint64{3, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("store.Trash = %v, want %v", got, want)
+	}
+	if trash[1].DeletedBy != "admin" || trash[1].DeletedAt.IsZero() {
+		t.Errorf("deleted suggestion has DeletedBy %q and DeletedAt %v", trash[1].DeletedBy, trash[1].DeletedAt)
+	}
+
+	if err := store.Restore(ctx, 2); err != nil {
+		t.Fatal("store.Restore failed:", err)
+	}
+	s, err := store.Get(ctx, 2)
+	if err != nil {
+		t.Fatal("store.Get of restored suggestion failed:", err)
+	}
+	if s.DeletedBy != "" || s.Upvotes != 1 {
+		t.Errorf("restored suggestion = %+v, want no deletion and its vote", s)
+	}
+	page, err := store.Query(ctx, teian.Query{Order: teian.OrderVotesDesc})
+	if err != nil {
+		t.Fatal("store.Query failed:", err)
+	}
+	if got, want := ids(page.Suggestions), []uint64{2, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("store.Query by votes after restore = %v, want %v", got, want)
+	}
+
+	if err := store.Purge(ctx, 2); err == nil {
+		t.Error("store.Purge of suggestion not in the trash expected to return error")
+	}
+	if err := store.Purge(ctx, 3); err != nil {
+		t.Fatal("store.Purge failed:", err)
+	}
+	if err := store.Restore(ctx, 3); err == nil {
+		t.Error("store.Restore of purged suggestion expected to return error")
+	}
+	if trash, err = store.Trash(ctx); err != nil || len(trash) != 0 {
+		t.Errorf("store.Trash after purge = %v, %v, want empty", ids(trash), err)
+	}
+}
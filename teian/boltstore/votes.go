@@ -0,0 +1,90 @@
+package boltstore
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/boltdb/bolt"
+	"github.com/kusubooru/teian/teian"
+)
+
+// The votes bucket holds the vote of each user on each suggestion with keys
+// of the form username + "\x00" + big-endian ID and a single signed byte as
+// value. The voteScores bucket orders suggestions by their net votes with
+// keys of the form scoreKey + big-endian ID and empty values.
+
+// scoreKey encodes the net votes of a suggestion so that bytewise order
+// matches numeric order, negative scores included.
+func scoreKey(votes int, id uint64) []byte {
+	return append(itob(uint64(int64(votes))^(1<<63)), itob(id)...)
+}
+
+func (db *Boltstore) Vote(ctx context.Context, id uint64, username string, vote int) error {
+	if vote < -1 || vote > 1 {
+		return teian.ErrBadVote
+	}
+	return db.updateTx(ctx, func(tx *bolt.Tx) error {
+		s, err := getSuggestion(tx, "", id)
+		if err != nil {
+			return err
+		}
+		if err := s.CanVote(username); err != nil {
+			return err
+		}
+		votes := tx.Bucket([]byte(votesBucket))
+		key := userKey(username, id)
+		old := 0
+		if v := votes.Get(key); len(v) == 1 {
+			old = int(int8(v[0]))
+		}
+		if old == vote {
+			return nil
+		}
+
+		scores := tx.Bucket([]byte(voteScoresBucket))
+		if err := scores.Delete(scoreKey(s.Votes(), id)); err != nil {
+			return err
+		}
+		s.ApplyVote(old, vote)
+		if err := scores.Put(scoreKey(s.Votes(), id), []byte{}); err != nil {
+			return err
+		}
+		if err := putSuggestion(tx, s); err != nil {
+			return err
+		}
+		if vote == 0 {
+			return votes.Delete(key)
+		}
+		return votes.Put(key, []byte{byte(int8(vote))})
+	})
+}
+
+// votesOn returns the keys and values of the votes on suggestion id. The
+// votes bucket is keyed by user so the whole bucket is scanned and the votes
+// collected before the caller changes it.
+func votesOn(tx *bolt.Tx, id uint64) (keys, values [][]byte, err error) {
+	suffix := itob(id)
+	err = tx.Bucket([]byte(votesBucket)).ForEach(func(k, v []byte) error {
+		if bytes.HasSuffix(k, suffix) && len(v) == 1 {
+			keys = append(keys, k)
+			values = append(values, v)
+		}
+		return nil
+	})
+	return keys, values, err
+}
+
+func (db *Boltstore) VotesOf(ctx context.Context, username string) (map[uint64]int, error) {
+	votes := make(map[uint64]int)
+	err := db.viewTx(ctx, func(tx *bolt.Tx) error {
+		prefix := userPrefix(username)
+		c := tx.Bucket([]byte(votesBucket)).Cursor()
+		for k, v := c.Seek(prefix); k != nil && bytes.HasPrefix(k, prefix); k, v = c.Next() {
+			if len(v) == 1 {
+				votes[btoi(k[len(prefix):])] = int(int8(v[0]))
+			}
+		}
+		return nil
+	})
+	return votes, err
+}
@@ -0,0 +1,87 @@
+package boltstore
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+func TestVote(t *testing.T) {
+	store, f := setup()
+	defer teardown(store, f)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if err := store.Create(ctx, "john", &teian.Suggestion{Text: "vote test", Public: true}); err != nil {
+			t.Fatal("store.Create failed:", err)
+		}
+	}
+	votes := []struct {
+		id       uint64
+		username string
+		vote     int
+	}{
+		{1, "mary", 1},
+		{1, "bob", -1},
+		{1, "ann", -1},
+		{2, "mary", 1},
+		{2, "mary", 1}, // voting twice counts once
+		{3, "bob", 1},
+		{3, "ann", 1},
+		{3, "bob", -1}, // changed vote
+		{3, "ann", 0},  // removed vote
+	}
+	for _, v := range votes {
+		if err := store.Vote(ctx, v.id, v.username, v.vote); err != nil {
+			t.Fatalf("store.Vote(ctx, %d, %q, %d) failed: %v", v.id, v.username, v.vote, err)
+		}
+	}
+
+	want := map[uint64][2]int{1: {1, 2}, 2: {1, 0}, 3: {0, 1}}
+	for id, w := range want {
+		s, err := store.Get(ctx, id)
+		if err != nil {
+			t.Fatal("store.Get failed:", err)
+		}
+		if got := [2]int{s.Upvotes, s.Downvotes}; got != w {
+			t.Errorf("suggestion %d has up/down votes %v, want %v", id, got, w)
+		}
+	}
+
+	got, err := store.VotesOf(ctx, "bob")
+	if err != nil {
+		t.Fatal("store.VotesOf failed:", err)
+	}
+	if want := map[uint64]int{1: -1, 3: -1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("store.VotesOf(ctx, %q) = %v, want %v", "bob", got, want)
+	}
+
+	page, err := store.Query(ctx, teian.Query{Order: teian.OrderVotesDesc})
+	if err != nil {
+		t.Fatal("store.Query failed:", err)
+	}
+	if got, want := ids(page.Suggestions), []uint64{2, 3, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("store.Query ordered by votes returned IDs %v, want %v", got, want)
+	}
+
+	if err := store.Vote(ctx, 1, "mary", 2); err != teian.ErrBadVote {
+		t.Errorf("store.Vote with vote 2 returned %v, want %v", err, teian.ErrBadVote)
+	}
+	if err := store.Vote(ctx, 9, "mary", 1); err == nil {
+		t.Error("store.Vote on missing entry expected to return error")
+	}
+
+	// Deleting a suggestion must remove it from the votes order.
+	if err := store.Delete(ctx, "john", 2, "john"); err != nil {
+		t.Fatal("store.Delete failed:", err)
+	}
+	page, err = store.Query(ctx, teian.Query{Order: teian.OrderVotesAsc})
+	if err != nil {
+		t.Fatal("store.Query failed:", err)
+	}
+	if got, want := ids(page.Suggestions), []uint64{1, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("store.Query ordered by votes after delete returned IDs %v, want %v", got, want)
+	}
+}
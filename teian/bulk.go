@@ -0,0 +1,22 @@
+package teian
+
+// BulkAction is an action that admins can apply to many suggestions at once.
+type BulkAction int
+
+const (
+	BulkDelete BulkAction = iota + 1
+	BulkStatus
+	BulkCategory
+)
+
+// BulkOp describes a bulk action. Status is used by BulkStatus and Category
+// by BulkCategory. By is the admin applying it.
+type BulkOp struct {
+	Action   BulkAction
+	Status   Status
+	Category string
+	By       string
+}
+
+// ErrBadBulkAction is returned for a BulkOp with an unknown action.
+var ErrBadBulkAction = Errorf(ErrInvalid, "unknown bulk action")
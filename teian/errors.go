@@ -0,0 +1,38 @@
+package teian
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The kinds of errors every SuggestionStore returns when an operation fails
+// because of what was asked rather than because the storage failed. Use
+// errors.Is to check the kind of an error.
+var (
+	// ErrNotFound is returned when a suggestion, revision or category does
+	// not exist. Suggestions in the trash are not found by any method but
+	// those of the trash.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict is returned when a change cannot be applied to the
+	// current state of a suggestion, such as merging a suggestion that is
+	// already merged.
+	ErrConflict = errors.New("conflict")
+	// ErrInvalid is returned when the arguments of an operation are
+	// invalid, such as an unknown category or a bad cursor.
+	ErrInvalid = errors.New("invalid")
+)
+
+// Error is an error of one of the kinds ErrNotFound, ErrConflict or
+// ErrInvalid with a message that describes it.
+type Error struct {
+	Kind error
+	Msg  string
+}
+
+func (e *Error) Error() string { return e.Msg }
+func (e *Error) Unwrap() error { return e.Kind }
+
+// Errorf returns an Error of kind with a formatted message.
+func Errorf(kind error, format string, a ...interface{}) error {
+	return &Error{Kind: kind, Msg: fmt.Sprintf(format, a...)}
+}
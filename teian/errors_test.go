@@ -0,0 +1,29 @@
+package teian
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestErrorf(t *testing.T) {
+	err := Errorf(ErrConflict, "suggestion %d is already merged into %d", 2, 1)
+	if got, want := err.Error(), "suggestion 2 is already merged into 1"; got != want {
+		t.Errorf("Errorf message = %q, want %q", got, want)
+	}
+	wrapped := fmt.Errorf("merge failed: %w", err)
+	if !errors.Is(wrapped, ErrConflict) {
+		t.Error("wrapped Errorf of ErrConflict is not ErrConflict")
+	}
+	if errors.Is(wrapped, ErrNotFound) {
+		t.Error("wrapped Errorf of ErrConflict is ErrNotFound")
+	}
+}
+
+func TestErrorKinds(t *testing.T) {
+	for _, err := range []error{ErrUnknownCategory, ErrBadVote, ErrBadBulkAction} {
+		if !errors.Is(err, ErrInvalid) {
+			t.Errorf("%q is not ErrInvalid", err)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package teian
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The formats suggestions can be exported to.
+const (
+	FormatCSV   = "csv"
+	FormatJSON  = "json"
+	FormatJSONL = "jsonl"
+)
+
+// ExportFormats returns the export formats.
+func ExportFormats() []string {
+	return []string{FormatCSV, FormatJSON, FormatJSONL}
+}
+
+// ExportContentType returns the MIME type of an export format.
+func ExportContentType(format string) string {
+	switch format {
+	case FormatCSV:
+		return "text/csv; charset=utf-8"
+	case FormatJSONL:
+		return "application/x-ndjson; charset=utf-8"
+	}
+	return "application/json; charset=utf-8"
+}
+
+// exportPageSize is how many suggestions Export reads from the store at a
+// time.
+const exportPageSize = 500
+
+// An Exporter writes suggestions in one of the export formats. Close must be
+// called after the last suggestion to complete the output.
+type Exporter interface {
+	Write(s *Suggestion) error
+	Close() error
+}
+
+// NewExporter returns an Exporter that writes to w in format.
+func NewExporter(w io.Writer, format string) (Exporter, error) {
+	switch format {
+	case FormatCSV:
+		e := &csvExporter{w: csv.NewWriter(w)}
+		return e, e.w.Write(CSVHeader)
+	case FormatJSON:
+		return &jsonExporter{w: w}, nil
+	case FormatJSONL:
+		return &jsonlExporter{json.NewEncoder(w)}, nil
+	}
+	return nil, fmt.Errorf("unknown export format %q", format)
+}
+
+// Export writes every suggestion of store that matches q to e and closes
+// it. The limit and cursor of q are ignored. When q has text the
+// suggestions are searched and written ranked by relevance like the admin
+// list shows them.
+func Export(ctx context.Context, store SuggestionStore, q Query, e Exporter) error {
+	q.Limit = exportPageSize
+	q.Cursor = ""
+	if q.Text != "" {
+		suggs, err := store.Search(ctx, q.Text)
+		if err != nil {
+			return err
+		}
+		q.Text = ""
+		for i := range suggs {
+			if !q.Match(&suggs[i]) {
+				continue
+			}
+			if err := e.Write(&suggs[i]); err != nil {
+				return err
+			}
+		}
+		return e.Close()
+	}
+	for {
+		page, err := store.Query(ctx, q)
+		if err != nil {
+			return err
+		}
+		for i := range page.Suggestions {
+			if err := e.Write(&page.Suggestions[i]); err != nil {
+				return err
+			}
+		}
+		if page.Next == "" {
+			return e.Close()
+		}
+		q.Cursor = page.Next
+	}
+}
+
+// CSVHeader holds the columns of the CSV export in order. Tags are
+// separated by spaces and the history and replies are not included.
+var CSVHeader = []string{
+	"id", "username", "created", "status", "category", "tags", "public",
+	"anonymous", "upvotes", "downvotes", "replies", "merged_into", "text",
+}
+
+type csvExporter struct {
+	w *csv.Writer
+}
+
+func (e *csvExporter) Write(s *Suggestion) error {
+	return e.w.Write([]string{
+		strconv.FormatUint(s.ID, 10),
+		s.Username,
+		s.Created.UTC().Format(time.RFC3339),
+		s.Status.String(),
+		s.Category,
+		strings.Join(s.Tags, " "),
+		strconv.FormatBool(s.Public),
+		strconv.FormatBool(s.Anonymous),
+		strconv.Itoa(s.Upvotes),
+		strconv.Itoa(s.Downvotes),
+		strconv.Itoa(len(s.Replies)),
+		strconv.FormatUint(s.MergedInto, 10),
+		s.Text,
+	})
+}
+
+func (e *csvExporter) Close() error {
+	e.w.Flush()
+	return e.w.Error()
+}
+
+// jsonExporter writes a JSON array one element at a time so that the whole
+// export is never held in memory.
+type jsonExporter struct {
+	w io.Writer
+	n int
+}
+
+func (e *jsonExporter) Write(s *Suggestion) error {
+	sep := ",\n"
+	if e.n == 0 {
+		sep = "[\n"
+	}
+	e.n++
+	b, err := json.Marshal(exportSuggestion(s))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(e.w, "%s%s", sep, b)
+	return err
+}
+
+func (e *jsonExporter) Close() error {
+	end := "\n]\n"
+	if e.n == 0 {
+		end = "[]\n"
+	}
+	_, err := io.WriteString(e.w, end)
+	return err
+}
+
+type jsonlExporter struct {
+	enc *json.Encoder
+}
+
+func (e *jsonlExporter) Write(s *Suggestion) error { return e.enc.Encode(exportSuggestion(s)) }
+func (e *jsonlExporter) Close() error              { return nil }
+
+// exportSuggestion returns s with its times in UTC so that exports do not
+// depend on the time zone of the server.
+func exportSuggestion(s *Suggestion) *Suggestion {
+	c := *s
+	c.Created = c.Created.UTC()
+	c.History = make([]StatusChange, len(s.History))
+	for i, h := range s.History {
+		h.At = h.At.UTC()
+		c.History[i] = h
+	}
+	c.Replies = make([]Reply, len(s.Replies))
+	for i, r := range s.Replies {
+		r.Created = r.Created.UTC()
+		c.Replies[i] = r
+	}
+	return &c
+}
@@ -0,0 +1,100 @@
+package teian
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func exportAll(t *testing.T, format string, suggs []Suggestion) string {
+	t.Helper()
+	var buf bytes.Buffer
+	e, err := NewExporter(&buf, format)
+	if err != nil {
+		t.Fatalf("NewExporter(%q) failed: %v", format, err)
+	}
+	for i := range suggs {
+		if err := e.Write(&suggs[i]); err != nil {
+			t.Fatalf("%s export failed: %v", format, err)
+		}
+	}
+	if err := e.Close(); err != nil {
+		t.Fatalf("%s export close failed: %v", format, err)
+	}
+	return buf.String()
+}
+
+var exportSuggestions = []Suggestion{
+	{
+		ID:       1,
+		Username: "jin",
+		Text:     "more tags,\nplease",
+		Created:  time.Date(2020, 5, 1, 12, 30, 0, 0, time.FixedZone("JST", 9*60*60)),
+		Status:   StatusPlanned,
+		Tags:     []string{"touhou", "1girl"},
+		Upvotes:  2,
+		Public:   true,
+	},
+	{ID: 2, Username: "mugen", Text: "dark theme", Created: time.Date(2020, 5, 2, 0, 0, 0, 0, time.UTC)},
+}
+
+func TestExport_csv(t *testing.T) {
+	out := exportAll(t, FormatCSV, exportSuggestions)
+	records, err := csv.NewReader(strings.NewReader(out)).ReadAll()
+	if err != nil {
+		t.Fatal("reading CSV export failed:", err)
+	}
+	if len(records) != 3 {
+		t.Fatalf("CSV export has %d records, want header and 2 suggestions", len(records))
+	}
+	if !reflect.DeepEqual(records[0], CSVHeader) {
+		t.Errorf("CSV header = %v, want %v", records[0], CSVHeader)
+	}
+	want := []string{"1", "jin", "2020-05-01T03:30:00Z", "planned", "", "touhou 1girl", "true", "false", "2", "0", "0", "0", "more tags,\nplease"}
+	if !reflect.DeepEqual(records[1], want) {
+		t.Errorf("CSV record = %q, want %q", records[1], want)
+	}
+}
+
+func TestExport_json(t *testing.T) {
+	for _, format := range []string{FormatJSON, FormatJSONL} {
+		out := exportAll(t, format, exportSuggestions)
+		var got []map[string]interface{}
+		if format == FormatJSON {
+			if err := json.Unmarshal([]byte(out), &got); err != nil {
+				t.Fatalf("decoding %s export failed: %v\n%s", format, err, out)
+			}
+		} else {
+			dec := json.NewDecoder(strings.NewReader(out))
+			for dec.More() {
+				var m map[string]interface{}
+				if err := dec.Decode(&m); err != nil {
+					t.Fatalf("decoding %s export failed: %v\n%s", format, err, out)
+				}
+				got = append(got, m)
+			}
+			if n := strings.Count(out, "\n"); n != 2 {
+				t.Errorf("%s export has %d lines, want 2", format, n)
+			}
+		}
+		if len(got) != 2 {
+			t.Fatalf("%s export has %d suggestions, want 2", format, len(got))
+		}
+		if got[0]["created"] != "2020-05-01T03:30:00Z" || got[0]["status"] != "planned" || got[1]["username"] != "mugen" {
+			t.Errorf("%s export = %v", format, got)
+		}
+	}
+	if out := exportAll(t, FormatJSON, nil); strings.TrimSpace(out) != "[]" {
+		t.Errorf("empty JSON export = %q, want []", out)
+	}
+}
+
+func TestNewExporter_unknownFormat(t *testing.T) {
+	if _, err := NewExporter(&bytes.Buffer{}, "xml"); err == nil {
+		t.Error("NewExporter with unknown format expected to return error")
+	}
+}
@@ -0,0 +1,219 @@
+package teian
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ImportError is a row of an import that is not a valid suggestion. Rows of
+// CSV files are numbered like in a spreadsheet so the header is row 1.
+// Elements of JSON arrays and lines of JSONL files are numbered from 1.
+type ImportError struct {
+	Row int
+	Err error
+}
+
+func (e *ImportError) Error() string {
+	return fmt.Sprintf("row %d: %v", e.Row, e.Err)
+}
+
+// ImportRow is a valid suggestion read from a row of an import.
+type ImportRow struct {
+	Row        int
+	Suggestion Suggestion
+}
+
+// ReadImport reads the suggestions of a file in one of the export formats.
+// The rows that are not valid suggestions are returned as ImportErrors while
+// err is only returned when the file cannot be read at all.
+//
+// CSV files must have a header naming their columns which can be any of
+// CSVHeader in any order. Only username and text are required. The replies
+// column is ignored since the replies themselves are not exported to CSV.
+// Dates can be RFC 3339 timestamps or plain YYYY-MM-DD dates.
+func ReadImport(r io.Reader, format string) (rows []ImportRow, invalid []*ImportError, err error) {
+	add := func(row int, s Suggestion, err error) {
+		if err == nil {
+			err = s.validateImport()
+		}
+		if err != nil {
+			invalid = append(invalid, &ImportError{row, err})
+			return
+		}
+		rows = append(rows, ImportRow{row, s})
+	}
+	switch format {
+	case FormatCSV:
+		err = readImportCSV(r, add)
+	case FormatJSON:
+		var elems []json.RawMessage
+		if err = json.NewDecoder(r).Decode(&elems); err != nil {
+			return nil, nil, fmt.Errorf("could not decode JSON array: %v", err)
+		}
+		for i, row := range elems {
+			var s Suggestion
+			err := json.Unmarshal(row, &s)
+			add(i+1, s, err)
+		}
+	case FormatJSONL:
+		sc := bufio.NewScanner(r)
+		sc.Buffer(nil, 1<<20)
+		for row := 1; sc.Scan(); row++ {
+			line := bytes.TrimSpace(sc.Bytes())
+			if len(line) == 0 {
+				continue
+			}
+			var s Suggestion
+			err := json.Unmarshal(line, &s)
+			add(row, s, err)
+		}
+		err = sc.Err()
+	default:
+		err = fmt.Errorf("unknown import format %q", format)
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+	return rows, invalid, nil
+}
+
+func readImportCSV(r io.Reader, add func(row int, s Suggestion, err error)) error {
+	cr := csv.NewReader(r)
+	header, err := cr.Read()
+	if err != nil {
+		return fmt.Errorf("could not read CSV header: %v", err)
+	}
+	known := make(map[string]bool)
+	for _, name := range CSVHeader {
+		known[name] = true
+	}
+	col := make(map[string]int)
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !known[name] {
+			return fmt.Errorf("unknown CSV column %q", name)
+		}
+		col[name] = i
+	}
+	for _, name := range []string{"username", "text"} {
+		if _, ok := col[name]; !ok {
+			return fmt.Errorf("CSV column %q is missing", name)
+		}
+	}
+	for row := 2; ; row++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return nil
+		}
+		if perr, ok := err.(*csv.ParseError); ok && perr.Err == csv.ErrFieldCount {
+			add(row, Suggestion{}, errors.New("wrong number of fields"))
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		get := func(name string) string {
+			if i, ok := col[name]; ok {
+				return strings.TrimSpace(record[i])
+			}
+			return ""
+		}
+		s, err := parseCSVSuggestion(get)
+		add(row, s, err)
+	}
+}
+
+// parseCSVSuggestion builds a suggestion out of the columns of a CSV row.
+func parseCSVSuggestion(get func(column string) string) (Suggestion, error) {
+	s := Suggestion{
+		Username: get("username"),
+		Text:     get("text"),
+		Category: get("category"),
+		Tags:     ParseTags(get("tags")),
+	}
+	var err error
+	if v := get("id"); v != "" {
+		if s.ID, err = strconv.ParseUint(v, 10, 64); err != nil {
+			return s, fmt.Errorf("bad id: %v", err)
+		}
+	}
+	if v := get("created"); v != "" {
+		if s.Created, err = parseImportTime(v); err != nil {
+			return s, err
+		}
+	}
+	if v := get("status"); v != "" {
+		if s.Status, err = ParseStatus(v); err != nil {
+			return s, err
+		}
+	}
+	for _, b := range []struct {
+		name string
+		v    *bool
+	}{{"public", &s.Public}, {"anonymous", &s.Anonymous}} {
+		if v := get(b.name); v != "" {
+			if *b.v, err = strconv.ParseBool(v); err != nil {
+				return s, fmt.Errorf("bad %s: %v", b.name, err)
+			}
+		}
+	}
+	for _, n := range []struct {
+		name string
+		v    *int
+	}{{"upvotes", &s.Upvotes}, {"downvotes", &s.Downvotes}} {
+		if v := get(n.name); v != "" {
+			if *n.v, err = strconv.Atoi(v); err != nil {
+				return s, fmt.Errorf("bad %s: %v", n.name, err)
+			}
+		}
+	}
+	if v := get("merged_into"); v != "" {
+		if s.MergedInto, err = strconv.ParseUint(v, 10, 64); err != nil {
+			return s, fmt.Errorf("bad merged_into: %v", err)
+		}
+	}
+	return s, nil
+}
+
+func parseImportTime(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return t, fmt.Errorf("bad created %q: must be an RFC 3339 timestamp or a YYYY-MM-DD date", v)
+	}
+	return t, nil
+}
+
+// validateImport checks that s can be imported. Attachments and the trash
+// fields are cleared since the files and the trash are not part of an
+// export.
+func (s *Suggestion) validateImport() error {
+	s.Username = strings.TrimSpace(s.Username)
+	if _, err := s.Status.MarshalText(); err != nil {
+		return err
+	}
+	switch {
+	case s.Username == "":
+		return errors.New("username is empty")
+	case strings.TrimSpace(s.Text) == "":
+		return errors.New("text is empty")
+	case s.Upvotes < 0 || s.Downvotes < 0:
+		return errors.New("votes cannot be negative")
+	case s.MergedInto != 0 && s.MergedInto == s.ID:
+		return errors.New("suggestion cannot be merged into itself")
+	}
+	s.Attachments = nil
+	s.DeletedBy = ""
+	s.DeletedAt = time.Time{}
+	return nil
+}
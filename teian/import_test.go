@@ -0,0 +1,71 @@
+package teian
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReadImport_csv(t *testing.T) {
+	in := `Username,Text,Created,Status,Tags,ID
+jin,"more tags,
+please",2020-05-01,planned,Touhou 1girl,7
+,no username,,,,
+mugen,bad status,,bogus,,
+mugen,dark theme,2020-05-02T09:00:00+09:00,,,
+mugen,too,many,fields,,,
+`
+	rows, invalid, err := ReadImport(strings.NewReader(in), FormatCSV)
+	if err != nil {
+		t.Fatal("ReadImport failed:", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("ReadImport returned %d rows, want 2", len(rows))
+	}
+	s := rows[0].Suggestion
+	if rows[0].Row != 2 || s.ID != 7 || s.Username != "jin" || s.Text != "more tags,\nplease" ||
+		s.Status != StatusPlanned || !s.Created.Equal(time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)) ||
+		strings.Join(s.Tags, " ") != "touhou 1girl" {
+		t.Errorf("ReadImport row %d = %+v", rows[0].Row, s)
+	}
+	if rows[1].Row != 5 || !rows[1].Suggestion.Created.Equal(time.Date(2020, 5, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("ReadImport row %d = %+v", rows[1].Row, rows[1].Suggestion)
+	}
+	var got []int
+	for _, e := range invalid {
+		got = append(got, e.Row)
+	}
+	if len(got) != 3 || got[0] != 3 || got[1] != 4 || got[2] != 6 {
+		t.Errorf("ReadImport invalid rows = %v, want [3 4 6]", invalid)
+	}
+
+	for _, in := range []string{"username,text,votes\n", "username\n", ""} {
+		if _, _, err := ReadImport(strings.NewReader(in), FormatCSV); err == nil {
+			t.Errorf("ReadImport(%q) expected to return error", in)
+		}
+	}
+}
+
+func TestReadImport_exported(t *testing.T) {
+	for _, format := range ExportFormats() {
+		out := exportAll(t, format, exportSuggestions)
+		rows, invalid, err := ReadImport(strings.NewReader(out), format)
+		if err != nil {
+			t.Fatalf("ReadImport of %s export failed: %v", format, err)
+		}
+		if len(invalid) != 0 || len(rows) != len(exportSuggestions) {
+			t.Fatalf("ReadImport of %s export = %v rows, invalid %v", format, len(rows), invalid)
+		}
+		for i, row := range rows {
+			want := exportSuggestions[i]
+			got := row.Suggestion
+			if got.ID != want.ID || got.Username != want.Username || got.Text != want.Text ||
+				got.Status != want.Status || !got.Created.Equal(want.Created) || got.Upvotes != want.Upvotes {
+				t.Errorf("ReadImport of %s export = %+v, want %+v", format, got, want)
+			}
+		}
+	}
+	if _, invalid, _ := ReadImport(strings.NewReader(`{"username":"jin","text":"x","status":"bogus"}`+"\n"), FormatJSONL); len(invalid) != 1 {
+		t.Errorf("ReadImport of JSONL with bad status returned invalid %v, want 1 row", invalid)
+	}
+}
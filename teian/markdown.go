@@ -0,0 +1,186 @@
+package teian
+
+import (
+	"html"
+	"html/template"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// Markdown renders a safe subset of Markdown as HTML. Paragraphs, lists,
+// fenced code blocks, `code`, *emphasis*, **strong** and [links](url) are
+// supported as well as references to the booru: "post #123" links to the
+// post and "tag:foo" to the posts with the tag. The text is always escaped
+// and only the HTML produced for these elements is let through. Links must
+// be relative or use http, https or mailto.
+func Markdown(text string) template.HTML {
+	var b strings.Builder
+	lines := strings.Split(strings.Replace(text, "\r\n", "\n", -1), "\n")
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case strings.TrimSpace(line) == "":
+			i++
+		case strings.HasPrefix(line, "```"):
+			i++
+			b.WriteString("<pre><code>")
+			for ; i < len(lines) && !strings.HasPrefix(lines[i], "```"); i++ {
+				b.WriteString(html.EscapeString(lines[i]))
+				b.WriteString("\n")
+			}
+			b.WriteString("</code></pre>\n")
+			i++ // closing fence
+		case listItem(line) != 0:
+			kind := listItem(line)
+			tag := "ul"
+			if kind == orderedItem {
+				tag = "ol"
+			}
+			b.WriteString("<" + tag + ">\n")
+			for ; i < len(lines) && listItem(lines[i]) == kind; i++ {
+				b.WriteString("<li>")
+				b.WriteString(inline(itemText(lines[i])))
+				b.WriteString("</li>\n")
+			}
+			b.WriteString("</" + tag + ">\n")
+		default:
+			var para []string
+			for ; i < len(lines) && strings.TrimSpace(lines[i]) != "" &&
+				!strings.HasPrefix(lines[i], "```") && listItem(lines[i]) == 0; i++ {
+				para = append(para, inline(strings.TrimSpace(lines[i])))
+			}
+			b.WriteString("<p>")
+			b.WriteString(strings.Join(para, "<br>\n"))
+			b.WriteString("</p>\n")
+		}
+	}
+	return template.HTML(b.String())
+}
+
+const (
+	unorderedItem = 1
+	orderedItem   = 2
+)
+
+var orderedItemRE = regexp.MustCompile(`^\s*\d+[.)]\s+`)
+
+// listItem returns the kind of list item line is or 0 if it is not one.
+func listItem(line string) int {
+	t := strings.TrimLeft(line, " \t")
+	switch {
+	case strings.HasPrefix(t, "- "), strings.HasPrefix(t, "* "), strings.HasPrefix(t, "+ "):
+		return unorderedItem
+	case orderedItemRE.MatchString(line):
+		return orderedItem
+	}
+	return 0
+}
+
+func itemText(line string) string {
+	if listItem(line) == orderedItem {
+		return strings.TrimSpace(orderedItemRE.ReplaceAllString(line, ""))
+	}
+	return strings.TrimSpace(strings.TrimLeft(line, " \t")[2:])
+}
+
+// inlineRE matches the inline elements. Only one of its groups is set for
+// each match and it tells which element was found.
+var inlineRE = regexp.MustCompile("" +
+	"`([^`]+)`" + // 1: code
+	`|\[([^\]]+)\]\(([^)\s]+)\)` + // 2, 3: link
+	`|\*\*([^*]+)\*\*` + // 4: strong
+	`|\*([^*\s][^*]*)\*` + // 5: emphasis
+	`|\bpost #(\d+)` + // 6: post reference
+	`|\btag:([^\s<>"'()\[\]]+)` + // 7: tag reference
+	`|(https?://[^\s<>"]+)`) // 8: bare URL
+
+// inline renders the inline elements of a single line.
+func inline(s string) string {
+	var b strings.Builder
+	for {
+		m := inlineRE.FindStringSubmatchIndex(s)
+		if m == nil {
+			b.WriteString(html.EscapeString(s))
+			return b.String()
+		}
+		b.WriteString(html.EscapeString(s[:m[0]]))
+		group := func(n int) string {
+			if m[2*n] < 0 {
+				return ""
+			}
+			return s[m[2*n]:m[2*n+1]]
+		}
+		end := m[1]
+		switch {
+		case m[2] >= 0:
+			b.WriteString("<code>" + html.EscapeString(group(1)) + "</code>")
+		case m[4] >= 0:
+			if u, ok := safeURL(group(3)); ok {
+				b.WriteString(link(u, html.EscapeString(group(2))))
+			} else {
+				b.WriteString(html.EscapeString(s[m[0]:m[1]]))
+			}
+		case m[8] >= 0:
+			b.WriteString("<strong>" + inline(group(4)) + "</strong>")
+		case m[10] >= 0:
+			b.WriteString("<em>" + inline(group(5)) + "</em>")
+		case m[12] >= 0:
+			b.WriteString(link("/post/view/"+group(6), html.EscapeString(s[m[0]:m[1]])))
+		case m[14] >= 0:
+			tag, rest := trimPunct(group(7))
+			if tag == "" {
+				b.WriteString(html.EscapeString(s[m[0]:m[1]]))
+				break
+			}
+			end -= len(rest)
+			b.WriteString(link("/post/list/"+url.PathEscape(tag)+"/1", html.EscapeString("tag:"+tag)))
+		case m[16] >= 0:
+			u, rest := trimPunct(group(8))
+			end -= len(rest)
+			b.WriteString(link(u, html.EscapeString(u)))
+		}
+		s = s[end:]
+	}
+}
+
+// trimPunct splits the punctuation that usually ends a sentence from the
+// end of s.
+func trimPunct(s string) (string, string) {
+	t := strings.TrimRight(s, ".,;:!?")
+	return t, s[len(t):]
+}
+
+func link(href, text string) string {
+	return `<a href="` + html.EscapeString(href) + `" rel="nofollow noopener">` + text + "</a>"
+}
+
+// safeURL reports whether raw is a relative URL or uses one of the allowed
+// schemes and returns it normalized.
+func safeURL(raw string) (string, bool) {
+	u, err := url.Parse(raw)
+	if err != nil {
+		return "", false
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "", "http", "https", "mailto":
+	default:
+		return "", false
+	}
+	return u.String(), true
+}
+
+// HTML returns the text of the suggestion rendered as Markdown.
+func (s *Suggestion) HTML() template.HTML {
+	return Markdown(s.Text)
+}
+
+// HTML returns the text of the reply rendered as Markdown.
+func (r Reply) HTML() template.HTML {
+	return Markdown(r.Text)
+}
+
+// HTML returns the text of the entry rendered as Markdown.
+func (e BoardEntry) HTML() template.HTML {
+	return Markdown(e.Text)
+}
@@ -0,0 +1,50 @@
+package teian
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestMarkdown(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"hello", "<p>hello</p>\n"},
+		{"one\ntwo\n\nthree", "<p>one<br>\ntwo</p>\n<p>three</p>\n"},
+		{"*a* **b** `<c>`", "<p><em>a</em> <strong>b</strong> <code>&lt;c&gt;</code></p>\n"},
+		{"- a\n- b\n1. c", "<ul>\n<li>a</li>\n<li>b</li>\n</ul>\n<ol>\n<li>c</li>\n</ol>\n"},
+		{"```\n<b>*x*</b>\n```", "<pre><code>&lt;b&gt;*x*&lt;/b&gt;\n</code></pre>\n"},
+		{"[site](https://kusubooru.com)", `<p><a href="https://kusubooru.com" rel="nofollow noopener">site</a></p>` + "\n"},
+		{"see post #123.", `<p>see <a href="/post/view/123" rel="nofollow noopener">post #123</a>.</p>` + "\n"},
+		{"tag:long_hair, please", `<p><a href="/post/list/long_hair/1" rel="nofollow noopener">tag:long_hair</a>, please</p>` + "\n"},
+		{"at https://a.com/x?y=1&z=2.", `<p>at <a href="https://a.com/x?y=1&amp;z=2" rel="nofollow noopener">https://a.com/x?y=1&amp;z=2</a>.</p>` + "\n"},
+		{"repost #1 and tag:...", "<p>repost #1 and tag:...</p>\n"},
+	}
+	for _, tt := range tests {
+		if got := string(Markdown(tt.in)); got != tt.want {
+			t.Errorf("Markdown(%q) = \n%q, want \n%q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestMarkdown_unsafe(t *testing.T) {
+	tests := []string{
+		`<script>alert(1)</script>`,
+		`[x](javascript:alert(1))`,
+		`[x](JavaScript:alert(1))`,
+		`[x](data:text/html,<script>)`,
+		`<img src=x onerror=alert(1)>`,
+		`[<b onclick="x">](/ok)`,
+		`**<i>**`,
+		`tag:"><script>`,
+	}
+	for _, in := range tests {
+		got := string(Markdown(in))
+		for _, bad := range []string{"<script", "<img", "<b ", "<i>", `href="javascript`, `href="JavaScript`, `href="data`, `"><`} {
+			if strings.Contains(got, bad) {
+				t.Errorf("Markdown(%q) = %q contains %q", in, got, bad)
+			}
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package memstore
+
+import (
+	"context"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+func (db *Memstore) Bulk(ctx context.Context, ids []uint64, op teian.BulkOp) (map[uint64]error, error) {
+	var apply func(id uint64) error
+	switch op.Action {
+	case teian.BulkDelete:
+		apply = func(id uint64) error {
+			return db.trashSuggestion("", id, op.By)
+		}
+	case teian.BulkStatus:
+		at := now()
+		apply = func(id uint64) error {
+			return db.updateSuggestion("", id, func(s *teian.Suggestion) bool {
+				return s.SetStatus(op.Status, op.By, at)
+			})
+		}
+	case teian.BulkCategory:
+		apply = func(id uint64) error {
+			return db.updateSuggestion("", id, func(s *teian.Suggestion) bool {
+				if s.Category == op.Category {
+					return false
+				}
+				s.Category = op.Category
+				return true
+			})
+		}
+	default:
+		return nil, teian.ErrBadBulkAction
+	}
+
+	if err := db.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer db.mu.Unlock()
+	if op.Action == teian.BulkCategory {
+		if err := db.checkCategory(op.Category); err != nil {
+			return nil, err
+		}
+	}
+	failed := make(map[uint64]error)
+	for _, id := range ids {
+		if err := apply(id); err != nil {
+			failed[id] = err
+		}
+	}
+	return failed, nil
+}
+
+func (db *Memstore) GetMany(ctx context.Context, ids []uint64) ([]teian.Suggestion, map[uint64]error, error) {
+	if err := db.rlock(ctx); err != nil {
+		return nil, nil, err
+	}
+	defer db.mu.RUnlock()
+	var suggs []teian.Suggestion
+	failed := make(map[uint64]error)
+	for _, id := range ids {
+		s, err := db.getSuggestion("", id)
+		if err != nil {
+			failed[id] = err
+			continue
+		}
+		suggs = append(suggs, *clone(s))
+	}
+	return suggs, failed, nil
+}
@@ -0,0 +1,85 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+func (db *Memstore) SetCategory(ctx context.Context, username string, id uint64, category string) error {
+	if err := db.lock(ctx); err != nil {
+		return err
+	}
+	defer db.mu.Unlock()
+	if err := db.checkCategory(category); err != nil {
+		return err
+	}
+	return db.updateSuggestion(username, id, func(s *teian.Suggestion) bool {
+		if s.Category == category {
+			return false
+		}
+		s.Category = category
+		return true
+	})
+}
+
+func (db *Memstore) Categories(ctx context.Context) ([]string, error) {
+	if err := db.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer db.mu.RUnlock()
+	var names []string
+	for name := range db.categories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (db *Memstore) AddCategory(ctx context.Context, name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" || strings.ContainsRune(name, 0) {
+		return teian.Errorf(teian.ErrInvalid, "invalid category name")
+	}
+	if err := db.lock(ctx); err != nil {
+		return err
+	}
+	defer db.mu.Unlock()
+	db.categories[name] = true
+	return nil
+}
+
+// RemoveCategory deletes the category name and leaves the suggestions that
+// were in it uncategorized.
+func (db *Memstore) RemoveCategory(ctx context.Context, name string) error {
+	if err := db.lock(ctx); err != nil {
+		return err
+	}
+	defer db.mu.Unlock()
+	if !db.categories[name] {
+		return teian.ErrNotFound
+	}
+	for _, s := range db.suggestions {
+		if s.Category == name {
+			s.Category = ""
+		}
+	}
+	delete(db.categories, name)
+	return nil
+}
+
+func (db *Memstore) CategoryCounts(ctx context.Context) (map[string]int, error) {
+	if err := db.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer db.mu.RUnlock()
+	counts := make(map[string]int)
+	for _, s := range db.suggestions {
+		if s.Category != "" {
+			counts[s.Category]++
+		}
+	}
+	return counts, nil
+}
@@ -0,0 +1,72 @@
+package memstore
+
+import (
+	"context"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+// Similar compares text with every suggestion that shares at least one
+// token with it, like Boltstore does through its search index.
+func (db *Memstore) Similar(ctx context.Context, text string, threshold float64) ([]teian.ScoredSuggestion, error) {
+	all, err := db.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tokens := teian.IndexText(text)
+	var suggs []teian.Suggestion
+	for _, s := range all {
+		for token := range teian.IndexText(s.Text) {
+			if tokens[token] != nil {
+				suggs = append(suggs, s)
+				break
+			}
+		}
+	}
+	return teian.Similar(suggs, text, threshold), nil
+}
+
+func (db *Memstore) Merge(ctx context.Context, into, from uint64, by string) error {
+	if into == from {
+		return teian.Errorf(teian.ErrInvalid, "cannot merge a suggestion into itself")
+	}
+	if err := db.lock(ctx); err != nil {
+		return err
+	}
+	defer db.mu.Unlock()
+	s, err := db.getSuggestion("", into)
+	if err != nil {
+		return err
+	}
+	d, err := db.getSuggestion("", from)
+	if err != nil {
+		return err
+	}
+	if s.MergedInto != 0 {
+		return teian.Errorf(teian.ErrConflict, "cannot merge into suggestion %d which is merged into %d", into, s.MergedInto)
+	}
+	if d.MergedInto != 0 {
+		return teian.Errorf(teian.ErrConflict, "suggestion %d is already merged into %d", from, d.MergedInto)
+	}
+	if len(d.Merged) != 0 {
+		return teian.Errorf(teian.ErrConflict, "cannot merge suggestion %d which has duplicates merged into it", from)
+	}
+
+	// Move the votes of the duplicate.
+	for username, votes := range db.votes {
+		vote, ok := votes[from]
+		if !ok {
+			continue
+		}
+		db.setVote(username, from, 0)
+		d.ApplyVote(vote, 0)
+		if _, ok := votes[into]; ok {
+			continue
+		}
+		db.setVote(username, into, vote)
+		s.ApplyVote(0, vote)
+	}
+
+	s.Merge(d, by, now())
+	return nil
+}
@@ -0,0 +1,73 @@
+package memstore
+
+import (
+	"context"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+func (db *Memstore) Import(ctx context.Context, suggs []teian.Suggestion, dryRun bool) ([]bool, error) {
+	if err := db.lock(ctx); err != nil {
+		return nil, err
+	}
+	defer db.mu.Unlock()
+
+	// The suggestions are only stored at the end so that an error or a
+	// dry run leaves the store unchanged.
+	added := make([]bool, len(suggs))
+	pending := make(map[uint64]*teian.Suggestion)
+
+	// Advance the sequence past the largest imported ID first so that the
+	// suggestions without one do not take an ID used further on.
+	seq := db.seq
+	for _, s := range suggs {
+		if s.ID > seq {
+			seq = s.ID
+		}
+	}
+	for i := range suggs {
+		s := &suggs[i]
+		if err := db.checkCategory(s.Category); err != nil {
+			return nil, err
+		}
+		if db.imported(pending, s) {
+			continue
+		}
+		if s.ID == 0 {
+			seq++
+			s.ID = seq
+		}
+		if s.Created.IsZero() {
+			s.Created = now()
+		}
+		pending[s.ID] = clone(s)
+		added[i] = true
+	}
+	if dryRun {
+		return added, nil
+	}
+	db.seq = seq
+	for id, s := range pending {
+		db.suggestions[id] = s
+		db.revisions[id] = []teian.Revision{{Text: s.Text, By: s.Username, At: s.Created}}
+	}
+	return added, nil
+}
+
+// imported reports whether s is already in the store or pending to be
+// added. Suggestions with an ID are looked up by it, including in the
+// trash, while suggestions without one are considered imported if the user
+// has one with the same text.
+func (db *Memstore) imported(pending map[uint64]*teian.Suggestion, s *teian.Suggestion) bool {
+	if s.ID != 0 {
+		return db.suggestions[s.ID] != nil || db.trash[s.ID] != nil || pending[s.ID] != nil
+	}
+	for _, m := range []map[uint64]*teian.Suggestion{db.suggestions, pending} {
+		for _, other := range m {
+			if other.Username == s.Username && other.Text == s.Text {
+				return true
+			}
+		}
+	}
+	return false
+}
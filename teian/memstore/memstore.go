@@ -0,0 +1,109 @@
+// Package memstore implements teian.SuggestionStore in memory. It is meant
+// for tests and local development; everything is lost when the program
+// exits. It behaves like boltstore, including the ID sequence and the
+// upload quota accounting.
+package memstore
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+// Memstore holds the suggestions in maps guarded by a mutex. Suggestions are
+// copied in and out so that callers never share them with the store.
+type Memstore struct {
+	mu sync.RWMutex
+	// seq is the last ID given to a suggestion. IDs are never reused.
+	seq         uint64
+	suggestions map[uint64]*teian.Suggestion
+	trash       map[uint64]*teian.Suggestion
+	// votes holds the vote of each user keyed by suggestion ID.
+	votes      map[string]map[uint64]int
+	categories map[string]bool
+	revisions  map[uint64][]teian.Revision
+	uploads    map[string]teian.Uploads
+	overrides  map[string]teian.Quota
+}
+
+// NewSuggestionStore returns an empty in-memory implementation of
+// teian.SuggestionStore.
+func NewSuggestionStore() *Memstore {
+	return &Memstore{
+		suggestions: make(map[uint64]*teian.Suggestion),
+		trash:       make(map[uint64]*teian.Suggestion),
+		votes:       make(map[string]map[uint64]int),
+		categories:  make(map[string]bool),
+		revisions:   make(map[uint64][]teian.Revision),
+		uploads:     make(map[string]teian.Uploads),
+		overrides:   make(map[string]teian.Quota),
+	}
+}
+
+// Close does nothing. It exists so that Memstore can be used wherever the
+// other stores are.
+func (db *Memstore) Close() {}
+
+// lock locks the store for writing unless ctx is already done.
+func (db *Memstore) lock(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	db.mu.Lock()
+	return nil
+}
+
+// rlock locks the store for reading unless ctx is already done.
+func (db *Memstore) rlock(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	db.mu.RLock()
+	return nil
+}
+
+// now returns the current time without the monotonic clock reading which
+// the other stores do not keep either.
+func now() time.Time {
+	return time.Now().Round(0)
+}
+
+// clone returns a deep copy of s. Empty lists become nil like they do when
+// Boltstore decodes a suggestion.
+func clone(s *teian.Suggestion) *teian.Suggestion {
+	c := *s
+	c.History = nil
+	if len(s.History) != 0 {
+		c.History = append([]teian.StatusChange(nil), s.History...)
+	}
+	c.Replies = nil
+	if len(s.Replies) != 0 {
+		c.Replies = append([]teian.Reply(nil), s.Replies...)
+	}
+	c.Tags = nil
+	if len(s.Tags) != 0 {
+		c.Tags = append([]string(nil), s.Tags...)
+	}
+	c.Merged = nil
+	if len(s.Merged) != 0 {
+		c.Merged = append([]uint64(nil), s.Merged...)
+	}
+	c.Attachments = nil
+	if len(s.Attachments) != 0 {
+		c.Attachments = append([]teian.Attachment(nil), s.Attachments...)
+	}
+	return &c
+}
+
+// sorted returns copies of the suggestions of m in ID order.
+func sorted(m map[uint64]*teian.Suggestion) []teian.Suggestion {
+	var suggs []teian.Suggestion
+	for _, s := range m {
+		suggs = append(suggs, *clone(s))
+	}
+	sort.Sort(teian.ByID(suggs))
+	return suggs
+}
@@ -0,0 +1,14 @@
+package memstore
+
+import (
+	"testing"
+
+	"github.com/kusubooru/teian/internal/storetest"
+)
+
+func TestConformance(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) (storetest.Store, func()) {
+		store := NewSuggestionStore()
+		return store, store.Close
+	})
+}
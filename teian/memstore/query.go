@@ -0,0 +1,147 @@
+package memstore
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"sort"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+var errBadCursor = teian.Errorf(teian.ErrInvalid, "invalid cursor")
+
+// A key is the position of a suggestion in the order of a query: the ID
+// preceded by the username or the net votes when ordering by those. A
+// cursor is the base64 encoded JSON of the key of the first suggestion of a
+// page.
+type key struct {
+	Username string `json:"u,omitempty"`
+	Votes    int    `json:"v,omitempty"`
+	ID       uint64 `json:"i"`
+}
+
+func encodeCursor(k key) string {
+	data, _ := json.Marshal(k)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(c string) (*key, error) {
+	data, err := base64.RawURLEncoding.DecodeString(c)
+	if err != nil {
+		return nil, errBadCursor
+	}
+	k := new(key)
+	if err := json.Unmarshal(data, k); err != nil || k.ID == 0 {
+		return nil, errBadCursor
+	}
+	return k, nil
+}
+
+// queryKey returns the function that gives the key of a suggestion in the
+// order o, the comparison of keys in ascending order and whether o is
+// descending.
+func queryKey(o teian.Order) (keyOf func(s *teian.Suggestion) key, less func(a, b key) bool, desc bool) {
+	switch o {
+	case teian.OrderUserAsc, teian.OrderUserDesc:
+		return func(s *teian.Suggestion) key { return key{Username: s.Username, ID: s.ID} },
+			func(a, b key) bool { return a.Username < b.Username || a.Username == b.Username && a.ID < b.ID },
+			o == teian.OrderUserDesc
+	case teian.OrderVotesAsc, teian.OrderVotesDesc:
+		return func(s *teian.Suggestion) key { return key{Votes: s.Votes(), ID: s.ID} },
+			func(a, b key) bool { return a.Votes < b.Votes || a.Votes == b.Votes && a.ID < b.ID },
+			o == teian.OrderVotesDesc
+	default:
+		return func(s *teian.Suggestion) key { return key{ID: s.ID} },
+			func(a, b key) bool { return a.ID < b.ID },
+			o != teian.OrderDateAsc
+	}
+}
+
+func (db *Memstore) Query(ctx context.Context, q teian.Query) (*teian.Page, error) {
+	var start *key
+	if q.Cursor != "" {
+		var err error
+		if start, err = decodeCursor(q.Cursor); err != nil {
+			return nil, err
+		}
+	}
+
+	// Sort the suggestions in the order of the query.
+	suggs, err := db.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	keyOf, less, desc := queryKey(q.Order)
+	keys := make([]key, len(suggs))
+	for i := range suggs {
+		keys[i] = keyOf(&suggs[i])
+	}
+	before := func(a, b key) bool {
+		if desc {
+			return less(b, a)
+		}
+		return less(a, b)
+	}
+	sort.Sort(byKey{suggs, keys, before})
+
+	// The page starts at the cursor or, if it is gone, at the suggestion
+	// that would follow it.
+	first := 0
+	if start != nil {
+		first = sort.Search(len(keys), func(i int) bool { return !before(keys[i], *start) })
+	}
+
+	// Collect the page and find the first match after it.
+	page := &teian.Page{}
+	for i := first; i < len(suggs); i++ {
+		if !q.Match(&suggs[i]) {
+			continue
+		}
+		if q.Limit > 0 && len(page.Suggestions) == q.Limit {
+			page.Next = encodeCursor(keys[i])
+			break
+		}
+		page.Suggestions = append(page.Suggestions, suggs[i])
+	}
+	if start == nil || q.Limit <= 0 {
+		return page, nil
+	}
+
+	// Walk back from the start of this page to find where the previous
+	// one starts.
+	n := 0
+	for i := first - 1; i >= 0 && n < q.Limit; i-- {
+		if q.Match(&suggs[i]) {
+			page.Prev = encodeCursor(keys[i])
+			n++
+		}
+	}
+	return page, nil
+}
+
+// byKey sorts suggestions along with their keys.
+type byKey struct {
+	suggs  []teian.Suggestion
+	keys   []key
+	before func(a, b key) bool
+}
+
+func (s byKey) Len() int           { return len(s.suggs) }
+func (s byKey) Less(i, j int) bool { return s.before(s.keys[i], s.keys[j]) }
+func (s byKey) Swap(i, j int) {
+	s.suggs[i], s.suggs[j] = s.suggs[j], s.suggs[i]
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+}
+
+// Search ranks every suggestion with teian.Search.
+func (db *Memstore) Search(ctx context.Context, query string) ([]teian.Suggestion, error) {
+	if len(teian.ParseSearch(query).Include) == 0 {
+		return nil, nil
+	}
+	all, err := db.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return teian.Search(all, query), nil
+}
@@ -0,0 +1,111 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+// CheckQuota adds an upload of n to those of username within
+// teian.QuotaWindow and returns how much of limit remains. If the user would
+// go over limit nothing is added and teian.ErrOverQuota is returned.
+func (db *Memstore) CheckQuota(ctx context.Context, username string, n, limit teian.Quota) (teian.Quota, error) {
+	if err := db.lock(ctx); err != nil {
+		return 0, err
+	}
+	defer db.mu.Unlock()
+	uploads, remain, err := db.uploads[username].Add(now(), n, limit)
+	if err != nil {
+		return 0, err
+	}
+	if len(uploads) == 0 {
+		delete(db.uploads, username)
+	} else {
+		db.uploads[username] = uploads
+	}
+	return remain, nil
+}
+
+// RefundQuota removes the most recent upload of n of username.
+func (db *Memstore) RefundQuota(ctx context.Context, username string, n teian.Quota) error {
+	if err := db.lock(ctx); err != nil {
+		return err
+	}
+	defer db.mu.Unlock()
+	uploads := db.uploads[username].Refund(n)
+	if len(uploads) == 0 {
+		delete(db.uploads, username)
+	} else {
+		db.uploads[username] = uploads
+	}
+	return nil
+}
+
+// QuotaOverride returns the upload quota an admin set for username.
+func (db *Memstore) QuotaOverride(ctx context.Context, username string) (teian.Quota, error) {
+	if err := db.rlock(ctx); err != nil {
+		return 0, err
+	}
+	defer db.mu.RUnlock()
+	limit, ok := db.overrides[username]
+	if !ok {
+		return 0, teian.ErrNotFound
+	}
+	return limit, nil
+}
+
+// SetQuotaOverride sets the upload quota of username regardless of their
+// class.
+func (db *Memstore) SetQuotaOverride(ctx context.Context, username string, limit teian.Quota) error {
+	if username == "" || limit < 0 {
+		return teian.Errorf(teian.ErrInvalid, "invalid quota %d for user %q", limit, username)
+	}
+	if err := db.lock(ctx); err != nil {
+		return err
+	}
+	defer db.mu.Unlock()
+	db.overrides[username] = limit
+	return nil
+}
+
+// RemoveQuotaOverride makes username go back to the upload quota of their
+// class.
+func (db *Memstore) RemoveQuotaOverride(ctx context.Context, username string) error {
+	if err := db.lock(ctx); err != nil {
+		return err
+	}
+	defer db.mu.Unlock()
+	if _, ok := db.overrides[username]; !ok {
+		return teian.ErrNotFound
+	}
+	delete(db.overrides, username)
+	return nil
+}
+
+// QuotaUsage returns every user that has used some of their upload quota or
+// has an override, ordered by username.
+func (db *Memstore) QuotaUsage(ctx context.Context) ([]teian.QuotaUsage, error) {
+	if err := db.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer db.mu.RUnlock()
+	t := now()
+	var usage []teian.QuotaUsage
+	used := make(map[string]bool)
+	for username, uploads := range db.uploads {
+		if uploads = uploads.Expire(t); len(uploads) == 0 {
+			continue
+		}
+		used[username] = true
+		limit, ok := db.overrides[username]
+		usage = append(usage, teian.QuotaUsage{Username: username, Used: uploads.Used(), Limit: limit, Override: ok})
+	}
+	for username, limit := range db.overrides {
+		if !used[username] {
+			usage = append(usage, teian.QuotaUsage{Username: username, Limit: limit, Override: true})
+		}
+	}
+	sort.Slice(usage, func(i, j int) bool { return usage[i].Username < usage[j].Username })
+	return usage, nil
+}
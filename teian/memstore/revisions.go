@@ -0,0 +1,53 @@
+package memstore
+
+import (
+	"context"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+// setText changes the text of the suggestion with id of username and
+// records the new text as a revision by the given author. An empty username
+// matches any suggestion. The caller must hold the lock.
+func (db *Memstore) setText(username string, id uint64, text, by string) error {
+	changed := false
+	err := db.updateSuggestion(username, id, func(s *teian.Suggestion) bool {
+		if s.Text == text {
+			return false
+		}
+		s.Text = text
+		changed = true
+		return true
+	})
+	if err != nil || !changed {
+		return err
+	}
+	db.revisions[id] = append(db.revisions[id], teian.Revision{Text: text, By: by, At: now()})
+	return nil
+}
+
+func (db *Memstore) Revisions(ctx context.Context, id uint64) ([]teian.Revision, error) {
+	if err := db.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer db.mu.RUnlock()
+	if _, err := db.getSuggestion("", id); err != nil {
+		return nil, err
+	}
+	return append([]teian.Revision(nil), db.revisions[id]...), nil
+}
+
+func (db *Memstore) Revert(ctx context.Context, id uint64, revision int, by string) error {
+	if err := db.lock(ctx); err != nil {
+		return err
+	}
+	defer db.mu.Unlock()
+	if _, err := db.getSuggestion("", id); err != nil {
+		return err
+	}
+	revs := db.revisions[id]
+	if revision < 0 || revision >= len(revs) {
+		return teian.ErrNotFound
+	}
+	return db.setText("", id, revs[revision].Text, by)
+}
@@ -0,0 +1,125 @@
+package memstore
+
+import (
+	"context"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+// getSuggestion returns the stored suggestion with id. If username is not
+// empty then the suggestion must also belong to username. The caller must
+// hold the lock.
+func (db *Memstore) getSuggestion(username string, id uint64) (*teian.Suggestion, error) {
+	s, ok := db.suggestions[id]
+	if !ok || username != "" && s.Username != username {
+		return nil, teian.ErrNotFound
+	}
+	return s, nil
+}
+
+// checkCategory returns teian.ErrUnknownCategory if category is not empty
+// and has not been defined. The caller must hold the lock.
+func (db *Memstore) checkCategory(category string) error {
+	if category != "" && !db.categories[category] {
+		return teian.ErrUnknownCategory
+	}
+	return nil
+}
+
+func (db *Memstore) Create(ctx context.Context, username string, sugg *teian.Suggestion) error {
+	if err := db.lock(ctx); err != nil {
+		return err
+	}
+	defer db.mu.Unlock()
+	if err := db.checkCategory(sugg.Category); err != nil {
+		return err
+	}
+	db.seq++
+	sugg.ID = db.seq
+	sugg.Username = username
+	sugg.Created = now()
+	db.suggestions[sugg.ID] = clone(sugg)
+	db.revisions[sugg.ID] = []teian.Revision{{Text: sugg.Text, By: username, At: sugg.Created}}
+	return nil
+}
+
+func (db *Memstore) Get(ctx context.Context, id uint64) (*teian.Suggestion, error) {
+	if err := db.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer db.mu.RUnlock()
+	s, err := db.getSuggestion("", id)
+	if err != nil {
+		return nil, err
+	}
+	return clone(s), nil
+}
+
+func (db *Memstore) OfUser(ctx context.Context, username string) ([]teian.Suggestion, error) {
+	if err := db.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer db.mu.RUnlock()
+	var suggs []teian.Suggestion
+	for _, s := range sorted(db.suggestions) {
+		if s.Username == username {
+			suggs = append(suggs, s)
+		}
+	}
+	return suggs, nil
+}
+
+func (db *Memstore) All(ctx context.Context) ([]teian.Suggestion, error) {
+	if err := db.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer db.mu.RUnlock()
+	return sorted(db.suggestions), nil
+}
+
+func (db *Memstore) SetStatus(ctx context.Context, username string, id uint64, status teian.Status, by string) error {
+	return db.update(ctx, username, id, func(s *teian.Suggestion) bool {
+		return s.SetStatus(status, by, now())
+	})
+}
+
+func (db *Memstore) Edit(ctx context.Context, username string, id uint64, text string) error {
+	if err := db.lock(ctx); err != nil {
+		return err
+	}
+	defer db.mu.Unlock()
+	return db.setText(username, id, text, username)
+}
+
+func (db *Memstore) AddReply(ctx context.Context, username string, id uint64, reply *teian.Reply) error {
+	return db.update(ctx, username, id, func(s *teian.Suggestion) bool {
+		reply.Created = now()
+		s.Replies = append(s.Replies, *reply)
+		return true
+	})
+}
+
+// update runs updateSuggestion holding the lock.
+func (db *Memstore) update(ctx context.Context, username string, id uint64, fn func(*teian.Suggestion) bool) error {
+	if err := db.lock(ctx); err != nil {
+		return err
+	}
+	defer db.mu.Unlock()
+	return db.updateSuggestion(username, id, fn)
+}
+
+// updateSuggestion finds the suggestion with id of username and calls fn
+// with a copy of it. The copy replaces the suggestion only if fn returns
+// true. The caller must hold the lock.
+func (db *Memstore) updateSuggestion(username string, id uint64, fn func(*teian.Suggestion) bool) error {
+	s, err := db.getSuggestion(username, id)
+	if err != nil {
+		return err
+	}
+	c := clone(s)
+	if !fn(c) {
+		return nil
+	}
+	db.suggestions[id] = clone(c)
+	return nil
+}
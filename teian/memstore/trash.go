@@ -0,0 +1,90 @@
+package memstore
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+// Deleted suggestions are moved to the trash map. Their revisions and votes
+// are kept until they are purged.
+
+func (db *Memstore) Delete(ctx context.Context, username string, id uint64, by string) error {
+	if err := db.lock(ctx); err != nil {
+		return err
+	}
+	defer db.mu.Unlock()
+	return db.trashSuggestion(username, id, by)
+}
+
+// trashSuggestion moves the suggestion with id of username to the trash. An
+// empty username matches any suggestion. The caller must hold the lock.
+func (db *Memstore) trashSuggestion(username string, id uint64, by string) error {
+	s, err := db.getSuggestion(username, id)
+	if err != nil {
+		return err
+	}
+	delete(db.suggestions, id)
+	s.DeletedBy = by
+	s.DeletedAt = now()
+	db.trash[id] = s
+	return nil
+}
+
+func (db *Memstore) Trash(ctx context.Context) ([]teian.Suggestion, error) {
+	if err := db.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer db.mu.RUnlock()
+	suggs := sorted(db.trash)
+	sort.SliceStable(suggs, func(i, j int) bool {
+		return suggs[i].DeletedAt.After(suggs[j].DeletedAt)
+	})
+	return suggs, nil
+}
+
+func (db *Memstore) Trashed(ctx context.Context, id uint64) (*teian.Suggestion, error) {
+	if err := db.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer db.mu.RUnlock()
+	s, ok := db.trash[id]
+	if !ok {
+		return nil, teian.ErrNotFound
+	}
+	return clone(s), nil
+}
+
+func (db *Memstore) Restore(ctx context.Context, id uint64) error {
+	if err := db.lock(ctx); err != nil {
+		return err
+	}
+	defer db.mu.Unlock()
+	s, ok := db.trash[id]
+	if !ok {
+		return teian.ErrNotFound
+	}
+	delete(db.trash, id)
+	s.DeletedBy = ""
+	s.DeletedAt = time.Time{}
+	db.suggestions[id] = s
+	return nil
+}
+
+func (db *Memstore) Purge(ctx context.Context, id uint64) error {
+	if err := db.lock(ctx); err != nil {
+		return err
+	}
+	defer db.mu.Unlock()
+	if _, ok := db.trash[id]; !ok {
+		return teian.ErrNotFound
+	}
+	delete(db.revisions, id)
+	for username := range db.votes {
+		db.setVote(username, id, 0)
+	}
+	delete(db.trash, id)
+	return nil
+}
@@ -0,0 +1,59 @@
+package memstore
+
+import (
+	"context"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+// setVote records the vote of username on suggestion id. A vote of 0
+// removes it. The caller must hold the lock.
+func (db *Memstore) setVote(username string, id uint64, vote int) {
+	if vote == 0 {
+		delete(db.votes[username], id)
+		if len(db.votes[username]) == 0 {
+			delete(db.votes, username)
+		}
+		return
+	}
+	if db.votes[username] == nil {
+		db.votes[username] = make(map[uint64]int)
+	}
+	db.votes[username][id] = vote
+}
+
+func (db *Memstore) Vote(ctx context.Context, id uint64, username string, vote int) error {
+	if vote < -1 || vote > 1 {
+		return teian.ErrBadVote
+	}
+	if err := db.lock(ctx); err != nil {
+		return err
+	}
+	defer db.mu.Unlock()
+	s, err := db.getSuggestion("", id)
+	if err != nil {
+		return err
+	}
+	if err := s.CanVote(username); err != nil {
+		return err
+	}
+	old := db.votes[username][id]
+	if old == vote {
+		return nil
+	}
+	s.ApplyVote(old, vote)
+	db.setVote(username, id, vote)
+	return nil
+}
+
+func (db *Memstore) VotesOf(ctx context.Context, username string) (map[uint64]int, error) {
+	if err := db.rlock(ctx); err != nil {
+		return nil, err
+	}
+	defer db.mu.RUnlock()
+	votes := make(map[uint64]int)
+	for id, vote := range db.votes[username] {
+		votes[id] = vote
+	}
+	return votes, nil
+}
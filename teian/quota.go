@@ -0,0 +1,130 @@
+package teian
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MB is the unit in which upload quotas are configured and shown.
+const MB Quota = 1 << 20
+
+// QuotaWindow is how long an upload counts against the quota of its user.
+// The window slides so a user gets back the quota of each upload once it
+// is QuotaWindow old rather than all of it at a fixed time of day.
+const QuotaWindow = 24 * time.Hour
+
+// Upload is the size and time of an upload counted against a quota.
+type Upload struct {
+	At   time.Time
+	Size Quota
+}
+
+// Uploads are the uploads of a user, oldest first.
+type Uploads []Upload
+
+// Expire returns the uploads that still count at now, those made within
+// QuotaWindow before it.
+func (u Uploads) Expire(now time.Time) Uploads {
+	cutoff := now.Add(-QuotaWindow)
+	i := 0
+	for i < len(u) && !u[i].At.After(cutoff) {
+		i++
+	}
+	return u[i:]
+}
+
+// Used returns the total size of the uploads.
+func (u Uploads) Used() Quota {
+	var used Quota
+	for _, up := range u {
+		used += up.Size
+	}
+	return used
+}
+
+// Add expires the old uploads and records an upload of n at now. It
+// returns the new uploads and how much of limit remains after it. If the
+// upload would go over limit it is not recorded and ErrOverQuota is
+// returned. Uploads of zero bytes are not recorded either so they can be
+// used to ask how much remains.
+func (u Uploads) Add(now time.Time, n, limit Quota) (Uploads, Quota, error) {
+	u = u.Expire(now)
+	used := u.Used() + n
+	if used > limit {
+		return u, 0, ErrOverQuota
+	}
+	if n != 0 {
+		u = append(u[:len(u):len(u)], Upload{At: now, Size: n})
+	}
+	return u, limit - used, nil
+}
+
+// Refund removes the most recent upload of n, giving back the quota it used.
+// It is meant to undo an upload that was counted but then not kept.
+func (u Uploads) Refund(n Quota) Uploads {
+	for i := len(u) - 1; i >= 0; i-- {
+		if u[i].Size == n {
+			return append(u[:i:i], u[i+1:]...)
+		}
+	}
+	return u
+}
+
+// ClassQuotas holds the upload quota of each shimmie user class such as
+// admin, user or ghost.
+type ClassQuotas map[string]Quota
+
+// Limit returns the upload quota of class. Classes without a quota cannot
+// upload anything.
+func (q ClassQuotas) Limit(class string) Quota {
+	return q[class]
+}
+
+// String formats the quotas the way ParseClassQuotas reads them, ordered by
+// class.
+func (q ClassQuotas) String() string {
+	classes := make([]string, 0, len(q))
+	for class := range q {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	for i, class := range classes {
+		classes[i] = fmt.Sprintf("%s=%d", class, q[class]/MB)
+	}
+	return strings.Join(classes, ",")
+}
+
+// ParseClassQuotas parses a comma separated list of class=MB pairs such as
+// "admin=1000,user=200,ghost=0".
+func ParseClassQuotas(s string) (ClassQuotas, error) {
+	q := make(ClassQuotas)
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		i := strings.Index(pair, "=")
+		if i <= 0 {
+			return nil, fmt.Errorf("bad class quota %q, want class=MB", pair)
+		}
+		mb, err := strconv.ParseInt(pair[i+1:], 10, 64)
+		if err != nil || mb < 0 {
+			return nil, fmt.Errorf("bad quota for class %q: %q", pair[:i], pair[i+1:])
+		}
+		q[pair[:i]] = Quota(mb) * MB
+	}
+	return q, nil
+}
+
+// QuotaUsage is how much of their upload quota a user has used. Limit is
+// only set when Override is true; otherwise the user has the quota of their
+// class.
+type QuotaUsage struct {
+	Username string
+	Used     Quota
+	Limit    Quota
+	Override bool
+}
@@ -0,0 +1,101 @@
+package teian
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseClassQuotas(t *testing.T) {
+	q, err := ParseClassQuotas("admin=1000, user=200,ghost=0")
+	if err != nil {
+		t.Fatal("ParseClassQuotas failed:", err)
+	}
+	want := ClassQuotas{"admin": 1000 * MB, "user": 200 * MB, "ghost": 0}
+	if !reflect.DeepEqual(q, want) {
+		t.Errorf("ParseClassQuotas = %v, want %v", q, want)
+	}
+	if got, want := q.Limit("user"), 200*MB; got != want {
+		t.Errorf("Limit(user) = %d, want %d", got, want)
+	}
+	if got := q.Limit("anonymous"); got != 0 {
+		t.Errorf("Limit of unknown class = %d, want 0", got)
+	}
+	if got, want := q.String(), "admin=1000,ghost=0,user=200"; got != want {
+		t.Errorf("String = %q, want %q", got, want)
+	}
+
+	for _, s := range []string{"admin", "=10", "user=-1", "user=lots"} {
+		if _, err := ParseClassQuotas(s); err == nil {
+			t.Errorf("ParseClassQuotas(%q) should fail", s)
+		}
+	}
+}
+
+func TestUploads_Add(t *testing.T) {
+	start := time.Date(2016, 1, 1, 11, 59, 0, 0, time.UTC)
+	var u Uploads
+	steps := []struct {
+		at     time.Time
+		n      Quota
+		remain Quota
+		err    error
+		len    int
+	}{
+		{start, 150 * MB, 50 * MB, nil, 1},
+		// A minute later is still within the window of the first upload.
+		{start.Add(time.Minute), 100 * MB, 0, ErrOverQuota, 1},
+		{start.Add(6 * time.Hour), 50 * MB, 0, nil, 2},
+		{start.Add(12 * time.Hour), 0, 0, nil, 2},
+		// The first upload expires exactly QuotaWindow after it was made.
+		{start.Add(QuotaWindow), 0, 150 * MB, nil, 1},
+		{start.Add(QuotaWindow + time.Hour), 100 * MB, 50 * MB, nil, 2},
+		{start.Add(2*QuotaWindow + 6*time.Hour), 0, 200 * MB, nil, 0},
+	}
+	for i, st := range steps {
+		var remain Quota
+		var err error
+		u, remain, err = u.Add(st.at, st.n, 200*MB)
+		if err != st.err {
+			t.Fatalf("step %d: Add returned error %v, want %v", i, err, st.err)
+		}
+		if remain != st.remain || len(u) != st.len {
+			t.Errorf("step %d: Add = %d remaining with %d uploads, want %d with %d", i, remain, len(u), st.remain, st.len)
+		}
+	}
+}
+
+func TestUploads_Expire(t *testing.T) {
+	now := time.Now()
+	u := Uploads{
+		{At: now.Add(-QuotaWindow - time.Second), Size: 1},
+		{At: now.Add(-time.Hour), Size: 2},
+		{At: now, Size: 4},
+	}
+	if got, want := u.Expire(now).Used(), Quota(6); got != want {
+		t.Errorf("Expire(now).Used() = %d, want %d", got, want)
+	}
+	if got := u.Expire(now.Add(QuotaWindow)); len(got) != 0 {
+		t.Errorf("Expire after the window = %v, want no uploads", got)
+	}
+}
+
+func TestUploads_Refund(t *testing.T) {
+	start := time.Date(2016, 1, 1, 11, 59, 0, 0, time.UTC)
+	u := Uploads{
+		{At: start, Size: 2},
+		{At: start.Add(time.Hour), Size: 1},
+		{At: start.Add(2 * time.Hour), Size: 2},
+	}
+	got := u.Refund(2)
+	want := Uploads{{At: start, Size: 2}, {At: start.Add(time.Hour), Size: 1}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Refund(2) = %v, want %v", got, want)
+	}
+	if len(u) != 3 || u[2].Size != 2 {
+		t.Errorf("Refund changed the original uploads to %v", u)
+	}
+	if got := u.Refund(5); !reflect.DeepEqual(got, u) {
+		t.Errorf("Refund of a missing size = %v, want %v", got, u)
+	}
+}
@@ -0,0 +1,135 @@
+package teian
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Revision is a version of the text of a suggestion along with who wrote it
+// and when. The first revision is the text the suggestion was created with.
+type Revision struct {
+	Text string
+	By   string
+	At   time.Time
+}
+
+// FmtAt returns the time of the revision formatted like
+// Suggestion.FmtCreated.
+func (r Revision) FmtAt() string {
+	return r.At.UTC().Format("Mon 02 Jan 2006 15:04:05 MST")
+}
+
+// DiffOp tells whether a part of a diff is in both texts, was deleted from
+// the old one or inserted in the new one.
+type DiffOp int
+
+const (
+	DiffEqual DiffOp = iota
+	DiffDelete
+	DiffInsert
+)
+
+// DiffPart is a run of text of a diff.
+type DiffPart struct {
+	Op   DiffOp
+	Text string
+}
+
+// Equal, Delete and Insert report the kind of the part for templates.
+func (p DiffPart) Equal() bool  { return p.Op == DiffEqual }
+func (p DiffPart) Delete() bool { return p.Op == DiffDelete }
+func (p DiffPart) Insert() bool { return p.Op == DiffInsert }
+
+// maxDiffCells limits the size of the table used to find the longest common
+// subsequence. Texts that would need more are shown as entirely replaced.
+const maxDiffCells = 4 << 20
+
+var wordRE = regexp.MustCompile(`\s+|[^\s]+`)
+
+// DiffWords returns the differences between old and new word by word.
+// Whitespace is kept so that joining the parts gives back the texts.
+func DiffWords(old, new string) []DiffPart {
+	return diff(wordRE.FindAllString(old, -1), wordRE.FindAllString(new, -1))
+}
+
+// DiffLines returns the differences between old and new line by line.
+func DiffLines(old, new string) []DiffPart {
+	return diff(splitLines(old), splitLines(new))
+}
+
+// splitLines splits s after each newline.
+func splitLines(s string) []string {
+	lines := strings.SplitAfter(s, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	return lines
+}
+
+// diff finds the longest common subsequence of the tokens of a and b and
+// returns the tokens that are not part of it as deletions and insertions.
+// Consecutive tokens of the same kind are joined.
+func diff(a, b []string) []DiffPart {
+	// Skip the common prefix and suffix which is usually most of the text.
+	pre := 0
+	for pre < len(a) && pre < len(b) && a[pre] == b[pre] {
+		pre++
+	}
+	suf := 0
+	for suf < len(a)-pre && suf < len(b)-pre && a[len(a)-1-suf] == b[len(b)-1-suf] {
+		suf++
+	}
+	var parts []DiffPart
+	add := func(op DiffOp, tokens ...string) {
+		for _, t := range tokens {
+			if n := len(parts); n != 0 && parts[n-1].Op == op {
+				parts[n-1].Text += t
+			} else {
+				parts = append(parts, DiffPart{op, t})
+			}
+		}
+	}
+	add(DiffEqual, a[:pre]...)
+	ma, mb := a[pre:len(a)-suf], b[pre:len(b)-suf]
+	if (len(ma)+1)*(len(mb)+1) > maxDiffCells {
+		add(DiffDelete, ma...)
+		add(DiffInsert, mb...)
+	} else {
+		// lcs[i][j] is the length of the LCS of ma[i:] and mb[j:].
+		lcs := make([][]int, len(ma)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(mb)+1)
+		}
+		for i := len(ma) - 1; i >= 0; i-- {
+			for j := len(mb) - 1; j >= 0; j-- {
+				if ma[i] == mb[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else if lcs[i+1][j] >= lcs[i][j+1] {
+					lcs[i][j] = lcs[i+1][j]
+				} else {
+					lcs[i][j] = lcs[i][j+1]
+				}
+			}
+		}
+		i, j := 0, 0
+		for i < len(ma) && j < len(mb) {
+			switch {
+			case ma[i] == mb[j]:
+				add(DiffEqual, ma[i])
+				i++
+				j++
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				add(DiffDelete, ma[i])
+				i++
+			default:
+				add(DiffInsert, mb[j])
+				j++
+			}
+		}
+		add(DiffDelete, ma[i:]...)
+		add(DiffInsert, mb[j:]...)
+	}
+	add(DiffEqual, a[len(a)-suf:]...)
+	return parts
+}
@@ -0,0 +1,53 @@
+package teian
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDiffWords(t *testing.T) {
+	tests := []struct {
+		old, new string
+		want     []DiffPart
+	}{
+		{"", "", nil},
+		{"same text", "same text", []DiffPart{{DiffEqual, "same text"}}},
+		{"add more tags", "add many more tags", []DiffPart{{DiffEqual, "add "}, {DiffInsert, "many "}, {DiffEqual, "more tags"}}},
+		{"a quick fox", "a slow fox", []DiffPart{{DiffEqual, "a "}, {DiffDelete, "quick"}, {DiffInsert, "slow"}, {DiffEqual, " fox"}}},
+		{"", "new", []DiffPart{{DiffInsert, "new"}}},
+	}
+	for _, tt := range tests {
+		if got := DiffWords(tt.old, tt.new); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("DiffWords(%q, %q) = %v, want %v", tt.old, tt.new, got, tt.want)
+		}
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	old := "one\ntwo\nthree\n"
+	new := "one\n2\nthree\nfour\n"
+	want := []DiffPart{{DiffEqual, "one\n"}, {DiffDelete, "two\n"}, {DiffInsert, "2\n"}, {DiffEqual, "three\n"}, {DiffInsert, "four\n"}}
+	if got := DiffLines(old, new); !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffLines = %v, want %v", got, want)
+	}
+}
+
+// TestDiff_joins checks that the old and new texts can be rebuilt from the
+// parts of a diff.
+func TestDiff_joins(t *testing.T) {
+	old := "the tag list should show counts and the uploads should be faster"
+	new := "tag lists should show post counts while uploads should be much faster"
+	var a, b strings.Builder
+	for _, p := range DiffWords(old, new) {
+		if p.Op != DiffInsert {
+			a.WriteString(p.Text)
+		}
+		if p.Op != DiffDelete {
+			b.WriteString(p.Text)
+		}
+	}
+	if a.String() != old || b.String() != new {
+		t.Errorf("diff rebuilds %q and %q, want %q and %q", a.String(), b.String(), old, new)
+	}
+}
@@ -0,0 +1,326 @@
+package teian
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/cases"
+	"golang.org/x/text/runes"
+	"golang.org/x/text/transform"
+	"golang.org/x/text/unicode/norm"
+)
+
+// diacritics are the combining marks removed when folding. Other marks,
+// such as the kana voicing marks, change the meaning of words and are kept.
+var diacritics = &unicode.RangeTable{
+	R16: []unicode.Range16{{Lo: 0x0300, Hi: 0x036f, Stride: 1}},
+}
+
+// fold normalizes text so that searching ignores case, accents and
+// compatibility differences such as full-width letters.
+func fold(text string) string {
+	t := transform.Chain(norm.NFKD, runes.Remove(runes.In(diacritics)), cases.Fold(), norm.NFC)
+	s, _, err := transform.String(t, text)
+	if err != nil {
+		return strings.ToLower(text)
+	}
+	return s
+}
+
+// isIdeographic reports whether r belongs to a script that is written
+// without spaces between words. Each such rune becomes a token of its own
+// and words are matched as phrases.
+func isIdeographic(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana)
+}
+
+// Tokenize splits text into normalized search tokens.
+func Tokenize(text string) []string {
+	var tokens []string
+	var word []rune
+	flush := func() {
+		if len(word) != 0 {
+			tokens = append(tokens, string(word))
+			word = word[:0]
+		}
+	}
+	for _, r := range fold(text) {
+		switch {
+		case isIdeographic(r):
+			flush()
+			tokens = append(tokens, string(r))
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			word = append(word, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	return tokens
+}
+
+// Postings maps each token of a text to the positions it appears at.
+type Postings map[string][]int
+
+// IndexText returns the postings of text.
+func IndexText(text string) Postings {
+	p := make(Postings)
+	for i, t := range Tokenize(text) {
+		p[t] = append(p[t], i)
+	}
+	return p
+}
+
+// SearchQuery is a parsed search. Each phrase is a list of tokens that must
+// appear next to each other; a single word is a phrase of one token.
+type SearchQuery struct {
+	// Include holds the phrases that must all be present.
+	Include [][]string
+	// Exclude holds the phrases that must not be present.
+	Exclude [][]string
+}
+
+// ParseSearch parses a search query. Words and "quoted phrases" are
+// required and prefixing either with a minus excludes it, for example:
+//
+//	tag -"tag list" upload
+func ParseSearch(query string) SearchQuery {
+	var q SearchQuery
+	for query != "" {
+		query = strings.TrimLeftFunc(query, unicode.IsSpace)
+		if query == "" {
+			break
+		}
+		exclude := false
+		if query[0] == '-' {
+			exclude = true
+			query = query[1:]
+		}
+		var part string
+		if strings.HasPrefix(query, `"`) {
+			query = query[1:]
+			end := strings.IndexByte(query, '"')
+			if end == -1 {
+				end = len(query)
+			}
+			part = query[:end]
+			query = query[min(end+1, len(query)):]
+		} else {
+			end := strings.IndexFunc(query, unicode.IsSpace)
+			if end == -1 {
+				end = len(query)
+			}
+			part = query[:end]
+			query = query[end:]
+		}
+		tokens := Tokenize(part)
+		if len(tokens) == 0 {
+			continue
+		}
+		if exclude {
+			q.Exclude = append(q.Exclude, tokens)
+		} else {
+			q.Include = append(q.Include, tokens)
+		}
+	}
+	return q
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// Tokens returns every distinct token that appears in the query.
+func (q SearchQuery) Tokens() []string {
+	seen := make(map[string]bool)
+	var tokens []string
+	for _, phrases := range [][][]string{q.Include, q.Exclude} {
+		for _, phrase := range phrases {
+			for _, t := range phrase {
+				if !seen[t] {
+					seen[t] = true
+					tokens = append(tokens, t)
+				}
+			}
+		}
+	}
+	return tokens
+}
+
+// count returns how many times phrase appears in p.
+func (p Postings) count(phrase []string) int {
+	n := 0
+	for _, start := range p[phrase[0]] {
+		found := true
+		for i, t := range phrase[1:] {
+			if !containsInt(p[t], start+i+1) {
+				found = false
+				break
+			}
+		}
+		if found {
+			n++
+		}
+	}
+	return n
+}
+
+func containsInt(s []int, v int) bool {
+	i := sort.SearchInts(s, v)
+	return i < len(s) && s[i] == v
+}
+
+// Match reports whether a text with postings p satisfies the query. A query
+// without any included phrase matches nothing.
+func (q SearchQuery) Match(p Postings) bool {
+	if len(q.Include) == 0 {
+		return false
+	}
+	for _, phrase := range q.Include {
+		if p.count(phrase) == 0 {
+			return false
+		}
+	}
+	for _, phrase := range q.Exclude {
+		if p.count(phrase) != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Score ranks a matching text with postings p using TF-IDF where df holds
+// the number of texts each token appears in out of n texts in total.
+func (q SearchQuery) Score(p Postings, df map[string]int, n int) float64 {
+	score := 0.0
+	for _, phrase := range q.Include {
+		idf := 0.0
+		for _, t := range phrase {
+			idf += math.Log(1 + float64(n)/float64(max(df[t], 1)))
+		}
+		score += (1 + math.Log(float64(p.count(phrase)))) * idf
+	}
+	return score
+}
+
+func max(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// ScoredSuggestion is a suggestion along with its search score.
+type ScoredSuggestion struct {
+	Suggestion
+	Score float64
+}
+
+// SortByScore sorts search results by descending score and newest first
+// when the scores are equal.
+func SortByScore(results []ScoredSuggestion) {
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Score != results[j].Score {
+			return results[i].Score > results[j].Score
+		}
+		return results[i].ID > results[j].ID
+	})
+}
+
+// Search returns the suggestions that match query ranked by relevance. It
+// searches every suggestion and is meant for stores that do not maintain
+// an index.
+func Search(suggs []Suggestion, query string) []Suggestion {
+	q := ParseSearch(query)
+	postings := make([]Postings, len(suggs))
+	df := make(map[string]int)
+	for i := range suggs {
+		postings[i] = IndexText(suggs[i].Text)
+		for t := range postings[i] {
+			df[t]++
+		}
+	}
+	var results []ScoredSuggestion
+	for i, p := range postings {
+		if q.Match(p) {
+			results = append(results, ScoredSuggestion{suggs[i], q.Score(p, df, len(suggs))})
+		}
+	}
+	SortByScore(results)
+	found := make([]Suggestion, len(results))
+	for i := range results {
+		found[i] = results[i].Suggestion
+	}
+	return found
+}
+
+// searchCursor is the position of the first suggestion of a page of search
+// results. Results are ranked again on every search so pages are found by
+// their offset rather than by a key like Query does.
+type searchCursor struct {
+	Offset int `json:"o"`
+}
+
+func encodeSearchCursor(offset int) string {
+	data, _ := json.Marshal(searchCursor{offset})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeSearchCursor(c string) (int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(c)
+	if err != nil {
+		return 0, Errorf(ErrInvalid, "invalid cursor")
+	}
+	var sc searchCursor
+	if err := json.Unmarshal(data, &sc); err != nil || sc.Offset < 0 {
+		return 0, Errorf(ErrInvalid, "invalid cursor")
+	}
+	return sc.Offset, nil
+}
+
+// PageSearch returns the page of q out of suggs, the ranked results of
+// searching for q.Text. The results that do not pass the other filters of
+// q are left out and the order of q is ignored. The cursors of the page
+// are only valid for PageSearch.
+func PageSearch(suggs []Suggestion, q Query) (*Page, error) {
+	first := 0
+	if q.Cursor != "" {
+		var err error
+		if first, err = decodeSearchCursor(q.Cursor); err != nil {
+			return nil, err
+		}
+	}
+	q.Text = ""
+	var matched []Suggestion
+	for i := range suggs {
+		if q.Match(&suggs[i]) {
+			matched = append(matched, suggs[i])
+		}
+	}
+	page := &Page{}
+	if q.Limit <= 0 {
+		page.Suggestions = matched
+		return page, nil
+	}
+	if first > len(matched) {
+		first = len(matched)
+	}
+	last := first + q.Limit
+	if last < len(matched) {
+		page.Next = encodeSearchCursor(last)
+	} else {
+		last = len(matched)
+	}
+	page.Suggestions = matched[first:last]
+	if first > 0 {
+		page.Prev = encodeSearchCursor(max(first-q.Limit, 0))
+	}
+	return page, nil
+}
@@ -0,0 +1,114 @@
+package teian
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+var tokenizeTests = []struct {
+	in  string
+	out []string
+}{
+	{"Add more Tags!", []string{"add", "more", "tags"}},
+	{"Café CAFE café", []string{"cafe", "cafe", "cafe"}},
+	{"Ｔａｇ tag-list", []string{"tag", "tag", "list"}},
+	{"Straße", []string{"strasse"}},
+	{"タグを追加", []string{"タ", "グ", "を", "追", "加"}},
+	{"ｶﾞ", []string{"ガ"}},
+	{"  ", nil},
+}
+
+func TestTokenize(t *testing.T) {
+	for _, tt := range tokenizeTests {
+		if got, want := Tokenize(tt.in), tt.out; !reflect.DeepEqual(got, want) {
+			t.Errorf("Tokenize(%q) = %q, want %q", tt.in, got, want)
+		}
+	}
+}
+
+var parseSearchTests = []struct {
+	in  string
+	out SearchQuery
+}{
+	{"tag", SearchQuery{Include: [][]string{{"tag"}}}},
+	{`Tag -"tag list" upload`, SearchQuery{
+		Include: [][]string{{"tag"}, {"upload"}},
+		Exclude: [][]string{{"tag", "list"}},
+	}},
+	{`"unterminated phrase`, SearchQuery{Include: [][]string{{"unterminated", "phrase"}}}},
+	{"tag-list -", SearchQuery{Include: [][]string{{"tag", "list"}}}},
+	{"", SearchQuery{}},
+}
+
+func TestParseSearch(t *testing.T) {
+	for _, tt := range parseSearchTests {
+		if got, want := ParseSearch(tt.in), tt.out; !reflect.DeepEqual(got, want) {
+			t.Errorf("ParseSearch(%q) = %#v, want %#v", tt.in, got, want)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	suggs := []Suggestion{
+		{ID: 1, Text: "Please add a tag list"},
+		{ID: 2, Text: "tag tag tag, the tag page is slow"},
+		{ID: 3, Text: "list of uploads"},
+		{ID: 4, Text: "The list tag is broken"},
+	}
+	tests := []struct {
+		query string
+		want  []uint64
+	}{
+		{"TAG", []uint64{2, 4, 1}},
+		{`"tag list"`, []uint64{1}},
+		{"tag -slow", []uint64{4, 1}},
+		{`list -"tag list"`, []uint64{4, 3}},
+		{"-tag", nil},
+		{"missing", nil},
+	}
+	for _, tt := range tests {
+		var got []uint64
+		for _, s := range Search(suggs, tt.query) {
+			got = append(got, s.ID)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q) returned IDs %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestPageSearch(t *testing.T) {
+	var suggs []Suggestion
+	for id := uint64(7); id > 0; id-- {
+		suggs = append(suggs, Suggestion{ID: id, Username: "john", Public: id%2 == 1})
+	}
+	q := Query{Text: "ignored", Public: true, Limit: 2}
+	var pages [][]uint64
+	for {
+		page, err := PageSearch(suggs, q)
+		if err != nil {
+			t.Fatal("PageSearch failed:", err)
+		}
+		var ids []uint64
+		for _, s := range page.Suggestions {
+			ids = append(ids, s.ID)
+		}
+		pages = append(pages, ids)
+		if len(pages) > 1 && page.Prev == "" {
+			t.Errorf("page %d has no previous page", len(pages))
+		}
+		if page.Next == "" {
+			break
+		}
+		q.Cursor = page.Next
+	}
+	if want := [][]uint64{{7, 5}, {3, 1}}; !reflect.DeepEqual(pages, want) {
+		t.Errorf("PageSearch pages = %v, want %v", pages, want)
+	}
+
+	q.Cursor = "!"
+	if _, err := PageSearch(suggs, q); !errors.Is(err, ErrInvalid) {
+		t.Errorf("PageSearch with bad cursor returned %v, want %v", err, ErrInvalid)
+	}
+}
@@ -0,0 +1,88 @@
+package teian
+
+import (
+	"sort"
+	"time"
+)
+
+// DuplicateThreshold is the similarity at or above which a new suggestion is
+// considered a likely duplicate of an existing one.
+const DuplicateThreshold = 0.4
+
+// Trigrams returns the set of character trigrams of the tokens of text. Each
+// token is padded with a space on both sides so that short words and word
+// boundaries count as well.
+func Trigrams(text string) map[string]bool {
+	set := make(map[string]bool)
+	for _, t := range Tokenize(text) {
+		r := []rune(" " + t + " ")
+		for i := 0; i+3 <= len(r); i++ {
+			set[string(r[i:i+3])] = true
+		}
+	}
+	return set
+}
+
+// Similarity returns the Jaccard index of the trigrams of a and b, from 0
+// for texts that share nothing to 1 for texts with the same trigrams.
+func Similarity(a, b string) float64 {
+	return jaccard(Trigrams(a), Trigrams(b))
+}
+
+func jaccard(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	common := 0
+	for t := range a {
+		if b[t] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
+
+// Similar returns the suggestions whose similarity to text is at least
+// threshold, the most similar first. The score of each result is its
+// similarity.
+func Similar(suggs []Suggestion, text string, threshold float64) []ScoredSuggestion {
+	trigrams := Trigrams(text)
+	var results []ScoredSuggestion
+	for _, s := range suggs {
+		if score := jaccard(trigrams, Trigrams(s.Text)); score >= threshold {
+			results = append(results, ScoredSuggestion{s, score})
+		}
+	}
+	SortByScore(results)
+	return results
+}
+
+// Merge folds the duplicate d into s. The replies and tags of d are added to
+// those of s, the merge is recorded in the history of s and d is marked as a
+// duplicate of s. The history of d stays with d. Votes are not merged since
+// only the store knows who voted.
+func (s *Suggestion) Merge(d *Suggestion, by string, at time.Time) {
+	s.Replies = append(s.Replies, d.Replies...)
+	sort.SliceStable(s.Replies, func(i, j int) bool {
+		return s.Replies[i].Created.Before(s.Replies[j].Created)
+	})
+	s.History = append(s.History, StatusChange{From: s.Status, To: s.Status, By: by, At: at, Merged: d.ID})
+	for _, t := range d.Tags {
+		if !containsString(s.Tags, t) {
+			s.Tags = append(s.Tags, t)
+		}
+	}
+	s.Merged = append(s.Merged, d.ID)
+
+	d.SetStatus(StatusDuplicate, by, at)
+	d.MergedInto = s.ID
+}
+
+func containsString(s []string, v string) bool {
+	for _, x := range s {
+		if x == v {
+			return true
+		}
+	}
+	return false
+}
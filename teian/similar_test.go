@@ -0,0 +1,75 @@
+package teian
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSimilarity(t *testing.T) {
+	tests := []struct {
+		a, b string
+		dup  bool
+	}{
+		{"Add more tags to uploads", "add more tags to uploads!", true},
+		{"Add more tags to uploads", "please add more tags on the uploads", true},
+		{"Add more tags to uploads", "Dark theme for the site", false},
+		{"", "anything", false},
+	}
+	for _, tt := range tests {
+		if got := Similarity(tt.a, tt.b) >= DuplicateThreshold; got != tt.dup {
+			t.Errorf("Similarity(%q, %q) = %.2f, duplicate %v, want %v", tt.a, tt.b, Similarity(tt.a, tt.b), got, tt.dup)
+		}
+	}
+	if got := Similarity("same text", "Same Text"); got != 1 {
+		t.Errorf("Similarity of texts differing in case = %v, want 1", got)
+	}
+}
+
+func TestSimilar(t *testing.T) {
+	suggs := []Suggestion{
+		{ID: 1, Text: "dark theme"},
+		{ID: 2, Text: "add a dark theme please"},
+		{ID: 3, Text: "dark theme for mobile"},
+	}
+	var got []uint64
+	for _, r := range Similar(suggs, "dark theme please", DuplicateThreshold) {
+		got = append(got, r.ID)
+	}
+	if want := []uint64{2, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Similar = %v, want %v", got, want)
+	}
+}
+
+func TestSuggestion_Merge(t *testing.T) {
+	t1 := time.Date(2016, 1, 1, 0, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Hour)
+	t3 := t2.Add(time.Hour)
+	s := &Suggestion{ID: 1, Tags: []string{"touhou"}, Replies: []Reply{{Text: "a", Created: t1}, {Text: "c", Created: t3}}}
+	d := &Suggestion{ID: 2, Tags: []string{"touhou", "smile"}, Replies: []Reply{{Text: "b", Created: t2}}}
+	d.SetStatus(StatusPlanned, "admin", t1)
+	s.Merge(d, "admin", t3)
+
+	var replies []string
+	for _, r := range s.Replies {
+		replies = append(replies, r.Text)
+	}
+	if want := []string{"a", "b", "c"}; !reflect.DeepEqual(replies, want) {
+		t.Errorf("merged replies = %q, want %q", replies, want)
+	}
+	if want := []string{"touhou", "smile"}; !reflect.DeepEqual(s.Tags, want) {
+		t.Errorf("merged tags = %q, want %q", s.Tags, want)
+	}
+	if want := []uint64{2}; !reflect.DeepEqual(s.Merged, want) {
+		t.Errorf("Merged = %v, want %v", s.Merged, want)
+	}
+	if d.Status != StatusDuplicate || d.MergedInto != 1 {
+		t.Errorf("duplicate has status %v and MergedInto %d, want %v and 1", d.Status, d.MergedInto, StatusDuplicate)
+	}
+	if want := []StatusChange{{From: StatusNew, To: StatusPlanned, By: "admin", At: t1}, {From: StatusPlanned, To: StatusDuplicate, By: "admin", At: t3}}; !reflect.DeepEqual(d.History, want) {
+		t.Errorf("duplicate history = %v, want %v", d.History, want)
+	}
+	if want := []StatusChange{{From: StatusNew, To: StatusNew, By: "admin", At: t3, Merged: 2}}; !reflect.DeepEqual(s.History, want) {
+		t.Errorf("merged into history = %v, want %v", s.History, want)
+	}
+}
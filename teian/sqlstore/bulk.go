@@ -0,0 +1,93 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+func (db *SQLStore) Bulk(ctx context.Context, ids []uint64, op teian.BulkOp) (map[uint64]error, error) {
+	var apply func(tx *sql.Tx, id uint64) error
+	switch op.Action {
+	case teian.BulkDelete:
+		apply = func(tx *sql.Tx, id uint64) error {
+			return db.trashSuggestion(tx, "", id, op.By)
+		}
+	case teian.BulkStatus:
+		at := now()
+		apply = func(tx *sql.Tx, id uint64) error {
+			return db.updateSuggestion(tx, "", id, func(s *teian.Suggestion) bool {
+				return s.SetStatus(op.Status, op.By, at)
+			})
+		}
+	case teian.BulkCategory:
+		apply = func(tx *sql.Tx, id uint64) error {
+			return db.updateSuggestion(tx, "", id, func(s *teian.Suggestion) bool {
+				if s.Category == op.Category {
+					return false
+				}
+				s.Category = op.Category
+				return true
+			})
+		}
+	default:
+		return nil, teian.ErrBadBulkAction
+	}
+
+	failed := make(map[uint64]error)
+	err := db.tx(ctx, func(tx *sql.Tx) error {
+		if op.Action == teian.BulkCategory {
+			if err := checkCategory(tx, op.Category); err != nil {
+				return err
+			}
+		}
+		for _, id := range ids {
+			if err := apply(tx, id); err != nil {
+				failed[id] = err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return failed, nil
+}
+
+// GetMany reads the suggestions with a single query so that they are read at
+// the same point in time.
+func (db *SQLStore) GetMany(ctx context.Context, ids []uint64) ([]teian.Suggestion, map[uint64]error, error) {
+	failed := make(map[uint64]error)
+	if len(ids) == 0 {
+		return nil, failed, nil
+	}
+	args := make([]interface{}, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	rows, err := db.DB.QueryContext(ctx, `SELECT `+suggestionColumns+` FROM teian_suggestions
+		WHERE id IN (?`+strings.Repeat(", ?", len(ids)-1)+`) AND deleted_at IS NULL`, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	found, err := scanSuggestions(rows)
+	if err != nil {
+		return nil, nil, err
+	}
+	byID := make(map[uint64]teian.Suggestion, len(found))
+	for _, s := range found {
+		byID[s.ID] = s
+	}
+	var suggs []teian.Suggestion
+	for _, id := range ids {
+		s, ok := byID[id]
+		if !ok {
+			failed[id] = teian.ErrNotFound
+			continue
+		}
+		suggs = append(suggs, s)
+	}
+	return suggs, failed, nil
+}
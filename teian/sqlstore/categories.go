@@ -0,0 +1,114 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"strings"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+// checkCategory returns teian.ErrUnknownCategory if category is not empty
+// and has not been defined.
+func checkCategory(tx *sql.Tx, category string) error {
+	if category == "" {
+		return nil
+	}
+	var name string
+	err := tx.QueryRow(`SELECT name FROM teian_categories WHERE name = ?`, category).Scan(&name)
+	if err == sql.ErrNoRows {
+		return teian.ErrUnknownCategory
+	}
+	return err
+}
+
+func (db *SQLStore) SetCategory(ctx context.Context, username string, id uint64, category string) error {
+	return db.tx(ctx, func(tx *sql.Tx) error {
+		if err := checkCategory(tx, category); err != nil {
+			return err
+		}
+		return db.updateSuggestion(tx, username, id, func(s *teian.Suggestion) bool {
+			if s.Category == category {
+				return false
+			}
+			s.Category = category
+			return true
+		})
+	})
+}
+
+func (db *SQLStore) Categories(ctx context.Context) ([]string, error) {
+	rows, err := db.DB.QueryContext(ctx, `SELECT name FROM teian_categories`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		names = append(names, name)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	// Sorted here since the collations of the databases differ.
+	sort.Strings(names)
+	return names, nil
+}
+
+func (db *SQLStore) AddCategory(ctx context.Context, name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" || strings.ContainsRune(name, 0) {
+		return teian.Errorf(teian.ErrInvalid, "invalid category name")
+	}
+	return db.tx(ctx, func(tx *sql.Tx) error {
+		err := checkCategory(tx, name)
+		if err != teian.ErrUnknownCategory {
+			return err
+		}
+		_, err = tx.Exec(`INSERT INTO teian_categories (name) VALUES (?)`, name)
+		return err
+	})
+}
+
+// RemoveCategory deletes the category name and leaves the suggestions that
+// were in it uncategorized. Like Boltstore, it leaves the trash untouched.
+func (db *SQLStore) RemoveCategory(ctx context.Context, name string) error {
+	return db.tx(ctx, func(tx *sql.Tx) error {
+		res, err := tx.Exec(`DELETE FROM teian_categories WHERE name = ?`, name)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil {
+			return err
+		} else if n == 0 {
+			return teian.ErrNotFound
+		}
+		_, err = tx.Exec(`UPDATE teian_suggestions SET category = ''
+			WHERE category = ? AND deleted_at IS NULL`, name)
+		return err
+	})
+}
+
+func (db *SQLStore) CategoryCounts(ctx context.Context) (map[string]int, error) {
+	rows, err := db.DB.QueryContext(ctx, `SELECT category, COUNT(*) FROM teian_suggestions
+		WHERE category <> '' AND deleted_at IS NULL GROUP BY category`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	counts := make(map[string]int)
+	for rows.Next() {
+		var category string
+		var n int
+		if err := rows.Scan(&category, &n); err != nil {
+			return nil, err
+		}
+		counts[category] = n
+	}
+	return counts, rows.Err()
+}
@@ -0,0 +1,117 @@
+package sqlstore
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+func TestCategories(t *testing.T) {
+	store, f := setup()
+	defer teardown(store, f)
+	ctx := context.Background()
+
+	for _, name := range []string{"ui", " api ", "ui", "Bugs"} {
+		if err := store.AddCategory(ctx, name); err != nil {
+			t.Fatalf("store.AddCategory(ctx, %q) failed: %v", name, err)
+		}
+	}
+	for _, name := range []string{"", "  ", "a\x00b"} {
+		if err := store.AddCategory(ctx, name); err == nil {
+			t.Errorf("store.AddCategory(ctx, %q) expected to return error", name)
+		}
+	}
+	names, err := store.Categories(ctx)
+	if err != nil {
+		t.Fatal("store.Categories failed:", err)
+	}
+	if want := []string{"Bugs", "api", "ui"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("store.Categories = %q, want %q", names, want)
+	}
+
+	for i := 0; i < 3; i++ {
+		if err := store.Create(ctx, "john", &teian.Suggestion{Text: "text", Category: "ui"}); err != nil {
+			t.Fatal("store.Create failed:", err)
+		}
+	}
+	if err := store.SetCategory(ctx, "john", 3, "api"); err != nil {
+		t.Fatal("store.SetCategory failed:", err)
+	}
+	if err := store.SetCategory(ctx, "john", 3, "nope"); err != teian.ErrUnknownCategory {
+		t.Errorf("store.SetCategory with unknown category returned %v, want %v", err, teian.ErrUnknownCategory)
+	}
+	if err := store.Delete(ctx, "john", 2, "john"); err != nil {
+		t.Fatal("store.Delete failed:", err)
+	}
+	counts, err := store.CategoryCounts(ctx)
+	if err != nil {
+		t.Fatal("store.CategoryCounts failed:", err)
+	}
+	if want := map[string]int{"ui": 1, "api": 1}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("store.CategoryCounts = %v, want %v", counts, want)
+	}
+
+	if err := store.RemoveCategory(ctx, "ui"); err != nil {
+		t.Fatal("store.RemoveCategory failed:", err)
+	}
+	if err := store.RemoveCategory(ctx, "ui"); err != teian.ErrNotFound {
+		t.Errorf("store.RemoveCategory of missing category returned %v, want %v", err, teian.ErrNotFound)
+	}
+	s, err := store.Get(ctx, 1)
+	if err != nil {
+		t.Fatal("store.Get failed:", err)
+	}
+	if s.Category != "" {
+		t.Errorf("suggestion of removed category has category %q, want none", s.Category)
+	}
+}
+
+func TestBulk(t *testing.T) {
+	store, f := setup()
+	defer teardown(store, f)
+	ctx := context.Background()
+
+	if err := store.AddCategory(ctx, "ui"); err != nil {
+		t.Fatal("store.AddCategory failed:", err)
+	}
+	for i := 0; i < 3; i++ {
+		if err := store.Create(ctx, "john", &teian.Suggestion{Text: "text"}); err != nil {
+			t.Fatal("store.Create failed:", err)
+		}
+	}
+	ops := []teian.BulkOp{
+		{Action: teian.BulkStatus, Status: teian.StatusPlanned, By: "admin"},
+		{Action: teian.BulkCategory, Category: "ui", By: "admin"},
+		{Action: teian.BulkDelete, By: "admin"},
+	}
+	for _, op := range ops {
+		failed, err := store.Bulk(ctx, []uint64{1, 2, 9}, op)
+		if err != nil {
+			t.Fatalf("store.Bulk(ctx, %+v) failed: %v", op, err)
+		}
+		if len(failed) != 1 || failed[9] != teian.ErrNotFound {
+			t.Errorf("store.Bulk(ctx, %+v) failed IDs = %v, want only 9", op, failed)
+		}
+	}
+	trash, err := store.Trash(ctx)
+	if err != nil {
+		t.Fatal("store.Trash failed:", err)
+	}
+	for _, s := range trash {
+		if s.Status != teian.StatusPlanned || s.Category != "ui" {
+			t.Errorf("bulk changed suggestion %d has status %v category %q, want planned ui", s.ID, s.Status, s.Category)
+		}
+	}
+	if len(trash) != 2 {
+		t.Errorf("store.Trash after bulk delete returned %d suggestions, want 2", len(trash))
+	}
+
+	if _, err := store.Bulk(ctx, []uint64{3}, teian.BulkOp{Action: teian.BulkCategory, Category: "nope"}); err != teian.ErrUnknownCategory {
+		t.Errorf("store.Bulk with unknown category returned %v, want %v", err, teian.ErrUnknownCategory)
+	}
+	if _, err := store.Bulk(ctx, []uint64{3}, teian.BulkOp{}); err != teian.ErrBadBulkAction {
+		t.Errorf("store.Bulk with no action returned %v, want %v", err, teian.ErrBadBulkAction)
+	}
+}
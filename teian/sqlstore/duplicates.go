@@ -0,0 +1,103 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+// Similar compares text with every suggestion that shares at least one
+// token with it, like Boltstore does through its search index.
+func (db *SQLStore) Similar(ctx context.Context, text string, threshold float64) ([]teian.ScoredSuggestion, error) {
+	all, err := db.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tokens := teian.IndexText(text)
+	var suggs []teian.Suggestion
+	for _, s := range all {
+		for token := range teian.IndexText(s.Text) {
+			if tokens[token] != nil {
+				suggs = append(suggs, s)
+				break
+			}
+		}
+	}
+	return teian.Similar(suggs, text, threshold), nil
+}
+
+func (db *SQLStore) Merge(ctx context.Context, into, from uint64, by string) error {
+	if into == from {
+		return teian.Errorf(teian.ErrInvalid, "cannot merge a suggestion into itself")
+	}
+	return db.tx(ctx, func(tx *sql.Tx) error {
+		s, err := db.getSuggestion(tx, "", into)
+		if err != nil {
+			return err
+		}
+		d, err := db.getSuggestion(tx, "", from)
+		if err != nil {
+			return err
+		}
+		if s.MergedInto != 0 {
+			return teian.Errorf(teian.ErrConflict, "cannot merge into suggestion %d which is merged into %d", into, s.MergedInto)
+		}
+		if d.MergedInto != 0 {
+			return teian.Errorf(teian.ErrConflict, "suggestion %d is already merged into %d", from, d.MergedInto)
+		}
+		if len(d.Merged) != 0 {
+			return teian.Errorf(teian.ErrConflict, "cannot merge suggestion %d which has duplicates merged into it", from)
+		}
+
+		// Move the votes of the duplicate.
+		votes, err := votesOn(tx, from)
+		if err != nil {
+			return err
+		}
+		for username, vote := range votes {
+			d.ApplyVote(vote, 0)
+			old, err := getVote(tx, username, into)
+			if err != nil {
+				return err
+			}
+			if old != 0 {
+				continue
+			}
+			if err := putVote(tx, username, into, 0, vote); err != nil {
+				return err
+			}
+			s.ApplyVote(0, vote)
+		}
+		if _, err := tx.Exec(`DELETE FROM teian_votes WHERE suggestion_id = ?`, from); err != nil {
+			return err
+		}
+
+		s.Merge(d, by, now())
+		for _, m := range []*teian.Suggestion{s, d} {
+			if err := putSuggestion(tx, m); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// votesOn returns the votes on suggestion id keyed by username.
+func votesOn(tx *sql.Tx, id uint64) (map[string]int, error) {
+	rows, err := tx.Query(`SELECT username, vote FROM teian_votes WHERE suggestion_id = ?`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	votes := make(map[string]int)
+	for rows.Next() {
+		var username string
+		var vote int
+		if err := rows.Scan(&username, &vote); err != nil {
+			return nil, err
+		}
+		votes[username] = vote
+	}
+	return votes, rows.Err()
+}
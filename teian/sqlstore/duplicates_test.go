@@ -0,0 +1,120 @@
+package sqlstore
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+func TestVote(t *testing.T) {
+	store, f := setup()
+	defer teardown(store, f)
+	ctx := context.Background()
+
+	if err := store.Create(ctx, "john", &teian.Suggestion{Text: "text", Public: true}); err != nil {
+		t.Fatal("store.Create failed:", err)
+	}
+	steps := []struct {
+		username string
+		vote     int
+		up, down int
+	}{
+		{"mary", 1, 1, 0},
+		{"mary", 1, 1, 0},
+		{"bob", -1, 1, 1},
+		{"mary", -1, 0, 2},
+		{"bob", 0, 0, 1},
+	}
+	for _, st := range steps {
+		if err := store.Vote(ctx, 1, st.username, st.vote); err != nil {
+			t.Fatal("store.Vote failed:", err)
+		}
+		s, err := store.Get(ctx, 1)
+		if err != nil {
+			t.Fatal("store.Get failed:", err)
+		}
+		if s.Upvotes != st.up || s.Downvotes != st.down {
+			t.Errorf("after vote %d by %s got %d up %d down, want %d up %d down", st.vote, st.username, s.Upvotes, s.Downvotes, st.up, st.down)
+		}
+	}
+	if err := store.Vote(ctx, 1, "mary", 2); err != teian.ErrBadVote {
+		t.Errorf("store.Vote(ctx, 1, mary, 2) returned %v, want %v", err, teian.ErrBadVote)
+	}
+	if err := store.Vote(ctx, 2, "mary", 1); err != teian.ErrNotFound {
+		t.Errorf("store.Vote on missing suggestion returned %v, want %v", err, teian.ErrNotFound)
+	}
+	votes, err := store.VotesOf(ctx, "mary")
+	if err != nil {
+		t.Fatal("store.VotesOf failed:", err)
+	}
+	if want := map[uint64]int{1: -1}; !reflect.DeepEqual(votes, want) {
+		t.Errorf("store.VotesOf(ctx, mary) = %v, want %v", votes, want)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	store, f := setup()
+	defer teardown(store, f)
+	ctx := context.Background()
+
+	for _, text := range []string{"add a dark theme", "dark theme please", "faster uploads"} {
+		if err := store.Create(ctx, "john", &teian.Suggestion{Text: text, Public: true}); err != nil {
+			t.Fatal("store.Create failed:", err)
+		}
+	}
+	similar, err := store.Similar(ctx, "a dark theme", 0.3)
+	if err != nil {
+		t.Fatal("store.Similar failed:", err)
+	}
+	var got []uint64
+	for _, s := range similar {
+		got = append(got, s.ID)
+	}
+	if want := []uint64{1, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("store.Similar returned IDs %v, want %v", got, want)
+	}
+
+	// mary voted on both so only bob's vote moves.
+	for _, v := range []struct {
+		id       uint64
+		username string
+		vote     int
+	}{{1, "mary", 1}, {2, "mary", -1}, {2, "bob", 1}} {
+		if err := store.Vote(ctx, v.id, v.username, v.vote); err != nil {
+			t.Fatal("store.Vote failed:", err)
+		}
+	}
+	if err := store.Merge(ctx, 1, 2, "admin"); err != nil {
+		t.Fatal("store.Merge failed:", err)
+	}
+	into, err := store.Get(ctx, 1)
+	if err != nil {
+		t.Fatal("store.Get failed:", err)
+	}
+	from, err := store.Get(ctx, 2)
+	if err != nil {
+		t.Fatal("store.Get failed:", err)
+	}
+	if into.Upvotes != 2 || into.Downvotes != 0 || !reflect.DeepEqual(into.Merged, []uint64{2}) {
+		t.Errorf("merged into suggestion has %d up %d down merged %v, want 2 up 0 down merged [2]", into.Upvotes, into.Downvotes, into.Merged)
+	}
+	if from.Upvotes != 0 || from.Downvotes != 0 || from.MergedInto != 1 || from.Status != teian.StatusDuplicate {
+		t.Errorf("duplicate has %d up %d down merged into %d status %v, want no votes merged into 1 and duplicate", from.Upvotes, from.Downvotes, from.MergedInto, from.Status)
+	}
+	votes, err := store.VotesOf(ctx, "bob")
+	if err != nil {
+		t.Fatal("store.VotesOf failed:", err)
+	}
+	if want := map[uint64]int{1: 1}; !reflect.DeepEqual(votes, want) {
+		t.Errorf("store.VotesOf(ctx, bob) after merge = %v, want %v", votes, want)
+	}
+
+	if err := store.Merge(ctx, 3, 2, "admin"); err == nil {
+		t.Error("store.Merge of merged suggestion expected to return error")
+	}
+	if err := store.Merge(ctx, 1, 1, "admin"); err == nil {
+		t.Error("store.Merge into itself expected to return error")
+	}
+}
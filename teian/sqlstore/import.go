@@ -0,0 +1,80 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+// errDryRun rolls back the transaction of an import that is only a dry run.
+var errDryRun = errors.New("dry run")
+
+func (db *SQLStore) Import(ctx context.Context, suggs []teian.Suggestion, dryRun bool) ([]bool, error) {
+	added := make([]bool, len(suggs))
+	err := db.tx(ctx, func(tx *sql.Tx) error {
+		// Advance the sequence past the largest imported ID first so that
+		// the suggestions without one do not take an ID used further on.
+		for _, s := range suggs {
+			if s.ID == 0 {
+				continue
+			}
+			_, err := tx.Exec(`UPDATE teian_meta SET value = ? WHERE name = 'sequence' AND value < ?`, s.ID, s.ID)
+			if err != nil {
+				return err
+			}
+		}
+		for i := range suggs {
+			s := &suggs[i]
+			if err := checkCategory(tx, s.Category); err != nil {
+				return err
+			}
+			exists, err := imported(tx, s)
+			if err != nil {
+				return err
+			}
+			if exists {
+				continue
+			}
+			if s.ID == 0 {
+				if s.ID, err = nextID(tx); err != nil {
+					return err
+				}
+			}
+			if s.Created.IsZero() {
+				s.Created = now()
+			}
+			if err := insertSuggestion(tx, s); err != nil {
+				return err
+			}
+			if err := putRevision(tx, s.ID, 0, &teian.Revision{Text: s.Text, By: s.Username, At: s.Created}); err != nil {
+				return err
+			}
+			added[i] = true
+		}
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	if err != nil && err != errDryRun {
+		return nil, err
+	}
+	return added, nil
+}
+
+// imported reports whether s is already in the store. Suggestions with an ID
+// are looked up by it, including in the trash, while suggestions without one
+// are considered imported if the user has one with the same text.
+func imported(tx *sql.Tx, s *teian.Suggestion) (bool, error) {
+	var n int
+	var err error
+	if s.ID != 0 {
+		err = tx.QueryRow(`SELECT COUNT(*) FROM teian_suggestions WHERE id = ?`, s.ID).Scan(&n)
+	} else {
+		err = tx.QueryRow(`SELECT COUNT(*) FROM teian_suggestions
+			WHERE username = ? AND text = ? AND deleted_at IS NULL`, s.Username, s.Text).Scan(&n)
+	}
+	return n != 0, err
+}
@@ -0,0 +1,81 @@
+package sqlstore
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+func TestImport(t *testing.T) {
+	store, f := setup()
+	defer teardown(store, f)
+	ctx := context.Background()
+
+	if err := store.Create(ctx, "john", &teian.Suggestion{Text: "existing"}); err != nil {
+		t.Fatal("store.Create failed:", err)
+	}
+	created := time.Date(2019, 5, 6, 7, 8, 9, 0, time.UTC)
+	suggs := []teian.Suggestion{
+		{ID: 1, Username: "john", Text: "existing"},
+		{ID: 7, Username: "mary", Text: "seven", Created: created},
+		{Username: "john", Text: "existing"},
+		{Username: "bob", Text: "new"},
+	}
+
+	added, err := store.Import(ctx, append([]teian.Suggestion(nil), suggs...), true)
+	if err != nil {
+		t.Fatal("store.Import dry run failed:", err)
+	}
+	want := []bool{false, true, false, true}
+	if !reflect.DeepEqual(added, want) {
+		t.Errorf("store.Import dry run added = %v, want %v", added, want)
+	}
+	if all, err := store.All(ctx); err != nil || len(all) != 1 {
+		t.Fatalf("store.All after dry run = %v, %v, want 1 suggestion", all, err)
+	}
+
+	added, err = store.Import(ctx, suggs, false)
+	if err != nil {
+		t.Fatal("store.Import failed:", err)
+	}
+	if !reflect.DeepEqual(added, want) {
+		t.Errorf("store.Import added = %v, want %v", added, want)
+	}
+	all, err := store.All(ctx)
+	if err != nil {
+		t.Fatal("store.All failed:", err)
+	}
+	if got, want := ids(all), []uint64{1, 7, 8}; !reflect.DeepEqual(got, want) {
+		t.Errorf("store.All after import returned IDs %v, want %v", got, want)
+	}
+	if !all[1].Created.Equal(created) {
+		t.Errorf("imported suggestion created %v, want %v", all[1].Created, created)
+	}
+	revs, err := store.Revisions(ctx, 7)
+	if err != nil {
+		t.Fatal("store.Revisions failed:", err)
+	}
+	if len(revs) != 1 || revs[0].Text != "seven" || revs[0].By != "mary" {
+		t.Errorf("store.Revisions of imported suggestion = %v, want its text by mary", revs)
+	}
+
+	// Importing again adds nothing and new suggestions continue the
+	// sequence.
+	added, err = store.Import(ctx, suggs[:2], false)
+	if err != nil {
+		t.Fatal("store.Import again failed:", err)
+	}
+	if !reflect.DeepEqual(added, []bool{false, false}) {
+		t.Errorf("store.Import again added = %v, want nothing", added)
+	}
+	sugg := &teian.Suggestion{Text: "next"}
+	if err := store.Create(ctx, "john", sugg); err != nil {
+		t.Fatal("store.Create failed:", err)
+	}
+	if sugg.ID != 9 {
+		t.Errorf("store.Create after import assigned ID %d, want 9", sugg.ID)
+	}
+}
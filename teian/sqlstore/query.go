@@ -0,0 +1,229 @@
+package sqlstore
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+var errBadCursor = teian.Errorf(teian.ErrInvalid, "invalid cursor")
+
+// queryBatch is how many rows Query reads at a time.
+const queryBatch = 100
+
+// A key is the position of a suggestion in the order of a query: the ID
+// preceded by the username or the net votes when ordering by those. A
+// cursor is the base64 encoded JSON of the key of the first suggestion of a
+// page.
+type key struct {
+	Username string `json:"u,omitempty"`
+	Votes    int    `json:"v,omitempty"`
+	ID       uint64 `json:"i"`
+}
+
+func encodeCursor(k key) string {
+	data, _ := json.Marshal(k)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(c string) (*key, error) {
+	data, err := base64.RawURLEncoding.DecodeString(c)
+	if err != nil {
+		return nil, errBadCursor
+	}
+	k := new(key)
+	if err := json.Unmarshal(data, k); err != nil || k.ID == 0 {
+		return nil, errBadCursor
+	}
+	return k, nil
+}
+
+// order describes how to walk the suggestions in the order of a query.
+type order struct {
+	// columns are the expressions of the order, the ID last.
+	columns []string
+	key     func(s *teian.Suggestion) key
+	values  func(k key) []interface{}
+	desc    bool
+}
+
+func queryOrder(o teian.Order) order {
+	switch o {
+	case teian.OrderUserAsc, teian.OrderUserDesc:
+		return order{
+			columns: []string{"username", "id"},
+			key:     func(s *teian.Suggestion) key { return key{Username: s.Username, ID: s.ID} },
+			values:  func(k key) []interface{} { return []interface{}{k.Username, k.ID} },
+			desc:    o == teian.OrderUserDesc,
+		}
+	case teian.OrderVotesAsc, teian.OrderVotesDesc:
+		return order{
+			columns: []string{"upvotes - downvotes", "id"},
+			key:     func(s *teian.Suggestion) key { return key{Votes: s.Votes(), ID: s.ID} },
+			values:  func(k key) []interface{} { return []interface{}{k.Votes, k.ID} },
+			desc:    o == teian.OrderVotesDesc,
+		}
+	default:
+		return order{
+			columns: []string{"id"},
+			key:     func(s *teian.Suggestion) key { return key{ID: s.ID} },
+			values:  func(k key) []interface{} { return []interface{}{k.ID} },
+			desc:    o != teian.OrderDateAsc,
+		}
+	}
+}
+
+// after returns the condition and arguments that select the rows that
+// follow k, or are k if inclusive, when walking in the given direction.
+func (o order) after(k key, desc, inclusive bool) (string, []interface{}) {
+	op := ">"
+	if desc {
+		op = "<"
+	}
+	values := o.values(k)
+	var conds []string
+	var args []interface{}
+	for i, col := range o.columns {
+		var eq []string
+		for j := 0; j < i; j++ {
+			eq = append(eq, o.columns[j]+" = ?")
+			args = append(args, values[j])
+		}
+		cmp := op
+		if inclusive && i == len(o.columns)-1 {
+			cmp += "="
+		}
+		conds = append(conds, "("+strings.Join(append(eq, col+" "+cmp+" ?"), " AND ")+")")
+		args = append(args, values[i])
+	}
+	return "(" + strings.Join(conds, " OR ") + ")", args
+}
+
+// walk calls fn with the suggestions that pass the filters of q that can be
+// checked by the database, in the given direction of the order of q,
+// starting at start or the beginning if it is nil. The rest of the filters
+// are left to fn. Walking stops when fn returns false.
+func (db *SQLStore) walk(ctx context.Context, q teian.Query, start *key, desc, inclusive bool, fn func(k key, s *teian.Suggestion) bool) error {
+	o := queryOrder(q.Order)
+	dir := " ASC"
+	if desc {
+		dir = " DESC"
+	}
+	var orderBy []string
+	for _, col := range o.columns {
+		orderBy = append(orderBy, col+dir)
+	}
+
+	where := []string{"deleted_at IS NULL"}
+	var filterArgs []interface{}
+	if len(q.Statuses) != 0 {
+		where = append(where, "status IN (?"+strings.Repeat(", ?", len(q.Statuses)-1)+")")
+		for _, s := range q.Statuses {
+			filterArgs = append(filterArgs, s)
+		}
+	}
+	if q.Public {
+		where = append(where, "public = ?")
+		filterArgs = append(filterArgs, true)
+	}
+	if q.Category != "" {
+		where = append(where, "category = ?")
+		filterArgs = append(filterArgs, q.Category)
+	}
+
+	for {
+		conds := where
+		args := filterArgs
+		if start != nil {
+			cond, startArgs := o.after(*start, desc, inclusive)
+			conds = append(conds[:len(conds):len(conds)], cond)
+			args = append(args[:len(args):len(args)], startArgs...)
+		}
+		rows, err := db.DB.QueryContext(ctx, `SELECT `+suggestionColumns+` FROM teian_suggestions
+			WHERE `+strings.Join(conds, " AND ")+`
+			ORDER BY `+strings.Join(orderBy, ", ")+` LIMIT ?`, append(args, queryBatch)...)
+		if err != nil {
+			return err
+		}
+		suggs, err := scanSuggestions(rows)
+		if err != nil {
+			return err
+		}
+		for i := range suggs {
+			k := o.key(&suggs[i])
+			if !fn(k, &suggs[i]) {
+				return nil
+			}
+			start, inclusive = &k, false
+		}
+		if len(suggs) < queryBatch {
+			return nil
+		}
+	}
+}
+
+func (db *SQLStore) Query(ctx context.Context, q teian.Query) (*teian.Page, error) {
+	var start *key
+	if q.Cursor != "" {
+		var err error
+		if start, err = decodeCursor(q.Cursor); err != nil {
+			return nil, err
+		}
+	}
+	desc := queryOrder(q.Order).desc
+	page := &teian.Page{}
+
+	// Collect the page and find the first match after it.
+	err := db.walk(ctx, q, start, desc, true, func(k key, s *teian.Suggestion) bool {
+		if !q.Match(s) {
+			return true
+		}
+		if q.Limit > 0 && len(page.Suggestions) == q.Limit {
+			page.Next = encodeCursor(k)
+			return false
+		}
+		page.Suggestions = append(page.Suggestions, *s)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if start == nil || q.Limit <= 0 {
+		return page, nil
+	}
+
+	// Walk back from the start of this page to find where the previous
+	// one starts.
+	var prev *key
+	n := 0
+	err = db.walk(ctx, q, start, !desc, false, func(k key, s *teian.Suggestion) bool {
+		if q.Match(s) {
+			prev = &k
+			n++
+		}
+		return n < q.Limit
+	})
+	if err != nil {
+		return nil, err
+	}
+	if prev != nil {
+		page.Prev = encodeCursor(*prev)
+	}
+	return page, nil
+}
+
+// Search ranks every suggestion with teian.Search since the texts are not
+// indexed in the database.
+func (db *SQLStore) Search(ctx context.Context, query string) ([]teian.Suggestion, error) {
+	if len(teian.ParseSearch(query).Include) == 0 {
+		return nil, nil
+	}
+	all, err := db.All(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return teian.Search(all, query), nil
+}
@@ -0,0 +1,190 @@
+package sqlstore
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+func TestQuery(t *testing.T) {
+	store, f := setup()
+	defer teardown(store, f)
+	ctx := context.Background()
+
+	// IDs 1-6 alternate between mary and john.
+	for i := 1; i <= 6; i++ {
+		username := "mary"
+		if i%2 == 0 {
+			username = "john"
+		}
+		err := store.Create(ctx, username, &teian.Suggestion{Text: fmt.Sprintf("suggestion #%d", i), Public: i <= 3})
+		if err != nil {
+			t.Fatal("store.Create failed:", err)
+		}
+	}
+	if err := store.SetStatus(ctx, "john", 4, teian.StatusDone, "admin"); err != nil {
+		t.Fatal("store.SetStatus failed:", err)
+	}
+	// Most of the suggestions are private so their owners vote on them.
+	votes := map[uint64]int{2: 1, 3: -1, 5: 1}
+	for id, vote := range votes {
+		username := "mary"
+		if id%2 == 0 {
+			username = "john"
+		}
+		if err := store.Vote(ctx, id, username, vote); err != nil {
+			t.Fatal("store.Vote failed:", err)
+		}
+	}
+
+	tests := []struct {
+		q    teian.Query
+		want []uint64
+	}{
+		{teian.Query{}, []uint64{6, 5, 4, 3, 2, 1}},
+		{teian.Query{Order: teian.OrderDateAsc}, []uint64{1, 2, 3, 4, 5, 6}},
+		{teian.Query{Order: teian.OrderUserAsc}, []uint64{2, 4, 6, 1, 3, 5}},
+		{teian.Query{Order: teian.OrderUserDesc}, []uint64{5, 3, 1, 6, 4, 2}},
+		{teian.Query{Order: teian.OrderVotesAsc}, []uint64{3, 1, 4, 6, 2, 5}},
+		{teian.Query{Order: teian.OrderVotesDesc}, []uint64{5, 2, 6, 4, 1, 3}},
+		{teian.Query{Username: "ar"}, []uint64{5, 3, 1}},
+		{teian.Query{Text: "#2"}, []uint64{2}},
+		{teian.Query{Public: true}, []uint64{3, 2, 1}},
+		{teian.Query{Statuses: []teian.Status{teian.StatusDone}}, []uint64{4}},
+		{teian.Query{Limit: 2}, []uint64{6, 5}},
+	}
+	for _, tt := range tests {
+		page, err := store.Query(ctx, tt.q)
+		if err != nil {
+			t.Fatalf("store.Query(ctx, %+v) failed: %v", tt.q, err)
+		}
+		if got := ids(page.Suggestions); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("store.Query(ctx, %+v) returned IDs %v, want %v", tt.q, got, tt.want)
+		}
+	}
+
+	if _, err := store.Query(ctx, teian.Query{Cursor: "!"}); err != errBadCursor {
+		t.Errorf("store.Query with bad cursor returned %v, want %v", err, errBadCursor)
+	}
+}
+
+func TestQuery_pages(t *testing.T) {
+	store, f := setup()
+	defer teardown(store, f)
+	ctx := context.Background()
+
+	// More suggestions than a batch so that walking needs several reads.
+	n := queryBatch + 20
+	for i := 1; i <= n; i++ {
+		username := fmt.Sprintf("user%d", i%7)
+		if err := store.Create(ctx, username, &teian.Suggestion{Text: fmt.Sprintf("suggestion #%d", i)}); err != nil {
+			t.Fatal("store.Create failed:", err)
+		}
+		if err := store.Vote(ctx, uint64(i), username, i%3-1); err != nil {
+			t.Fatal("store.Vote failed:", err)
+		}
+	}
+
+	orders := []teian.Order{
+		teian.OrderDateDesc, teian.OrderDateAsc,
+		teian.OrderUserDesc, teian.OrderUserAsc,
+		teian.OrderVotesDesc, teian.OrderVotesAsc,
+	}
+	for _, order := range orders {
+		all, err := store.Query(ctx, teian.Query{Order: order})
+		if err != nil {
+			t.Fatalf("store.Query order %q failed: %v", order, err)
+		}
+		if len(all.Suggestions) != n {
+			t.Fatalf("store.Query order %q returned %d suggestions, want %d", order, len(all.Suggestions), n)
+		}
+
+		// Walk forwards page by page and then back.
+		q := teian.Query{Order: order, Limit: 9, Text: "1"}
+		var want []uint64
+		for _, s := range all.Suggestions {
+			if q.Match(&s) {
+				want = append(want, s.ID)
+			}
+		}
+		var got []uint64
+		var pages [][]uint64
+		var cursors []string
+		for {
+			page, err := store.Query(ctx, q)
+			if err != nil {
+				t.Fatalf("store.Query(ctx, %+v) failed: %v", q, err)
+			}
+			cursors = append(cursors, q.Cursor)
+			pages = append(pages, ids(page.Suggestions))
+			got = append(got, ids(page.Suggestions)...)
+			if page.Next == "" {
+				break
+			}
+			q.Cursor = page.Next
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("paging order %q returned IDs %v, want %v", order, got, want)
+		}
+		for i := len(cursors) - 1; i > 0; i-- {
+			q.Cursor = cursors[i]
+			page, err := store.Query(ctx, q)
+			if err != nil {
+				t.Fatalf("store.Query(ctx, %+v) failed: %v", q, err)
+			}
+			q.Cursor = page.Prev
+			prev, err := store.Query(ctx, q)
+			if err != nil {
+				t.Fatalf("store.Query(ctx, %+v) failed: %v", q, err)
+			}
+			if got := ids(prev.Suggestions); !reflect.DeepEqual(got, pages[i-1]) {
+				t.Errorf("order %q page %d has previous page %v, want %v", order, i, got, pages[i-1])
+			}
+		}
+		q.Cursor = ""
+		first, err := store.Query(ctx, q)
+		if err != nil {
+			t.Fatalf("store.Query(ctx, %+v) failed: %v", q, err)
+		}
+		if first.Prev != "" {
+			t.Errorf("order %q first page has prev cursor %q, want none", order, first.Prev)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	store, f := setup()
+	defer teardown(store, f)
+	ctx := context.Background()
+
+	texts := []string{"add a dark theme", "dark mode for the board", "faster uploads"}
+	for _, text := range texts {
+		if err := store.Create(ctx, "john", &teian.Suggestion{Text: text}); err != nil {
+			t.Fatal("store.Create failed:", err)
+		}
+	}
+	if err := store.Delete(ctx, "john", 2, "john"); err != nil {
+		t.Fatal("store.Delete failed:", err)
+	}
+	tests := []struct {
+		query string
+		want  []uint64
+	}{
+		{"dark", []uint64{1}},
+		{"uploads", []uint64{3}},
+		{"-dark", nil},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		found, err := store.Search(ctx, tt.query)
+		if err != nil {
+			t.Fatalf("store.Search(ctx, %q) failed: %v", tt.query, err)
+		}
+		if got := ids(found); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("store.Search(ctx, %q) returned IDs %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
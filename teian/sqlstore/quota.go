@@ -0,0 +1,139 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+// CheckQuota adds an upload of n to those of username within
+// teian.QuotaWindow and returns how much of limit remains. If the user would
+// go over limit nothing is added and teian.ErrOverQuota is returned. The
+// uploads that have left the window are deleted.
+func (db *SQLStore) CheckQuota(ctx context.Context, username string, n, limit teian.Quota) (teian.Quota, error) {
+	var remain teian.Quota
+	err := db.tx(ctx, func(tx *sql.Tx) error {
+		uploads, err := queryUploads(tx, `SELECT at, size FROM teian_quota_uploads WHERE username = ? ORDER BY at`+db.dialect.forUpdate, username)
+		if err != nil {
+			return err
+		}
+		t := now()
+		if len(uploads.Expire(t)) != len(uploads) {
+			_, err = tx.Exec(`DELETE FROM teian_quota_uploads WHERE username = ? AND at <= ?`, username, t.Add(-teian.QuotaWindow))
+			if err != nil {
+				return err
+			}
+		}
+		if _, remain, err = uploads.Add(t, n, limit); err != nil {
+			return err
+		}
+		if n != 0 {
+			_, err = tx.Exec(`INSERT INTO teian_quota_uploads (username, at, size) VALUES (?, ?, ?)`, username, t, int64(n))
+		}
+		return err
+	})
+	return remain, err
+}
+
+// RefundQuota deletes the most recent upload of n of username.
+func (db *SQLStore) RefundQuota(ctx context.Context, username string, n teian.Quota) error {
+	return db.tx(ctx, func(tx *sql.Tx) error {
+		var id int64
+		err := tx.QueryRow(`SELECT id FROM teian_quota_uploads WHERE username = ? AND size = ? ORDER BY id DESC LIMIT 1`, username, int64(n)).Scan(&id)
+		if err == sql.ErrNoRows {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`DELETE FROM teian_quota_uploads WHERE id = ?`, id)
+		return err
+	})
+}
+
+func queryUploads(tx *sql.Tx, query string, args ...interface{}) (teian.Uploads, error) {
+	rows, err := tx.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var uploads teian.Uploads
+	for rows.Next() {
+		var u teian.Upload
+		if err := rows.Scan(&u.At, &u.Size); err != nil {
+			return nil, err
+		}
+		uploads = append(uploads, u)
+	}
+	return uploads, rows.Err()
+}
+
+// QuotaOverride returns the upload quota an admin set for username.
+func (db *SQLStore) QuotaOverride(ctx context.Context, username string) (teian.Quota, error) {
+	var limit int64
+	err := db.DB.QueryRowContext(ctx, `SELECT quota FROM teian_quota_overrides WHERE username = ?`, username).Scan(&limit)
+	if err == sql.ErrNoRows {
+		return 0, teian.ErrNotFound
+	}
+	return teian.Quota(limit), err
+}
+
+// SetQuotaOverride sets the upload quota of username regardless of their
+// class.
+func (db *SQLStore) SetQuotaOverride(ctx context.Context, username string, limit teian.Quota) error {
+	if username == "" || limit < 0 {
+		return teian.Errorf(teian.ErrInvalid, "invalid quota %d for user %q", limit, username)
+	}
+	return db.tx(ctx, func(tx *sql.Tx) error {
+		if _, err := tx.Exec(`DELETE FROM teian_quota_overrides WHERE username = ?`, username); err != nil {
+			return err
+		}
+		_, err := tx.Exec(`INSERT INTO teian_quota_overrides (username, quota) VALUES (?, ?)`, username, int64(limit))
+		return err
+	})
+}
+
+// RemoveQuotaOverride makes username go back to the upload quota of their
+// class.
+func (db *SQLStore) RemoveQuotaOverride(ctx context.Context, username string) error {
+	res, err := db.DB.ExecContext(ctx, `DELETE FROM teian_quota_overrides WHERE username = ?`, username)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return teian.ErrNotFound
+	}
+	return nil
+}
+
+// QuotaUsage returns every user that has used some of their upload quota or
+// has an override, ordered by username.
+func (db *SQLStore) QuotaUsage(ctx context.Context) ([]teian.QuotaUsage, error) {
+	cutoff := now().Add(-teian.QuotaWindow)
+	rows, err := db.DB.QueryContext(ctx, `
+		SELECT u.username, COALESCE(q.used, 0), COALESCE(o.quota, 0), o.username IS NOT NULL
+		FROM (SELECT username FROM teian_quota_uploads WHERE at > ? UNION SELECT username FROM teian_quota_overrides) u
+		LEFT JOIN (
+			SELECT username, SUM(size) AS used FROM teian_quota_uploads WHERE at > ? GROUP BY username
+		) q ON q.username = u.username
+		LEFT JOIN teian_quota_overrides o ON o.username = u.username
+		ORDER BY u.username`, cutoff, cutoff)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var usage []teian.QuotaUsage
+	for rows.Next() {
+		var u teian.QuotaUsage
+		if err := rows.Scan(&u.Username, &u.Used, &u.Limit, &u.Override); err != nil {
+			return nil, err
+		}
+		usage = append(usage, u)
+	}
+	return usage, rows.Err()
+}
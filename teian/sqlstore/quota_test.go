@@ -0,0 +1,103 @@
+package sqlstore
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+func TestQuota(t *testing.T) {
+	store, f := setup()
+	defer teardown(store, f)
+	ctx := context.Background()
+
+	username := "john"
+
+	// add 5 out of 10 MB quota
+	_, err := store.CheckQuota(ctx, username, teian.Quota(5<<20), testQuota)
+	if err != nil {
+		t.Fatal("store.CheckQuota failed:", err)
+	}
+	// add 5 + 2 out of 10 MB quota
+	remain, err := store.CheckQuota(ctx, username, teian.Quota(2<<20), testQuota)
+	if err != nil {
+		t.Fatal("store.CheckQuota failed:", err)
+	}
+	// expect remain to be 3 MB
+	got, want := int64(remain), int64(3<<20)
+	if got != want {
+		t.Fatalf("store.CheckQuota should return remain %v, got %v", want, got)
+	}
+	// try to add 4 MB more while only 3 MB remain
+	_, err = store.CheckQuota(ctx, username, teian.Quota(4<<20), testQuota)
+	if err != teian.ErrOverQuota {
+		t.Error("store.CheckQuota expected to return ErrOverQuota error, got:", err)
+	}
+	// The failed check must not have used any quota.
+	remain, err = store.CheckQuota(ctx, username, 0, testQuota)
+	if err != nil {
+		t.Fatal("store.CheckQuota failed:", err)
+	}
+	if got, want := int64(remain), int64(3<<20); got != want {
+		t.Fatalf("store.CheckQuota after going over should return remain %v, got %v", want, got)
+	}
+
+	// An upload that has left the window no longer counts and is deleted.
+	_, err = store.DB.Exec(`INSERT INTO teian_quota_uploads (username, at, size) VALUES (?, ?, ?)`,
+		"mary", now().Add(-teian.QuotaWindow-time.Minute), testQuota)
+	if err != nil {
+		t.Fatal(err)
+	}
+	remain, err = store.CheckQuota(ctx, "mary", teian.Quota(10<<20), testQuota)
+	if err != nil {
+		t.Fatal("store.CheckQuota failed:", err)
+	}
+	// expect remain to be 0 MB
+	if got, want := int64(remain), int64(0<<20); got != want {
+		t.Fatalf("store.CheckQuota should return remain %v, got %v", want, got)
+	}
+	var n int
+	if err := store.DB.QueryRow(`SELECT COUNT(*) FROM teian_quota_uploads WHERE username = ?`, "mary").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("%d uploads of mary after CheckQuota, want only the new one", n)
+	}
+}
+
+func TestMigrateQuota(t *testing.T) {
+	store, f := setup()
+	defer teardown(store, f)
+
+	// The table of older versions with the quota used today.
+	if _, err := store.DB.Exec(`CREATE TABLE teian_quota (username VARCHAR(255) NOT NULL PRIMARY KEY, used BIGINT NOT NULL)`); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.DB.Exec(`INSERT INTO teian_quota (username, used) VALUES (?, ?), (?, ?)`, "john", 3<<20, "mary", 0); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := store.migrateQuota(); err != nil {
+			t.Fatal("store.migrateQuota failed:", err)
+		}
+	}
+
+	usage, err := store.QuotaUsage(context.Background())
+	if err != nil {
+		t.Fatal("store.QuotaUsage failed:", err)
+	}
+	want := []teian.QuotaUsage{{Username: "john", Used: 3 << 20}}
+	if !reflect.DeepEqual(usage, want) {
+		t.Errorf("store.QuotaUsage after migration = %+v, want %+v", usage, want)
+	}
+	var n int
+	if err := store.DB.QueryRow(store.dialect.hasTable, "teian_quota").Scan(&n); err != nil {
+		t.Fatal(err)
+	}
+	if n != 0 {
+		t.Error("teian_quota was not dropped after migration")
+	}
+}
@@ -0,0 +1,105 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+// The teian_revisions table holds every version of the text of the
+// suggestions numbered from 0 for the text they were created with.
+
+func putRevision(tx *sql.Tx, id uint64, n int, r *teian.Revision) error {
+	_, err := tx.Exec(`INSERT INTO teian_revisions (suggestion_id, n, text, by_user, created) VALUES (?, ?, ?, ?, ?)`,
+		id, n, r.Text, r.By, dbTime(r.At))
+	return err
+}
+
+func getRevisions(tx *sql.Tx, id uint64) ([]teian.Revision, error) {
+	rows, err := tx.Query(`SELECT text, by_user, created FROM teian_revisions WHERE suggestion_id = ? ORDER BY n`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var revs []teian.Revision
+	for rows.Next() {
+		var r teian.Revision
+		if err := rows.Scan(&r.Text, &r.By, &r.At); err != nil {
+			return nil, err
+		}
+		r.At = r.At.UTC()
+		revs = append(revs, r)
+	}
+	return revs, rows.Err()
+}
+
+// setText changes the text of the suggestion with id of username and
+// records the new text as a revision by the given author. An empty username
+// matches any suggestion.
+func (db *SQLStore) setText(tx *sql.Tx, username string, id uint64, text, by string) error {
+	var old teian.Suggestion
+	changed := false
+	err := db.updateSuggestion(tx, username, id, func(s *teian.Suggestion) bool {
+		if s.Text == text {
+			return false
+		}
+		old = *s
+		s.Text = text
+		changed = true
+		return true
+	})
+	if err != nil || !changed {
+		return err
+	}
+	revs, err := getRevisions(tx, id)
+	if err != nil {
+		return err
+	}
+	if len(revs) == 0 {
+		orig := &teian.Revision{Text: old.Text, By: old.Username, At: old.Created}
+		if err := putRevision(tx, id, 0, orig); err != nil {
+			return err
+		}
+		revs = append(revs, *orig)
+	}
+	return putRevision(tx, id, len(revs), &teian.Revision{Text: text, By: by, At: now()})
+}
+
+func (db *SQLStore) Revisions(ctx context.Context, id uint64) ([]teian.Revision, error) {
+	var revs []teian.Revision
+	err := db.tx(ctx, func(tx *sql.Tx) error {
+		s, err := db.getSuggestion(tx, "", id)
+		if err != nil {
+			return err
+		}
+		if revs, err = getRevisions(tx, id); err != nil {
+			return err
+		}
+		if len(revs) == 0 {
+			revs = []teian.Revision{{Text: s.Text, By: s.Username, At: s.Created}}
+		}
+		return nil
+	})
+	return revs, err
+}
+
+func (db *SQLStore) Revert(ctx context.Context, id uint64, revision int, by string) error {
+	return db.tx(ctx, func(tx *sql.Tx) error {
+		s, err := db.getSuggestion(tx, "", id)
+		if err != nil {
+			return err
+		}
+		revs, err := getRevisions(tx, id)
+		if err != nil {
+			return err
+		}
+		if len(revs) == 0 {
+			revs = []teian.Revision{{Text: s.Text}}
+		}
+		if revision < 0 || revision >= len(revs) {
+			return teian.ErrNotFound
+		}
+		return db.setText(tx, "", id, revs[revision].Text, by)
+	})
+}
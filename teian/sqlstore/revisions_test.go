@@ -0,0 +1,63 @@
+package sqlstore
+
+import (
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+func revisionTexts(revs []teian.Revision) []string {
+	var texts []string
+	for _, r := range revs {
+		texts = append(texts, r.Text)
+	}
+	return texts
+}
+
+func TestRevisions(t *testing.T) {
+	store, f := setup()
+	defer teardown(store, f)
+	ctx := context.Background()
+
+	if err := store.Create(ctx, "john", &teian.Suggestion{Text: "one"}); err != nil {
+		t.Fatal("store.Create failed:", err)
+	}
+	if err := store.Edit(ctx, "john", 1, "two"); err != nil {
+		t.Fatal("store.Edit failed:", err)
+	}
+	if err := store.Edit(ctx, "john", 1, "two"); err != nil {
+		t.Fatal("store.Edit without changes failed:", err)
+	}
+	if err := store.Edit(ctx, "mary", 1, "three"); err != teian.ErrNotFound {
+		t.Errorf("store.Edit of suggestion of other user returned %v, want %v", err, teian.ErrNotFound)
+	}
+	if err := store.Revert(ctx, 1, 0, "admin"); err != nil {
+		t.Fatal("store.Revert failed:", err)
+	}
+	if err := store.Revert(ctx, 1, 5, "admin"); err != teian.ErrNotFound {
+		t.Errorf("store.Revert to missing revision returned %v, want %v", err, teian.ErrNotFound)
+	}
+
+	revs, err := store.Revisions(ctx, 1)
+	if err != nil {
+		t.Fatal("store.Revisions failed:", err)
+	}
+	if got, want := revisionTexts(revs), []string{"one", "two", "one"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("store.Revisions texts = %q, want %q", got, want)
+	}
+	if revs[2].By != "admin" {
+		t.Errorf("reverted revision by %q, want admin", revs[2].By)
+	}
+	s, err := store.Get(ctx, 1)
+	if err != nil {
+		t.Fatal("store.Get failed:", err)
+	}
+	if s.Text != "one" {
+		t.Errorf("text after revert = %q, want %q", s.Text, "one")
+	}
+	if !revs[0].At.Equal(s.Created) {
+		t.Errorf("first revision at %v, want the creation time %v", revs[0].At, s.Created)
+	}
+}
@@ -0,0 +1,400 @@
+// Package sqlstore implements teian.SuggestionStore over database/sql. It
+// supports MySQL and SQLite. The drivers are not imported by this package;
+// programs that use it must import the driver they need.
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+// The suggestions are stored one per row in teian_suggestions. Their
+// scalar fields have their own columns while the history, replies, tags,
+// merged IDs and attachments are JSON encoded. Suggestions in the trash
+// have a deleted_at time and are left out of every method but the trash
+// ones.
+//
+// IDs are taken from the sequence row of teian_meta instead of an auto
+// increment column so that they are never reused and imports can advance
+// the sequence like with Boltstore.
+
+// dialect holds what differs between the supported databases.
+type dialect struct {
+	schema []string
+	// forUpdate locks the rows selected in a transaction that will be
+	// updated. SQLite locks the whole database on write instead.
+	forUpdate string
+	// hasTable counts the tables named by its single argument.
+	hasTable string
+}
+
+var dialects = map[string]dialect{
+	"mysql": {
+		schema: []string{
+			`CREATE TABLE IF NOT EXISTS teian_meta (
+				name VARCHAR(64) NOT NULL PRIMARY KEY,
+				value BIGINT NOT NULL
+			) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin`,
+			`CREATE TABLE IF NOT EXISTS teian_suggestions (
+				id BIGINT UNSIGNED NOT NULL PRIMARY KEY,
+				username VARCHAR(255) NOT NULL,
+				text TEXT NOT NULL,
+				created DATETIME(6) NOT NULL,
+				status INT NOT NULL,
+				public BOOLEAN NOT NULL,
+				anonymous BOOLEAN NOT NULL,
+				category VARCHAR(255) NOT NULL,
+				upvotes INT NOT NULL,
+				downvotes INT NOT NULL,
+				merged_into BIGINT UNSIGNED NOT NULL,
+				history MEDIUMTEXT NOT NULL,
+				replies MEDIUMTEXT NOT NULL,
+				tags TEXT NOT NULL,
+				merged TEXT NOT NULL,
+				attachments TEXT NOT NULL,
+				deleted_by VARCHAR(255) NOT NULL,
+				deleted_at DATETIME(6) NULL,
+				INDEX teian_suggestions_username (username),
+				INDEX teian_suggestions_category (category)
+			) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin`,
+			`CREATE TABLE IF NOT EXISTS teian_votes (
+				username VARCHAR(255) NOT NULL,
+				suggestion_id BIGINT UNSIGNED NOT NULL,
+				vote INT NOT NULL,
+				PRIMARY KEY (username, suggestion_id),
+				INDEX teian_votes_suggestion (suggestion_id)
+			) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin`,
+			`CREATE TABLE IF NOT EXISTS teian_categories (
+				name VARCHAR(255) NOT NULL PRIMARY KEY
+			) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin`,
+			`CREATE TABLE IF NOT EXISTS teian_revisions (
+				suggestion_id BIGINT UNSIGNED NOT NULL,
+				n INT NOT NULL,
+				text TEXT NOT NULL,
+				by_user VARCHAR(255) NOT NULL,
+				created DATETIME(6) NOT NULL,
+				PRIMARY KEY (suggestion_id, n)
+			) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin`,
+			`CREATE TABLE IF NOT EXISTS teian_quota_uploads (
+				id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+				username VARCHAR(255) NOT NULL,
+				at DATETIME(6) NOT NULL,
+				size BIGINT NOT NULL,
+				INDEX teian_quota_uploads_username (username, at)
+			) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin`,
+			`CREATE TABLE IF NOT EXISTS teian_quota_overrides (
+				username VARCHAR(255) NOT NULL PRIMARY KEY,
+				quota BIGINT NOT NULL
+			) DEFAULT CHARSET=utf8mb4 COLLATE=utf8mb4_bin`,
+		},
+		forUpdate: " FOR UPDATE",
+		hasTable:  `SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = DATABASE() AND table_name = ?`,
+	},
+	"sqlite3": {
+		schema: []string{
+			`CREATE TABLE IF NOT EXISTS teian_meta (
+				name TEXT NOT NULL PRIMARY KEY,
+				value INTEGER NOT NULL
+			)`,
+			`CREATE TABLE IF NOT EXISTS teian_suggestions (
+				id INTEGER NOT NULL PRIMARY KEY,
+				username TEXT NOT NULL,
+				text TEXT NOT NULL,
+				created TIMESTAMP NOT NULL,
+				status INTEGER NOT NULL,
+				public BOOLEAN NOT NULL,
+				anonymous BOOLEAN NOT NULL,
+				category TEXT NOT NULL,
+				upvotes INTEGER NOT NULL,
+				downvotes INTEGER NOT NULL,
+				merged_into INTEGER NOT NULL,
+				history TEXT NOT NULL,
+				replies TEXT NOT NULL,
+				tags TEXT NOT NULL,
+				merged TEXT NOT NULL,
+				attachments TEXT NOT NULL,
+				deleted_by TEXT NOT NULL,
+				deleted_at TIMESTAMP NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS teian_suggestions_username ON teian_suggestions (username)`,
+			`CREATE INDEX IF NOT EXISTS teian_suggestions_category ON teian_suggestions (category)`,
+			`CREATE TABLE IF NOT EXISTS teian_votes (
+				username TEXT NOT NULL,
+				suggestion_id INTEGER NOT NULL,
+				vote INTEGER NOT NULL,
+				PRIMARY KEY (username, suggestion_id)
+			)`,
+			`CREATE INDEX IF NOT EXISTS teian_votes_suggestion ON teian_votes (suggestion_id)`,
+			`CREATE TABLE IF NOT EXISTS teian_categories (
+				name TEXT NOT NULL PRIMARY KEY
+			)`,
+			`CREATE TABLE IF NOT EXISTS teian_revisions (
+				suggestion_id INTEGER NOT NULL,
+				n INTEGER NOT NULL,
+				text TEXT NOT NULL,
+				by_user TEXT NOT NULL,
+				created TIMESTAMP NOT NULL,
+				PRIMARY KEY (suggestion_id, n)
+			)`,
+			`CREATE TABLE IF NOT EXISTS teian_quota_uploads (
+				id INTEGER NOT NULL PRIMARY KEY,
+				username TEXT NOT NULL,
+				at TIMESTAMP NOT NULL,
+				size INTEGER NOT NULL
+			)`,
+			`CREATE INDEX IF NOT EXISTS teian_quota_uploads_username ON teian_quota_uploads (username, at)`,
+			`CREATE TABLE IF NOT EXISTS teian_quota_overrides (
+				username TEXT NOT NULL PRIMARY KEY,
+				quota INTEGER NOT NULL
+			)`,
+		},
+		hasTable: `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`,
+	},
+}
+
+type SQLStore struct {
+	DB      *sql.DB
+	dialect dialect
+}
+
+// NewSuggestionStore opens a database connection for the given driver,
+// either mysql or sqlite3, and configuration and returns an implementation
+// of teian.SuggestionStore. The tables are created if they do not exist.
+// MySQL configurations must have parseTime=true.
+func NewSuggestionStore(driver, config string) *SQLStore {
+	db, err := sql.Open(driver, config)
+	if err != nil {
+		log.Fatalln("database connection failed:", err)
+	}
+	store, err := New(db, driver)
+	if err != nil {
+		log.Fatalln("database setup failed:", err)
+	}
+	return store
+}
+
+// New returns an implementation of teian.SuggestionStore that uses db
+// which was opened with driver. The tables are created if they do not
+// exist.
+func New(db *sql.DB, driver string) (*SQLStore, error) {
+	d, ok := dialects[driver]
+	if !ok {
+		return nil, fmt.Errorf("unsupported driver %q", driver)
+	}
+	if driver == "sqlite3" {
+		// SQLite allows a single writer so transactions are serialized
+		// here rather than failing with "database is locked".
+		db.SetMaxOpenConns(1)
+	}
+	for _, stmt := range d.schema {
+		if _, err := db.Exec(stmt); err != nil {
+			return nil, fmt.Errorf("create schema: %v", err)
+		}
+	}
+	store := &SQLStore{db, d}
+	err := store.tx(context.Background(), func(tx *sql.Tx) error {
+		var seq uint64
+		err := tx.QueryRow(`SELECT value FROM teian_meta WHERE name = 'sequence'`).Scan(&seq)
+		if err == sql.ErrNoRows {
+			_, err = tx.Exec(`INSERT INTO teian_meta (name, value) VALUES ('sequence', 0)`)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create sequence: %v", err)
+	}
+	if err := store.migrateQuota(); err != nil {
+		return nil, fmt.Errorf("migrate quota: %v", err)
+	}
+	return store, nil
+}
+
+// migrateQuota converts the teian_quota table of older versions, which kept
+// a running total per user reset every day, into uploads made now so that
+// the quota used so far still counts for a whole window like with
+// Boltstore. The rows are deleted as they are converted and the table is
+// dropped afterwards since MySQL cannot drop it inside the transaction.
+func (db *SQLStore) migrateQuota() error {
+	var n int
+	if err := db.DB.QueryRow(db.dialect.hasTable, "teian_quota").Scan(&n); err != nil {
+		return err
+	}
+	if n == 0 {
+		return nil
+	}
+	err := db.tx(context.Background(), func(tx *sql.Tx) error {
+		_, err := tx.Exec(`INSERT INTO teian_quota_uploads (username, at, size)
+			SELECT username, ?, used FROM teian_quota WHERE used <> 0`, now())
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(`DELETE FROM teian_quota`)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	_, err = db.DB.Exec(`DROP TABLE teian_quota`)
+	return err
+}
+
+// Close releases all database resources.
+func (db *SQLStore) Close() {
+	if err := db.DB.Close(); err != nil {
+		log.Println("database close failed:", err)
+	}
+}
+
+// tx runs fn in a transaction which is committed if fn returns nil and
+// rolled back otherwise. The transaction is also rolled back if ctx is done
+// before it commits.
+func (db *SQLStore) tx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// now returns the current time at the precision the databases store.
+func now() time.Time {
+	return dbTime(time.Now())
+}
+
+// dbTime returns t in UTC truncated to microseconds, the precision of
+// MySQL, so that a suggestion stored and read back is the same.
+func dbTime(t time.Time) time.Time {
+	return t.UTC().Truncate(time.Microsecond)
+}
+
+// nextID returns the next value of the ID sequence.
+func nextID(tx *sql.Tx) (uint64, error) {
+	if _, err := tx.Exec(`UPDATE teian_meta SET value = value + 1 WHERE name = 'sequence'`); err != nil {
+		return 0, err
+	}
+	var id uint64
+	err := tx.QueryRow(`SELECT value FROM teian_meta WHERE name = 'sequence'`).Scan(&id)
+	return id, err
+}
+
+const suggestionColumns = `id, username, text, created, status, public, anonymous, category,
+	upvotes, downvotes, merged_into, history, replies, tags, merged, attachments,
+	deleted_by, deleted_at`
+
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanSuggestion(row scanner) (*teian.Suggestion, error) {
+	s := new(teian.Suggestion)
+	var history, replies, tags, merged, attachments string
+	var deletedAt sql.NullTime
+	err := row.Scan(&s.ID, &s.Username, &s.Text, &s.Created, &s.Status, &s.Public, &s.Anonymous, &s.Category,
+		&s.Upvotes, &s.Downvotes, &s.MergedInto, &history, &replies, &tags, &merged, &attachments,
+		&s.DeletedBy, &deletedAt)
+	if err != nil {
+		return nil, err
+	}
+	s.Created = s.Created.UTC()
+	if deletedAt.Valid {
+		s.DeletedAt = deletedAt.Time.UTC()
+	}
+	for _, f := range []struct {
+		data string
+		v    interface{}
+	}{
+		{history, &s.History},
+		{replies, &s.Replies},
+		{tags, &s.Tags},
+		{merged, &s.Merged},
+		{attachments, &s.Attachments},
+	} {
+		if err := json.Unmarshal([]byte(f.data), f.v); err != nil {
+			return nil, fmt.Errorf("could not decode suggestion %d: %v", s.ID, err)
+		}
+	}
+	// Empty lists are returned as nil like the gob encoding of Boltstore
+	// does.
+	if len(s.History) == 0 {
+		s.History = nil
+	}
+	if len(s.Replies) == 0 {
+		s.Replies = nil
+	}
+	if len(s.Tags) == 0 {
+		s.Tags = nil
+	}
+	if len(s.Merged) == 0 {
+		s.Merged = nil
+	}
+	if len(s.Attachments) == 0 {
+		s.Attachments = nil
+	}
+	return s, nil
+}
+
+func scanSuggestions(rows *sql.Rows) ([]teian.Suggestion, error) {
+	defer rows.Close()
+	var suggs []teian.Suggestion
+	for rows.Next() {
+		s, err := scanSuggestion(rows)
+		if err != nil {
+			return nil, err
+		}
+		suggs = append(suggs, *s)
+	}
+	return suggs, rows.Err()
+}
+
+// suggestionValues returns the values of the columns of s in the order of
+// suggestionColumns.
+func suggestionValues(s *teian.Suggestion) ([]interface{}, error) {
+	values := []interface{}{s.ID, s.Username, s.Text, dbTime(s.Created), s.Status, s.Public, s.Anonymous, s.Category,
+		s.Upvotes, s.Downvotes, s.MergedInto}
+	for _, v := range []interface{}{s.History, s.Replies, s.Tags, s.Merged, s.Attachments} {
+		data, err := json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("could not encode suggestion: %v", err)
+		}
+		values = append(values, string(data))
+	}
+	var deletedAt sql.NullTime
+	if !s.DeletedAt.IsZero() {
+		deletedAt = sql.NullTime{Time: dbTime(s.DeletedAt), Valid: true}
+	}
+	return append(values, s.DeletedBy, deletedAt), nil
+}
+
+// insertSuggestion stores a new suggestion s.
+func insertSuggestion(tx *sql.Tx, s *teian.Suggestion) error {
+	values, err := suggestionValues(s)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`INSERT INTO teian_suggestions (`+suggestionColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`, values...)
+	return err
+}
+
+// putSuggestion stores the changes of the existing suggestion s.
+func putSuggestion(tx *sql.Tx, s *teian.Suggestion) error {
+	values, err := suggestionValues(s)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`UPDATE teian_suggestions SET username = ?, text = ?, created = ?, status = ?,
+		public = ?, anonymous = ?, category = ?, upvotes = ?, downvotes = ?, merged_into = ?,
+		history = ?, replies = ?, tags = ?, merged = ?, attachments = ?, deleted_by = ?, deleted_at = ?
+		WHERE id = ?`, append(values[1:], s.ID)...)
+	return err
+}
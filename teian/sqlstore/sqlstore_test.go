@@ -0,0 +1,287 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+	"github.com/kusubooru/teian/teian"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+const testQuota = 10 << 20 // 10 MB
+
+// The tests run on a temporary SQLite database. If TEIAN_MYSQL_DSN is set,
+// e.g. to "user:pass@(localhost:3306)/teian_test?parseTime=true", they run
+// on that MySQL database instead. Its teian tables are dropped by every
+// test.
+var mysqlDSN = os.Getenv("TEIAN_MYSQL_DSN")
+
+var tables = []string{"teian_meta", "teian_suggestions", "teian_votes", "teian_categories", "teian_revisions", "teian_quota", "teian_quota_uploads", "teian_quota_overrides"}
+
+func setup() (*SQLStore, string) {
+	if mysqlDSN != "" {
+		db, err := sql.Open("mysql", mysqlDSN)
+		if err != nil {
+			log.Fatal("mysql open failed:", err)
+		}
+		for _, table := range tables {
+			if _, err := db.Exec("DROP TABLE IF EXISTS " + table); err != nil {
+				log.Fatal("could not drop test table:", err)
+			}
+		}
+		store, err := New(db, "mysql")
+		if err != nil {
+			log.Fatal("could not create sql store:", err)
+		}
+		return store, ""
+	}
+	f, err := ioutil.TempFile("", "teian_sqlite_tmpfile_")
+	if err != nil {
+		log.Fatal("could not create sqlite temp file for tests:", err)
+	}
+	f.Close()
+	return NewSuggestionStore("sqlite3", f.Name()), f.Name()
+}
+
+func teardown(store *SQLStore, tmpfile string) {
+	store.Close()
+	if tmpfile == "" {
+		return
+	}
+	if err := os.Remove(tmpfile); err != nil {
+		log.Println("could not remove sqlite temp file:", err)
+	}
+}
+
+func ids(suggs []teian.Suggestion) []uint64 {
+	var ids []uint64
+	for _, s := range suggs {
+		ids = append(ids, s.ID)
+	}
+	return ids
+}
+
+func TestNew_existingSchema(t *testing.T) {
+	store, f := setup()
+	defer teardown(store, f)
+	ctx := context.Background()
+
+	if err := store.Create(ctx, "john", &teian.Suggestion{Text: "one"}); err != nil {
+		t.Fatal("store.Create failed:", err)
+	}
+	// Opening an existing database keeps its data and sequence.
+	driver := "sqlite3"
+	if mysqlDSN != "" {
+		driver = "mysql"
+	}
+	again, err := New(store.DB, driver)
+	if err != nil {
+		t.Fatal("New on existing schema failed:", err)
+	}
+	sugg := &teian.Suggestion{Text: "two"}
+	if err := again.Create(ctx, "john", sugg); err != nil {
+		t.Fatal("store.Create failed:", err)
+	}
+	if sugg.ID != 2 {
+		t.Errorf("store.Create after New on existing schema assigned ID %d, want 2", sugg.ID)
+	}
+}
+
+func TestNew_unsupportedDriver(t *testing.T) {
+	if _, err := New(nil, "postgres"); err == nil {
+		t.Error("New with unsupported driver expected to return error")
+	}
+}
+
+func TestCreate_Get(t *testing.T) {
+	store, f := setup()
+	defer teardown(store, f)
+	ctx := context.Background()
+
+	if err := store.AddCategory(ctx, "ui"); err != nil {
+		t.Fatal("store.AddCategory failed:", err)
+	}
+	sugg := &teian.Suggestion{
+		Text:        "my first suggestion",
+		Public:      true,
+		Anonymous:   true,
+		Category:    "ui",
+		Tags:        []string{"cat", "dog"},
+		Attachments: []teian.Attachment{{Name: "a.png", Thumb: "a_thumb.png", ContentType: "image/png", Size: 42}},
+	}
+	if err := store.Create(ctx, "john", sugg); err != nil {
+		t.Fatal("store.Create failed:", err)
+	}
+	if err := store.SetStatus(ctx, "john", 1, teian.StatusPlanned, "admin"); err != nil {
+		t.Fatal("store.SetStatus failed:", err)
+	}
+	if err := store.AddReply(ctx, "john", 1, &teian.Reply{Username: "admin", Text: "thanks"}); err != nil {
+		t.Fatal("store.AddReply failed:", err)
+	}
+	if err := store.SetStatus(ctx, "mary", 1, teian.StatusDone, "admin"); err != teian.ErrNotFound {
+		t.Errorf("store.SetStatus on suggestion of other user returned %v, want %v", err, teian.ErrNotFound)
+	}
+
+	got, err := store.Get(ctx, 1)
+	if err != nil {
+		t.Fatal("store.Get failed:", err)
+	}
+	if got.Created.IsZero() || !got.Created.Equal(sugg.Created) {
+		t.Errorf("store.Get returned created %v, want %v", got.Created, sugg.Created)
+	}
+	if len(got.History) != 1 || len(got.Replies) != 1 {
+		t.Fatalf("store.Get returned history %v and replies %v, want one of each", got.History, got.Replies)
+	}
+	want := teian.Suggestion{
+		ID:          1,
+		Username:    "john",
+		Text:        "my first suggestion",
+		Created:     sugg.Created,
+		Status:      teian.StatusPlanned,
+		History:     []teian.StatusChange{{From: teian.StatusNew, To: teian.StatusPlanned, By: "admin", At: got.History[0].At}},
+		Replies:     []teian.Reply{{Username: "admin", Text: "thanks", Created: got.Replies[0].Created}},
+		Public:      true,
+		Anonymous:   true,
+		Category:    "ui",
+		Tags:        []string{"cat", "dog"},
+		Attachments: sugg.Attachments,
+	}
+	if !reflect.DeepEqual(*got, want) {
+		t.Errorf("store.Get returned \n%#v, want \n%#v", *got, want)
+	}
+
+	if _, err := store.Get(ctx, 2); err != teian.ErrNotFound {
+		t.Errorf("store.Get of missing suggestion returned %v, want %v", err, teian.ErrNotFound)
+	}
+	if err := store.Create(ctx, "john", &teian.Suggestion{Category: "nope"}); err != teian.ErrUnknownCategory {
+		t.Errorf("store.Create with unknown category returned %v, want %v", err, teian.ErrUnknownCategory)
+	}
+}
+
+func TestOfUser_All(t *testing.T) {
+	store, f := setup()
+	defer teardown(store, f)
+	ctx := context.Background()
+
+	for i := 1; i <= 4; i++ {
+		username := "mary"
+		if i%2 == 0 {
+			username = "john"
+		}
+		if err := store.Create(ctx, username, &teian.Suggestion{Text: fmt.Sprintf("suggestion #%d", i)}); err != nil {
+			t.Fatal("store.Create failed:", err)
+		}
+	}
+	if err := store.Delete(ctx, "john", 4, "john"); err != nil {
+		t.Fatal("store.Delete failed:", err)
+	}
+	if err := store.Delete(ctx, "john", 3, "john"); err != teian.ErrNotFound {
+		t.Errorf("store.Delete of suggestion of other user returned %v, want %v", err, teian.ErrNotFound)
+	}
+
+	john, err := store.OfUser(ctx, "john")
+	if err != nil {
+		t.Fatal("store.OfUser failed:", err)
+	}
+	if got, want := ids(john), []uint64{2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("store.OfUser returned IDs %v, want %v", got, want)
+	}
+	all, err := store.All(ctx)
+	if err != nil {
+		t.Fatal("store.All failed:", err)
+	}
+	if got, want := ids(all), []uint64{1, 2, 3}; !reflect.DeepEqual(got, want) {
+		t.Errorf("store.All returned IDs %v, want %v", got, want)
+	}
+}
+
+func TestTrash(t *testing.T) {
+	store, f := setup()
+	defer teardown(store, f)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if err := store.Create(ctx, "john", &teian.Suggestion{Text: "text", Public: true}); err != nil {
+			t.Fatal("store.Create failed:", err)
+		}
+	}
+	if err := store.Vote(ctx, 1, "mary", 1); err != nil {
+		t.Fatal("store.Vote failed:", err)
+	}
+	for _, id := range []uint64{1, 2} {
+		if err := store.Delete(ctx, "", id, "admin"); err != nil {
+			t.Fatal("store.Delete failed:", err)
+		}
+		// Make sure the deletion times differ.
+		time.Sleep(time.Millisecond)
+	}
+
+	trash, err := store.Trash(ctx)
+	if err != nil {
+		t.Fatal("store.Trash failed:", err)
+	}
+	if got, want := ids(trash), []uint64{2, 1}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("store.Trash returned IDs %v, want %v", got, want)
+	}
+	if trash[0].DeletedBy != "admin" || trash[0].DeletedAt.IsZero() {
+		t.Errorf("store.Trash returned deleted by %q at %v, want admin and a time", trash[0].DeletedBy, trash[0].DeletedAt)
+	}
+	if _, err := store.Get(ctx, 1); err != teian.ErrNotFound {
+		t.Errorf("store.Get of deleted suggestion returned %v, want %v", err, teian.ErrNotFound)
+	}
+
+	if err := store.Restore(ctx, 1); err != nil {
+		t.Fatal("store.Restore failed:", err)
+	}
+	restored, err := store.Get(ctx, 1)
+	if err != nil {
+		t.Fatal("store.Get of restored suggestion failed:", err)
+	}
+	if restored.DeletedBy != "" || !restored.DeletedAt.IsZero() || restored.Upvotes != 1 {
+		t.Errorf("store.Restore lead to %#v, want no deletion and its vote", restored)
+	}
+	if err := store.Restore(ctx, 1); err != teian.ErrNotFound {
+		t.Errorf("store.Restore of restored suggestion returned %v, want %v", err, teian.ErrNotFound)
+	}
+
+	if err := store.Purge(ctx, 3); err != teian.ErrNotFound {
+		t.Errorf("store.Purge of suggestion not in the trash returned %v, want %v", err, teian.ErrNotFound)
+	}
+	if err := store.Delete(ctx, "", 1, "admin"); err != nil {
+		t.Fatal("store.Delete failed:", err)
+	}
+	if err := store.Purge(ctx, 1); err != nil {
+		t.Fatal("store.Purge failed:", err)
+	}
+	votes, err := store.VotesOf(ctx, "mary")
+	if err != nil {
+		t.Fatal("store.VotesOf failed:", err)
+	}
+	if len(votes) != 0 {
+		t.Errorf("store.VotesOf after purge returned %v, want none", votes)
+	}
+	trash, err = store.Trash(ctx)
+	if err != nil {
+		t.Fatal("store.Trash failed:", err)
+	}
+	if got, want := ids(trash), []uint64{2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("store.Trash after purge returned IDs %v, want %v", got, want)
+	}
+
+	// IDs of purged suggestions are not reused.
+	sugg := &teian.Suggestion{Text: "new"}
+	if err := store.Create(ctx, "john", sugg); err != nil {
+		t.Fatal("store.Create failed:", err)
+	}
+	if sugg.ID != 4 {
+		t.Errorf("store.Create after purge assigned ID %d, want 4", sugg.ID)
+	}
+}
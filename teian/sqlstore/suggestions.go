@@ -0,0 +1,106 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+// getSuggestion returns the suggestion with id, locking it if the
+// transaction is going to update it. If username is not empty then the
+// suggestion must also belong to username.
+func (db *SQLStore) getSuggestion(tx *sql.Tx, username string, id uint64) (*teian.Suggestion, error) {
+	s, err := scanSuggestion(tx.QueryRow(`SELECT `+suggestionColumns+` FROM teian_suggestions
+		WHERE id = ? AND deleted_at IS NULL`+db.dialect.forUpdate, id))
+	if err == sql.ErrNoRows || err == nil && username != "" && s.Username != username {
+		return nil, teian.ErrNotFound
+	}
+	return s, err
+}
+
+func (db *SQLStore) Create(ctx context.Context, username string, sugg *teian.Suggestion) error {
+	return db.tx(ctx, func(tx *sql.Tx) error {
+		if err := checkCategory(tx, sugg.Category); err != nil {
+			return err
+		}
+		id, err := nextID(tx)
+		if err != nil {
+			return err
+		}
+		sugg.ID = id
+		sugg.Username = username
+		sugg.Created = now()
+		if err := insertSuggestion(tx, sugg); err != nil {
+			return err
+		}
+		return putRevision(tx, id, 0, &teian.Revision{Text: sugg.Text, By: username, At: sugg.Created})
+	})
+}
+
+func (db *SQLStore) Get(ctx context.Context, id uint64) (*teian.Suggestion, error) {
+	s, err := scanSuggestion(db.DB.QueryRowContext(ctx, `SELECT `+suggestionColumns+` FROM teian_suggestions
+		WHERE id = ? AND deleted_at IS NULL`, id))
+	if err == sql.ErrNoRows {
+		return nil, teian.ErrNotFound
+	}
+	return s, err
+}
+
+func (db *SQLStore) OfUser(ctx context.Context, username string) ([]teian.Suggestion, error) {
+	rows, err := db.DB.QueryContext(ctx, `SELECT `+suggestionColumns+` FROM teian_suggestions
+		WHERE username = ? AND deleted_at IS NULL ORDER BY id`, username)
+	if err != nil {
+		return nil, err
+	}
+	return scanSuggestions(rows)
+}
+
+func (db *SQLStore) All(ctx context.Context) ([]teian.Suggestion, error) {
+	rows, err := db.DB.QueryContext(ctx, `SELECT `+suggestionColumns+` FROM teian_suggestions
+		WHERE deleted_at IS NULL ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	return scanSuggestions(rows)
+}
+
+func (db *SQLStore) SetStatus(ctx context.Context, username string, id uint64, status teian.Status, by string) error {
+	return db.update(ctx, username, id, func(s *teian.Suggestion) bool {
+		return s.SetStatus(status, by, now())
+	})
+}
+
+func (db *SQLStore) Edit(ctx context.Context, username string, id uint64, text string) error {
+	return db.tx(ctx, func(tx *sql.Tx) error {
+		return db.setText(tx, username, id, text, username)
+	})
+}
+
+func (db *SQLStore) AddReply(ctx context.Context, username string, id uint64, reply *teian.Reply) error {
+	return db.update(ctx, username, id, func(s *teian.Suggestion) bool {
+		reply.Created = now()
+		s.Replies = append(s.Replies, *reply)
+		return true
+	})
+}
+
+// update runs updateSuggestion in its own transaction.
+func (db *SQLStore) update(ctx context.Context, username string, id uint64, fn func(*teian.Suggestion) bool) error {
+	return db.tx(ctx, func(tx *sql.Tx) error {
+		return db.updateSuggestion(tx, username, id, fn)
+	})
+}
+
+// updateSuggestion finds the suggestion with id of username and calls fn to
+// modify it. The changes are stored only if fn returns true.
+func (db *SQLStore) updateSuggestion(tx *sql.Tx, username string, id uint64, fn func(*teian.Suggestion) bool) error {
+	s, err := db.getSuggestion(tx, username, id)
+	if err != nil {
+		return err
+	}
+	if !fn(s) {
+		return nil
+	}
+	return putSuggestion(tx, s)
+}
@@ -0,0 +1,96 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+	"sort"
+	"time"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+// Deleted suggestions stay in teian_suggestions with their deleted_at set.
+// Their revisions and votes are kept until they are purged.
+
+func (db *SQLStore) Delete(ctx context.Context, username string, id uint64, by string) error {
+	return db.tx(ctx, func(tx *sql.Tx) error {
+		return db.trashSuggestion(tx, username, id, by)
+	})
+}
+
+// trashSuggestion moves the suggestion with id of username to the trash. An
+// empty username matches any suggestion.
+func (db *SQLStore) trashSuggestion(tx *sql.Tx, username string, id uint64, by string) error {
+	s, err := db.getSuggestion(tx, username, id)
+	if err != nil {
+		return err
+	}
+	s.DeletedBy = by
+	s.DeletedAt = now()
+	return putSuggestion(tx, s)
+}
+
+func (db *SQLStore) Trash(ctx context.Context) ([]teian.Suggestion, error) {
+	rows, err := db.DB.QueryContext(ctx, `SELECT `+suggestionColumns+` FROM teian_suggestions
+		WHERE deleted_at IS NOT NULL ORDER BY id`)
+	if err != nil {
+		return nil, err
+	}
+	suggs, err := scanSuggestions(rows)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(suggs, func(i, j int) bool {
+		return suggs[i].DeletedAt.After(suggs[j].DeletedAt)
+	})
+	return suggs, nil
+}
+
+// getTrashed returns the deleted suggestion with id.
+func (db *SQLStore) getTrashed(tx *sql.Tx, id uint64) (*teian.Suggestion, error) {
+	s, err := scanSuggestion(tx.QueryRow(`SELECT `+suggestionColumns+` FROM teian_suggestions
+		WHERE id = ? AND deleted_at IS NOT NULL`+db.dialect.forUpdate, id))
+	if err == sql.ErrNoRows {
+		return nil, teian.ErrNotFound
+	}
+	return s, err
+}
+
+func (db *SQLStore) Trashed(ctx context.Context, id uint64) (*teian.Suggestion, error) {
+	s, err := scanSuggestion(db.DB.QueryRowContext(ctx, `SELECT `+suggestionColumns+` FROM teian_suggestions
+		WHERE id = ? AND deleted_at IS NOT NULL`, id))
+	if err == sql.ErrNoRows {
+		return nil, teian.ErrNotFound
+	}
+	return s, err
+}
+
+func (db *SQLStore) Restore(ctx context.Context, id uint64) error {
+	return db.tx(ctx, func(tx *sql.Tx) error {
+		s, err := db.getTrashed(tx, id)
+		if err != nil {
+			return err
+		}
+		s.DeletedBy = ""
+		s.DeletedAt = time.Time{}
+		return putSuggestion(tx, s)
+	})
+}
+
+func (db *SQLStore) Purge(ctx context.Context, id uint64) error {
+	return db.tx(ctx, func(tx *sql.Tx) error {
+		if _, err := db.getTrashed(tx, id); err != nil {
+			return err
+		}
+		for _, stmt := range []string{
+			`DELETE FROM teian_revisions WHERE suggestion_id = ?`,
+			`DELETE FROM teian_votes WHERE suggestion_id = ?`,
+			`DELETE FROM teian_suggestions WHERE id = ?`,
+		} {
+			if _, err := tx.Exec(stmt, id); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,83 @@
+package sqlstore
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/kusubooru/teian/teian"
+)
+
+// The teian_votes table holds the vote of each user on each suggestion. The
+// vote counts of the suggestions are kept in their rows as well so that
+// queries can order by them.
+
+// getVote returns the vote of username on suggestion id or 0 if they have
+// not voted.
+func getVote(tx *sql.Tx, username string, id uint64) (int, error) {
+	var vote int
+	err := tx.QueryRow(`SELECT vote FROM teian_votes WHERE username = ? AND suggestion_id = ?`, username, id).Scan(&vote)
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+	return vote, err
+}
+
+// putVote sets the vote of username on suggestion id that was old before.
+// A vote of 0 removes it.
+func putVote(tx *sql.Tx, username string, id uint64, old, vote int) error {
+	var err error
+	switch {
+	case vote == 0:
+		_, err = tx.Exec(`DELETE FROM teian_votes WHERE username = ? AND suggestion_id = ?`, username, id)
+	case old == 0:
+		_, err = tx.Exec(`INSERT INTO teian_votes (username, suggestion_id, vote) VALUES (?, ?, ?)`, username, id, vote)
+	default:
+		_, err = tx.Exec(`UPDATE teian_votes SET vote = ? WHERE username = ? AND suggestion_id = ?`, vote, username, id)
+	}
+	return err
+}
+
+func (db *SQLStore) Vote(ctx context.Context, id uint64, username string, vote int) error {
+	if vote < -1 || vote > 1 {
+		return teian.ErrBadVote
+	}
+	return db.tx(ctx, func(tx *sql.Tx) error {
+		s, err := db.getSuggestion(tx, "", id)
+		if err != nil {
+			return err
+		}
+		if err := s.CanVote(username); err != nil {
+			return err
+		}
+		old, err := getVote(tx, username, id)
+		if err != nil {
+			return err
+		}
+		if old == vote {
+			return nil
+		}
+		s.ApplyVote(old, vote)
+		if err := putSuggestion(tx, s); err != nil {
+			return err
+		}
+		return putVote(tx, username, id, old, vote)
+	})
+}
+
+func (db *SQLStore) VotesOf(ctx context.Context, username string) (map[uint64]int, error) {
+	rows, err := db.DB.QueryContext(ctx, `SELECT suggestion_id, vote FROM teian_votes WHERE username = ?`, username)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	votes := make(map[uint64]int)
+	for rows.Next() {
+		var id uint64
+		var vote int
+		if err := rows.Scan(&id, &vote); err != nil {
+			return nil, err
+		}
+		votes[id] = vote
+	}
+	return votes, rows.Err()
+}
@@ -1,7 +1,9 @@
 package teian
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"sort"
 	"strings"
 	"time"
@@ -19,6 +21,12 @@ type Conf struct {
 	Keywords    string
 	WriteMsg    string
 	Version     string
+	// EditGrace is how long after creation users can edit their suggestions.
+	EditGrace time.Duration
+	// MaxAttachments is how many images can be attached to a suggestion.
+	MaxAttachments int
+	// Quotas are the upload quotas of the shimmie user classes.
+	Quotas ClassQuotas
 }
 
 // SiteTitle returns the Title capitalized.
@@ -27,26 +35,478 @@ func (c Conf) SiteTitle() string {
 }
 
 // SuggestionStore describes all the operations that need to access a storage
-// for the suggestions.
+// for the suggestions. Every operation takes the context of the request it
+// serves and fails with the context's error if it is done before the
+// operation starts. Errors caused by the arguments are of the kinds
+// ErrNotFound, ErrConflict or ErrInvalid.
 type SuggestionStore interface {
 	// Create creates a new suggestion for a user.
-	Create(username string, sugg *Suggestion) error
+	Create(ctx context.Context, username string, sugg *Suggestion) error
+	// Get returns the suggestion with the given id.
+	Get(ctx context.Context, id uint64) (*Suggestion, error)
+	// GetMany returns the suggestions with the given ids in that order, read
+	// in a single transaction. The ids that could not be read are returned
+	// with their errors keyed by ID while the rest are returned.
+	GetMany(ctx context.Context, ids []uint64) ([]Suggestion, map[uint64]error, error)
 	// OfUser gets all the suggestions created by a user.
-	OfUser(username string) ([]Suggestion, error)
+	OfUser(ctx context.Context, username string) ([]Suggestion, error)
 	// All returns all the suggestions.
-	All() ([]Suggestion, error)
-	// Delete deletes a user's suggestion.
-	Delete(username string, id uint64) error
+	All(ctx context.Context) ([]Suggestion, error)
+	// Search returns the suggestions that match a search query ranked by
+	// relevance. See ParseSearch for the query syntax.
+	Search(ctx context.Context, query string) ([]Suggestion, error)
+	// Query returns a page of the suggestions that match q.
+	Query(ctx context.Context, q Query) (*Page, error)
+	// Vote records the vote of a user on a suggestion. A vote of 1 is an
+	// upvote, -1 a downvote and 0 removes the user's vote. Each user has at
+	// most one vote per suggestion. Votes are only accepted as allowed by
+	// Suggestion.CanVote.
+	Vote(ctx context.Context, id uint64, username string, vote int) error
+	// VotesOf returns the votes of a user keyed by suggestion ID.
+	VotesOf(ctx context.Context, username string) (map[uint64]int, error)
+	// Delete moves a user's suggestion to the trash recording who deleted
+	// it and when. Suggestions in the trash are left out of every other
+	// method until they are restored.
+	Delete(ctx context.Context, username string, id uint64, by string) error
+	// Bulk applies op to the suggestions with the given ids in a single
+	// transaction. The suggestions that could not be changed are returned
+	// with their errors keyed by ID while the rest are changed.
+	Bulk(ctx context.Context, ids []uint64, op BulkOp) (map[uint64]error, error)
+	// Trash returns the deleted suggestions, the most recently deleted
+	// first.
+	Trash(ctx context.Context) ([]Suggestion, error)
+	// Trashed returns the deleted suggestion with the given id.
+	Trashed(ctx context.Context, id uint64) (*Suggestion, error)
+	// Restore moves a suggestion out of the trash.
+	Restore(ctx context.Context, id uint64) error
+	// Purge permanently removes a suggestion from the trash along with its
+	// revisions and votes.
+	Purge(ctx context.Context, id uint64) error
+	// Import adds suggestions keeping their IDs and creation times. The
+	// suggestions without an ID are given one and those without a creation
+	// time are created now. Suggestions that are already in the store,
+	// including the trash, are skipped so that an import can be run again.
+	// Those without an ID are considered in the store if their user has one
+	// with the same text. Import reports which suggestions were added. With
+	// dryRun nothing is changed but the report is the same.
+	Import(ctx context.Context, suggs []Suggestion, dryRun bool) (added []bool, err error)
+	// SetStatus transitions a user's suggestion to a new status. The change
+	// is recorded in the suggestion's history along with who made it.
+	SetStatus(ctx context.Context, username string, id uint64, status Status, by string) error
+	// Edit replaces the text of a user's suggestion. The new text is
+	// recorded as a revision by the user.
+	Edit(ctx context.Context, username string, id uint64, text string) error
+	// Revisions returns every revision of the text of a suggestion, oldest
+	// first.
+	Revisions(ctx context.Context, id uint64) ([]Revision, error)
+	// Revert sets the text of a suggestion to one of its revisions, by
+	// index in Revisions. The result is recorded as a new revision.
+	Revert(ctx context.Context, id uint64, revision int, by string) error
+	// AddReply adds a reply to the thread of a user's suggestion.
+	AddReply(ctx context.Context, username string, id uint64, reply *Reply) error
+	// SetCategory changes the category of a user's suggestion. The category
+	// must exist or be empty to leave the suggestion uncategorized.
+	SetCategory(ctx context.Context, username string, id uint64, category string) error
+	// Similar returns the suggestions whose text has at least the given
+	// similarity to text, the most similar first. See Similarity.
+	Similar(ctx context.Context, text string, threshold float64) ([]ScoredSuggestion, error)
+	// Merge merges the duplicate suggestion from into the suggestion into.
+	// Votes are moved to into unless the voter already voted on it and from
+	// is marked as a duplicate. See Suggestion.Merge. Neither suggestion may
+	// already be merged into another and from may not have duplicates of its
+	// own so that merges never form chains or cycles.
+	Merge(ctx context.Context, into, from uint64, by string) error
 
-	CheckQuota(username string, n Quota) (Quota, error)
+	// Categories returns the names of the categories admins have defined in
+	// alphabetical order.
+	Categories(ctx context.Context) ([]string, error)
+	// AddCategory defines a new category.
+	AddCategory(ctx context.Context, name string) error
+	// RemoveCategory removes a category. Suggestions in it are left
+	// uncategorized.
+	RemoveCategory(ctx context.Context, name string) error
+	// CategoryCounts returns the number of suggestions in each category.
+	// Uncategorized suggestions are not counted.
+	CategoryCounts(ctx context.Context) (map[string]int, error)
+
+	// CheckQuota adds n to the upload quota used by username and returns
+	// how much of limit remains. If the user would go over limit nothing is
+	// added and ErrOverQuota is returned.
+	CheckQuota(ctx context.Context, username string, n, limit Quota) (Quota, error)
+	// RefundQuota gives back the quota of an upload of n that CheckQuota
+	// counted but that was then not kept. See Uploads.Refund.
+	RefundQuota(ctx context.Context, username string, n Quota) error
+	// QuotaOverride returns the upload quota an admin set for username in
+	// place of the quota of their class.
+	QuotaOverride(ctx context.Context, username string) (Quota, error)
+	// SetQuotaOverride sets the upload quota of username regardless of
+	// their class.
+	SetQuotaOverride(ctx context.Context, username string, limit Quota) error
+	// RemoveQuotaOverride makes username go back to the upload quota of
+	// their class.
+	RemoveQuotaOverride(ctx context.Context, username string) error
+	// QuotaUsage returns every user that has used some of their upload
+	// quota or has an override, ordered by username.
+	QuotaUsage(ctx context.Context) ([]QuotaUsage, error)
+}
+
+// Order is the order in which a query returns suggestions.
+type Order string
+
+// The available orders. They match the values of the admin toolbar.
+const (
+	OrderDateDesc  Order = "dd"
+	OrderDateAsc   Order = "da"
+	OrderUserDesc  Order = "ud"
+	OrderUserAsc   Order = "ua"
+	OrderVotesDesc Order = "vd"
+	OrderVotesAsc  Order = "va"
+)
+
+// Query describes which suggestions to return, in what order and how many.
+// Zero valued fields do not filter.
+type Query struct {
+	// Username returns suggestions whose username contains it.
+	Username string
+	// Text returns suggestions whose text contains it.
+	Text string
+	// Statuses returns suggestions with any of these statuses.
+	Statuses []Status
+	// Public returns only public suggestions.
+	Public bool
+	// Category returns suggestions in that category.
+	Category string
+	// Since returns suggestions created at or after it.
+	Since time.Time
+	// Until returns suggestions created before it.
+	Until time.Time
+	// Order defaults to OrderDateDesc.
+	Order Order
+	// Limit is the maximum number of suggestions to return. Zero or less
+	// means no limit.
+	Limit int
+	// Cursor is a value of Page.Next or Page.Prev of a previous query with
+	// the same filters and order. An empty cursor starts from the beginning.
+	Cursor string
+}
+
+// Match reports whether s passes the filters of q.
+func (q *Query) Match(s *Suggestion) bool {
+	if q.Username != "" && !strings.Contains(s.Username, q.Username) {
+		return false
+	}
+	if q.Text != "" && !strings.Contains(s.Text, q.Text) {
+		return false
+	}
+	if len(q.Statuses) != 0 && !containsStatus(q.Statuses, s.Status) {
+		return false
+	}
+	if q.Public && !s.Public {
+		return false
+	}
+	if q.Category != "" && s.Category != q.Category {
+		return false
+	}
+	if !q.Since.IsZero() && s.Created.Before(q.Since) {
+		return false
+	}
+	if !q.Until.IsZero() && !s.Created.Before(q.Until) {
+		return false
+	}
+	return true
+}
+
+func containsStatus(statuses []Status, status Status) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// Page is a page of suggestions returned by a query.
+type Page struct {
+	Suggestions []Suggestion
+	// Next is the cursor of the next page or empty if this is the last.
+	Next string
+	// Prev is the cursor of the previous page or empty if this is the first.
+	Prev string
 }
 
 // Suggestion represents a suggestion that a user can create.
 type Suggestion struct {
-	ID       uint64
-	Username string
-	Text     string
-	Created  time.Time
+	ID       uint64         `json:"id"`
+	Username string         `json:"username"`
+	Text     string         `json:"text"`
+	Created  time.Time      `json:"created"`
+	Status   Status         `json:"status"`
+	History  []StatusChange `json:"history"`
+	Replies  []Reply        `json:"replies"`
+	// Upvotes and Downvotes are the number of users that voted each way.
+	Upvotes   int `json:"upvotes"`
+	Downvotes int `json:"downvotes"`
+	// Public suggestions are shown to everyone on the public board.
+	Public bool `json:"public"`
+	// Anonymous suggestions never show their username to other users.
+	Anonymous bool `json:"anonymous"`
+	// Category is one of the categories defined by the admins or empty.
+	Category string `json:"category"`
+	// Tags are booru tags the suggestion refers to.
+	Tags []string `json:"tags"`
+	// Merged holds the IDs of the duplicates merged into the suggestion and
+	// MergedInto the ID of the suggestion it was merged into, if any.
+	Merged     []uint64 `json:"merged"`
+	MergedInto uint64   `json:"merged_into"`
+	// Attachments are the images uploaded along with the suggestion.
+	Attachments []Attachment `json:"attachments"`
+	// DeletedBy and DeletedAt record who moved the suggestion to the trash
+	// and when.
+	DeletedBy string    `json:"deleted_by,omitempty"`
+	DeletedAt time.Time `json:"-"`
+}
+
+// Attachment is an image attached to a suggestion. The image and its
+// thumbnail are stored in the upload directory of the suggestion's author.
+type Attachment struct {
+	// Name and Thumb are the file names of the image and its thumbnail.
+	Name        string `json:"name"`
+	Thumb       string `json:"thumb"`
+	ContentType string `json:"content_type"`
+	Size        int64  `json:"size"`
+}
+
+// ErrUnknownCategory is returned when a suggestion is assigned a category
+// that has not been defined.
+var ErrUnknownCategory = Errorf(ErrInvalid, "unknown category")
+
+// ParseTags splits a space separated list of booru tags, lowercases them and
+// removes duplicates.
+func ParseTags(s string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, t := range strings.Fields(strings.ToLower(s)) {
+		if !seen[t] {
+			seen[t] = true
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
+
+// BoardEntry is the view of a public suggestion that is shown to everyone.
+type BoardEntry struct {
+	ID        uint64    `json:"id"`
+	Username  string    `json:"username,omitempty"`
+	Text      string    `json:"text"`
+	Status    Status    `json:"status"`
+	Created   time.Time `json:"created"`
+	Upvotes   int       `json:"upvotes"`
+	Downvotes int       `json:"downvotes"`
+}
+
+// NewBoardEntry returns the public view of s. The username is left empty if
+// the author asked to be anonymous.
+func NewBoardEntry(s *Suggestion) BoardEntry {
+	e := BoardEntry{
+		ID:        s.ID,
+		Username:  s.Username,
+		Text:      s.Text,
+		Status:    s.Status,
+		Created:   s.Created,
+		Upvotes:   s.Upvotes,
+		Downvotes: s.Downvotes,
+	}
+	if s.Anonymous {
+		e.Username = ""
+	}
+	return e
+}
+
+// FmtCreated returns the creation time of the entry formatted like
+// Suggestion.FmtCreated.
+func (e BoardEntry) FmtCreated() string {
+	return e.Created.UTC().Format("Mon 02 Jan 2006 15:04:05 MST")
+}
+
+// Votes returns the net score of the votes on the suggestion.
+func (s *Suggestion) Votes() int {
+	return s.Upvotes - s.Downvotes
+}
+
+// ApplyVote updates the vote counts of the suggestion for a user that
+// changes their vote from old to vote.
+func (s *Suggestion) ApplyVote(old, vote int) {
+	switch old {
+	case 1:
+		s.Upvotes--
+	case -1:
+		s.Downvotes--
+	}
+	switch vote {
+	case 1:
+		s.Upvotes++
+	case -1:
+		s.Downvotes++
+	}
+}
+
+// CanVote returns an error if username may not vote on the suggestion.
+// Users can only vote on suggestions they can see, those that are public or
+// their own, and only while the suggestion is open. Suggestions the user
+// cannot see are reported as not found so that votes do not reveal them.
+func (s *Suggestion) CanVote(username string) error {
+	if !s.Public && s.Username != username {
+		return ErrNotFound
+	}
+	if !s.Status.Open() {
+		return Errorf(ErrConflict, "cannot vote on suggestion %d which is %s", s.ID, s.Status)
+	}
+	return nil
+}
+
+// ErrBadVote is returned when a vote is not one of 1, -1 or 0.
+var ErrBadVote = Errorf(ErrInvalid, "vote must be 1, -1 or 0")
+
+// Reply is a comment posted on a suggestion, usually by an admin, that the
+// author of the suggestion can read.
+type Reply struct {
+	Username string    `json:"username"`
+	Text     string    `json:"text"`
+	Created  time.Time `json:"created"`
+}
+
+// FmtCreated returns the creation time of the reply formatted like
+// Suggestion.FmtCreated.
+func (r Reply) FmtCreated() string {
+	return r.Created.UTC().Format("Mon 02 Jan 2006 15:04:05 MST")
+}
+
+// Editable reports whether the suggestion was created less than grace ago
+// and can still be edited by its author.
+func (s *Suggestion) Editable(grace time.Duration) bool {
+	return time.Since(s.Created) < grace
+}
+
+// SetStatus changes the status of the suggestion and appends the transition
+// to its history. It returns false if the suggestion already had that status
+// in which case nothing is recorded.
+func (s *Suggestion) SetStatus(status Status, by string, at time.Time) bool {
+	if s.Status == status {
+		return false
+	}
+	s.History = append(s.History, StatusChange{From: s.Status, To: status, By: by, At: at})
+	s.Status = status
+	return true
+}
+
+// Status is the state of a suggestion in the admin triage workflow.
+type Status int
+
+// The statuses a suggestion can go through. The zero value is StatusNew so
+// that suggestions stored before statuses existed are considered new.
+const (
+	StatusNew Status = iota
+	StatusAcknowledged
+	StatusPlanned
+	StatusInProgress
+	StatusDone
+	StatusRejected
+	StatusDuplicate
+)
+
+var statusNames = []string{
+	StatusNew:          "new",
+	StatusAcknowledged: "acknowledged",
+	StatusPlanned:      "planned",
+	StatusInProgress:   "in-progress",
+	StatusDone:         "done",
+	StatusRejected:     "rejected",
+	StatusDuplicate:    "duplicate",
+}
+
+func (s Status) String() string {
+	if s < 0 || int(s) >= len(statusNames) {
+		return fmt.Sprintf("Status(%d)", int(s))
+	}
+	return statusNames[s]
+}
+
+// Open reports whether the suggestion is still being considered, as opposed
+// to done, rejected or marked as a duplicate.
+func (s Status) Open() bool {
+	return s < StatusDone
+}
+
+// OpenStatuses returns the statuses for which Open is true.
+func OpenStatuses() []Status {
+	var ss []Status
+	for _, s := range Statuses() {
+		if s.Open() {
+			ss = append(ss, s)
+		}
+	}
+	return ss
+}
+
+// Statuses returns all the valid statuses in workflow order.
+func Statuses() []Status {
+	ss := make([]Status, len(statusNames))
+	for i := range statusNames {
+		ss[i] = Status(i)
+	}
+	return ss
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s Status) MarshalText() ([]byte, error) {
+	if s < 0 || int(s) >= len(statusNames) {
+		return nil, fmt.Errorf("unknown status %d", int(s))
+	}
+	return []byte(statusNames[s]), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *Status) UnmarshalText(text []byte) error {
+	st, err := ParseStatus(string(text))
+	if err != nil {
+		return err
+	}
+	*s = st
+	return nil
+}
+
+// ParseStatus returns the status that has the given name.
+func ParseStatus(name string) (Status, error) {
+	for i, n := range statusNames {
+		if n == name {
+			return Status(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown status %q", name)
+}
+
+// StatusChange records a transition of a suggestion from one status to
+// another, who made it and when. A change with Merged set records the merge
+// of that duplicate instead and leaves the status as it was.
+type StatusChange struct {
+	From   Status    `json:"from"`
+	To     Status    `json:"to"`
+	By     string    `json:"by"`
+	At     time.Time `json:"at"`
+	Merged uint64    `json:"merged,omitempty"`
+}
+
+// FmtAt returns the time of the status change formatted like
+// Suggestion.FmtCreated.
+func (c StatusChange) FmtAt() string {
+	return c.At.UTC().Format("Mon 02 Jan 2006 15:04:05 MST")
+}
+
+// FmtDeletedAt returns the time the suggestion was deleted formatted like
+// FmtCreated.
+func (s *Suggestion) FmtDeletedAt() string {
+	return s.DeletedAt.UTC().Format("Mon 02 Jan 2006 15:04:05 MST")
 }
 
 // FmtCreated returns the creation time of the suggestion formatted as:
@@ -113,3 +573,14 @@ func FilterByText(suggs []Suggestion, text string) []Suggestion {
 	}
 	return f
 }
+
+// FilterByStatus returns suggestions that have the provided status.
+func FilterByStatus(suggs []Suggestion, status Status) []Suggestion {
+	var f []Suggestion
+	for _, s := range suggs {
+		if s.Status == status {
+			f = append(f, s)
+		}
+	}
+	return f
+}
@@ -89,7 +89,7 @@ func TestFilterByText(t *testing.T) {
 	for _, tt := range filterByTextTests {
 		sort.Sort(sort.Reverse(ByDate(tt.in)))
 		if got, want := FilterByText(tt.in, tt.text), tt.out; !reflect.DeepEqual(got, want) {
-			t.Errorf("FilterByText(%q, %q) = %q, want %q", tt.in, tt.text, got, want)
+			t.Errorf("FilterByText(%v, %q) = %v, want %v", tt.in, tt.text, got, want)
 		}
 	}
 }
@@ -115,7 +115,131 @@ func TestFilterByUserText(t *testing.T) {
 	for _, tt := range filterByUserTests {
 		sort.Sort(sort.Reverse(ByUser(tt.in)))
 		if got, want := FilterByUser(tt.in, tt.username), tt.out; !reflect.DeepEqual(got, want) {
-			t.Errorf("FilterByUser(%q, %q) = %#v, want %#v", tt.in, tt.username, got, want)
+			t.Errorf("FilterByUser(%v, %q) = %#v, want %#v", tt.in, tt.username, got, want)
 		}
 	}
 }
+
+func TestParseStatus(t *testing.T) {
+	for _, st := range Statuses() {
+		got, err := ParseStatus(st.String())
+		if err != nil {
+			t.Errorf("ParseStatus(%q) returned error: %v", st, err)
+		}
+		if got != st {
+			t.Errorf("ParseStatus(%q) = %v, want %v", st, got, st)
+		}
+	}
+	if _, err := ParseStatus("unknown"); err == nil {
+		t.Error(`ParseStatus("unknown") expected to return error`)
+	}
+}
+
+func TestSuggestion_SetStatus(t *testing.T) {
+	s := Suggestion{}
+	if changed := s.SetStatus(StatusNew, "admin", today); changed {
+		t.Error("SetStatus to the same status should not record a change")
+	}
+	if changed := s.SetStatus(StatusPlanned, "admin", today); !changed {
+		t.Error("SetStatus to a different status should record a change")
+	}
+	want := []StatusChange{{From: StatusNew, To: StatusPlanned, By: "admin", At: today}}
+	if got := s.History; !reflect.DeepEqual(got, want) {
+		t.Errorf("SetStatus history = %#v, want %#v", got, want)
+	}
+	if got, want := s.Status, StatusPlanned; got != want {
+		t.Errorf("SetStatus status = %v, want %v", got, want)
+	}
+}
+
+var filterByStatusTests = []struct {
+	in     []Suggestion
+	status Status
+	out    []Suggestion
+}{
+	{
+		[]Suggestion{{ID: 1, Status: StatusDone}, {ID: 2}},
+		StatusNew,
+		[]Suggestion{{ID: 2}},
+	},
+	{
+		[]Suggestion{},
+		StatusDone,
+		nil,
+	},
+}
+
+func TestFilterByStatus(t *testing.T) {
+	for _, tt := range filterByStatusTests {
+		if got, want := FilterByStatus(tt.in, tt.status), tt.out; !reflect.DeepEqual(got, want) {
+			t.Errorf("FilterByStatus(%#v, %v) = %#v, want %#v", tt.in, tt.status, got, want)
+		}
+	}
+}
+
+func TestQuery_Match(t *testing.T) {
+	s := &Suggestion{Username: "john", Text: "more tags", Status: StatusPlanned, Created: yesterday}
+	tests := []struct {
+		q    Query
+		want bool
+	}{
+		{Query{}, true},
+		{Query{Username: "oh"}, true},
+		{Query{Username: "mary"}, false},
+		{Query{Text: "tags"}, true},
+		{Query{Text: "Tags"}, false},
+		{Query{Statuses: []Status{StatusNew, StatusPlanned}}, true},
+		{Query{Statuses: []Status{StatusNew}}, false},
+		{Query{Since: yesterday}, true},
+		{Query{Since: today}, false},
+		{Query{Until: today}, true},
+		{Query{Until: yesterday}, false},
+		{Query{Public: true}, false},
+		{Query{Category: "uploads"}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.q.Match(s); got != tt.want {
+			t.Errorf("Query%+v.Match(%#v) = %v, want %v", tt.q, s, got, tt.want)
+		}
+	}
+}
+
+func TestNewBoardEntry(t *testing.T) {
+	s := &Suggestion{ID: 1, Username: "john", Text: "hi", Public: true}
+	if got, want := NewBoardEntry(s).Username, "john"; got != want {
+		t.Errorf("NewBoardEntry username = %q, want %q", got, want)
+	}
+	s.Anonymous = true
+	if got := NewBoardEntry(s).Username; got != "" {
+		t.Errorf("NewBoardEntry of anonymous suggestion has username %q", got)
+	}
+}
+
+func TestStatus_MarshalText(t *testing.T) {
+	for _, st := range Statuses() {
+		text, err := st.MarshalText()
+		if err != nil {
+			t.Fatalf("%v.MarshalText failed: %v", st, err)
+		}
+		var got Status
+		if err := got.UnmarshalText(text); err != nil {
+			t.Fatalf("UnmarshalText(%q) failed: %v", text, err)
+		}
+		if got != st {
+			t.Errorf("UnmarshalText(%q) = %v, want %v", text, got, st)
+		}
+	}
+	if _, err := Status(99).MarshalText(); err == nil {
+		t.Error("MarshalText of unknown status expected to return error")
+	}
+}
+
+func TestParseTags(t *testing.T) {
+	got := ParseTags("  Touhou  long_hair touhou\tsmile ")
+	if want := []string{"touhou", "long_hair", "smile"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseTags = %q, want %q", got, want)
+	}
+	if got := ParseTags(" "); got != nil {
+		t.Errorf("ParseTags of blank string = %q, want nil", got)
+	}
+}
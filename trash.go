@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"html/template"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/kusubooru/shimmie"
+	"github.com/kusubooru/teian/teian"
+)
+
+// serveTrash shows the deleted suggestions to an admin.
+func (app *App) serveTrash(w http.ResponseWriter, r *http.Request) {
+	user, ok := shimmie.FromContextGetUser(r.Context())
+	if !ok || user.Admin != "Y" {
+		http.Error(w, "You are not authorized to view this page.", http.StatusUnauthorized)
+		return
+	}
+	suggs, err := app.Suggestions.Trash(r.Context())
+	if err != nil {
+		app.storeError(w, err, "could not get deleted suggestions")
+		return
+	}
+	app.render(w, trashTmpl, suggs)
+}
+
+// handleTrash allows an admin to restore a deleted suggestion or to purge it
+// permanently.
+func (app *App) handleTrash(w http.ResponseWriter, r *http.Request) {
+	// only accept POST method
+	if r.Method != "POST" {
+		http.Error(w, fmt.Sprintf("%v method not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := shimmie.FromContextGetUser(r.Context())
+	if !ok || user.Admin != "Y" {
+		http.Error(w, "You are not authorized to perform this action.", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseUint(r.PostFormValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("bad id provided: %v", err), http.StatusBadRequest)
+		return
+	}
+	switch r.PostFormValue("action") {
+	case "restore":
+		err = app.Suggestions.Restore(r.Context(), id)
+	case "purge":
+		err = app.purge(r.Context(), id)
+	default:
+		http.Error(w, "unknown action", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, fmt.Sprintf("%s suggestion failed: %v", r.PostFormValue("action"), err), storeStatus(err))
+		return
+	}
+	http.Redirect(w, r, "/suggest/admin/trash", http.StatusFound)
+}
+
+// purge permanently removes the deleted suggestion with id and its
+// attachments.
+func (app *App) purge(ctx context.Context, id uint64) error {
+	s, err := app.Suggestions.Trashed(ctx, id)
+	if err != nil {
+		return err
+	}
+	return app.purgeSuggestion(ctx, s)
+}
+
+func (app *App) purgeSuggestion(ctx context.Context, s *teian.Suggestion) error {
+	if err := app.Suggestions.Purge(ctx, s.ID); err != nil {
+		return err
+	}
+	removeAttachments(filepath.Join(*uploadDir, s.Username), s.Attachments, app.Log)
+	return nil
+}
+
+// purgeTrash permanently removes the suggestions that were deleted more
+// than retention ago and returns how many were removed.
+func (app *App) purgeTrash(ctx context.Context, retention time.Duration) (int, error) {
+	suggs, err := app.Suggestions.Trash(ctx)
+	if err != nil {
+		return 0, err
+	}
+	cutoff := time.Now().Add(-retention)
+	n := 0
+	for i := range suggs {
+		if suggs[i].DeletedAt.After(cutoff) {
+			continue
+		}
+		if err := app.purgeSuggestion(ctx, &suggs[i]); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, nil
+}
+
+// purgeTrashEvery runs purgeTrash every interval until the returned stop
+// function is called. Stop waits for a purge that is running to finish so
+// that the store can be closed after it.
+func (app *App) purgeTrashEvery(interval, retention time.Duration) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		for {
+			select {
+			case <-ticker.C:
+				if n, err := app.purgeTrash(context.Background(), retention); err != nil {
+					app.Log.Println("Purge trash failed:", err)
+				} else if n != 0 {
+					app.Log.Printf("Purged %d suggestions from the trash", n)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		ticker.Stop()
+		close(done)
+		<-stopped
+	}
+}
+
+var trashTmpl = template.Must(template.New("trashTmpl").Parse(baseTemplate + subnavTemplate + tagsTemplate + repliesTemplate + trashTemplate))
+
+const trashTemplate = `
+{{define "content"}}
+{{ range $k, $v := .Data }}
+	<div class="suggestion">
+		<span>#{{$v.ID}} {{$v.FmtCreated}} by <a href="/user/{{$v.Username}}">{{$v.Username}}</a> ({{$v.Status}}), deleted {{$v.FmtDeletedAt}} by <a href="/user/{{$v.DeletedBy}}">{{$v.DeletedBy}}</a></span>
+		<form method="post" action="/suggest/admin/trash/action">
+			<input type="hidden" name="id" value="{{$v.ID}}">
+			<input type="hidden" name="action" value="restore">
+			<input type="submit" value="Restore">
+		</form>
+		<form method="post" action="/suggest/admin/trash/action" onsubmit="return confirm('Permanently delete suggestion #{{$v.ID}}?');">
+			<input type="hidden" name="id" value="{{$v.ID}}">
+			<input type="hidden" name="action" value="purge">
+			<input type="submit" value="Delete forever">
+		</form>
+		<div class="text">{{$v.HTML}}</div>
+		{{template "tags" $v.Tags}}
+		{{template "replies" $v.Replies}}
+	</div>
+{{ else }}
+	<div class="suggestion-form">
+		<p>The trash is empty.</p>
+	</div>
+{{ end }}
+{{end}}
+`
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kusubooru/teian/internal/mock"
+	"github.com/kusubooru/teian/teian"
+)
+
+func TestApp_purgeTrash(t *testing.T) {
+	now := time.Now()
+	s := &mock.SuggestionStore{}
+	s.TrashFn = func(ctx context.Context) ([]teian.Suggestion, error) {
+		return []teian.Suggestion{
+			{ID: 1, DeletedAt: now.Add(-time.Hour)},
+			{ID: 2, DeletedAt: now.Add(-48 * time.Hour)},
+			{ID: 3, DeletedAt: now.Add(-72 * time.Hour)},
+		}, nil
+	}
+	var purged []uint64
+	s.PurgeFn = func(ctx context.Context, id uint64) error {
+		purged = append(purged, id)
+		return nil
+	}
+	app := App{Log: discardLogger, Suggestions: s}
+
+	n, err := app.purgeTrash(context.Background(), 24*time.Hour)
+	if err != nil {
+		t.Fatal("purgeTrash failed:", err)
+	}
+	if n != 2 || len(purged) != 2 || purged[0] != 2 || purged[1] != 3 {
+		t.Errorf("purgeTrash purged %d suggestions %v, want 2 suggestions [2 3]", n, purged)
+	}
+}
+
+func TestApp_purge(t *testing.T) {
+	s := &mock.SuggestionStore{}
+	s.TrashedFn = func(ctx context.Context, id uint64) (*teian.Suggestion, error) {
+		if id != 1 {
+			return nil, teian.ErrNotFound
+		}
+		return &teian.Suggestion{ID: 1, Username: "jin"}, nil
+	}
+	s.PurgeFn = func(ctx context.Context, id uint64) error { return nil }
+	app := App{Log: discardLogger, Suggestions: s}
+
+	if err := app.purge(context.Background(), 1); err != nil || !s.PurgeInvoked {
+		t.Errorf("purge of deleted suggestion = %v, purged %v, want it purged", err, s.PurgeInvoked)
+	}
+	s.PurgeInvoked = false
+	if err := app.purge(context.Background(), 2); err != teian.ErrNotFound || s.PurgeInvoked {
+		t.Errorf("purge of suggestion not in the trash = %v, purged %v, want %v", err, s.PurgeInvoked, teian.ErrNotFound)
+	}
+}
+
+func TestApp_purgeTrashEvery(t *testing.T) {
+	s := &mock.SuggestionStore{}
+	calls := make(chan struct{}, 100)
+	s.TrashFn = func(ctx context.Context) ([]teian.Suggestion, error) {
+		calls <- struct{}{}
+		return nil, nil
+	}
+	app := App{Log: discardLogger, Suggestions: s}
+
+	stop := app.purgeTrashEvery(time.Millisecond, time.Hour)
+	<-calls
+	stop()
+	n := len(calls)
+	time.Sleep(5 * time.Millisecond)
+	if got := len(calls); got != n {
+		t.Errorf("purgeTrashEvery purged %d more times after stop", got-n)
+	}
+}
@@ -50,6 +50,11 @@ func (app *App) handleUpload(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "Wrong username or password.", http.StatusUnauthorized)
 		return
 	}
+	limit, err := app.quotaLimit(r.Context(), user)
+	if err != nil {
+		app.Errorf(w, http.StatusInternalServerError, err, "get quota of %q failed: %v", username, err)
+		return
+	}
 
 	file, handler, err := r.FormFile(uploadFormFileName)
 	if err != nil {
@@ -79,7 +84,7 @@ func (app *App) handleUpload(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check how many bytes the user is allowed to upload.
-	remain, err := app.Suggestions.CheckQuota(username, teian.Quota(n))
+	remain, err := app.Suggestions.CheckQuota(r.Context(), username, teian.Quota(n), limit)
 	if err != nil {
 		if rerr := os.Remove(f.Name()); rerr != nil {
 			app.Log.Println("file cleanup failed:", rerr)
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/kusubooru/shimmie"
+	"github.com/kusubooru/teian/teian"
+)
+
+const listPageSize = 50
+
+// serveList shows the open public suggestions to logged in users so that
+// they can vote on them.
+func (app *App) serveList(w http.ResponseWriter, r *http.Request) {
+	user, ok := shimmie.FromContextGetUser(r.Context())
+	if !ok {
+		http.Error(w, "You are not authorized to view this page.", http.StatusUnauthorized)
+		return
+	}
+	q := teian.Query{
+		Statuses: teian.OpenStatuses(),
+		Public:   true,
+		Order:    teian.Order(r.FormValue("o")),
+		Limit:    listPageSize,
+		Cursor:   r.FormValue("c"),
+	}
+	// Ordering by user would reveal which anonymous suggestions share an
+	// author, and so would the cursors of such orders.
+	switch q.Order {
+	case teian.OrderVotesDesc, teian.OrderVotesAsc, teian.OrderDateDesc, teian.OrderDateAsc:
+	default:
+		q.Order = teian.OrderVotesDesc
+	}
+	page, err := app.Suggestions.Query(r.Context(), q)
+	if err != nil {
+		app.storeError(w, err, "could not get suggestions")
+		return
+	}
+	votes, err := app.Suggestions.VotesOf(r.Context(), user.Name)
+	if err != nil {
+		app.storeError(w, err, "could not get votes")
+		return
+	}
+
+	data := struct {
+		Suggestions []teian.Suggestion
+		Votes       map[uint64]int
+		Order       teian.Order
+		Self        string
+		Prev        string
+		Next        string
+	}{
+		Suggestions: page.Suggestions,
+		Votes:       votes,
+		Order:       q.Order,
+		Self:        r.URL.RequestURI(),
+	}
+	if page.Prev != "" {
+		data.Prev = pageURL(r, page.Prev)
+	}
+	if page.Next != "" {
+		data.Next = pageURL(r, page.Next)
+	}
+	app.render(w, voteListTmpl, data)
+}
+
+func (app *App) handleVote(w http.ResponseWriter, r *http.Request) {
+	// only accept POST method
+	if r.Method != "POST" {
+		http.Error(w, fmt.Sprintf("%v method not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	user, ok := shimmie.FromContextGetUser(r.Context())
+	if !ok {
+		http.Error(w, "You are not authorized to perform this action.", http.StatusUnauthorized)
+		return
+	}
+	id, err := strconv.ParseUint(r.PostFormValue("id"), 10, 64)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("bad id provided: %v", err), http.StatusBadRequest)
+		return
+	}
+	vote, err := strconv.Atoi(r.PostFormValue("vote"))
+	if err != nil || vote < -1 || vote > 1 {
+		http.Error(w, teian.ErrBadVote.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := app.Suggestions.Vote(r.Context(), id, user.Name, vote); err != nil {
+		app.storeError(w, err, "vote failed")
+		return
+	}
+	// Return to the page the vote came from but never leave the list.
+	redirect := r.PostFormValue("redirect")
+	if !strings.HasPrefix(redirect, "/suggest/list") {
+		redirect = "/suggest/list"
+	}
+	http.Redirect(w, r, redirect, http.StatusSeeOther)
+}
+
+var voteListTmpl = template.Must(template.New("voteListTmpl").Parse(baseTemplate + subnavTemplate + voteListTemplate))
+
+const voteListTemplate = `
+{{define "css"}}
+.votes {
+	display: inline-block;
+	min-width: 3em;
+	text-align: center;
+}
+.votes form {
+	display: inline;
+}
+.votes .voted {
+	font-weight: bold;
+	color: #006FFA;
+}
+{{end}}
+{{define "toolbar"}}
+<div class="toolbar">
+	<form method="get" action="/suggest/list">
+		<label for="order">Order By</label>
+		<select id="order" name="o">
+			<option value="vd"{{if eq .Data.Order "vd"}} selected{{end}}>Votes Desc</option>
+			<option value="va"{{if eq .Data.Order "va"}} selected{{end}}>Votes Asc</option>
+			<option value="dd"{{if eq .Data.Order "dd"}} selected{{end}}>Date Desc</option>
+			<option value="da"{{if eq .Data.Order "da"}} selected{{end}}>Date Asc</option>
+		</select>
+		<button type="submit">Show</button>
+	</form>
+</div>
+{{end}}
+{{define "content"}}
+{{ $self := .Data.Self }}
+{{ $votes := .Data.Votes }}
+{{ range $k, $v := .Data.Suggestions }}
+	{{ $vote := index $votes $v.ID }}
+	<div class="suggestion">
+		<span class="votes">
+			<form method="post" action="/suggest/vote">
+				<input type="hidden" name="id" value="{{$v.ID}}">
+				<input type="hidden" name="redirect" value="{{$self}}">
+				<input type="hidden" name="vote" value="{{if eq $vote 1}}0{{else}}1{{end}}">
+				<input type="submit" value="&#9650;" title="Upvote"{{if eq $vote 1}} class="voted"{{end}}>
+			</form>
+			<span>{{$v.Votes}}</span>
+			<form method="post" action="/suggest/vote">
+				<input type="hidden" name="id" value="{{$v.ID}}">
+				<input type="hidden" name="redirect" value="{{$self}}">
+				<input type="hidden" name="vote" value="{{if eq $vote -1}}0{{else}}-1{{end}}">
+				<input type="submit" value="&#9660;" title="Downvote"{{if eq $vote -1}} class="voted"{{end}}>
+			</form>
+		</span>
+		<span>{{$v.FmtCreated}} by {{if $v.Anonymous}}anonymous{{else}}<a href="/user/{{$v.Username}}">{{$v.Username}}</a>{{end}} ({{$v.Status}})</span>
+		<div class="text">{{$v.HTML}}</div>
+	</div>
+{{ else }}
+	<div class="suggestion-form">
+		<p>There are no open public suggestions.</p>
+	</div>
+{{ end }}
+<div class="pagination">
+	{{if .Data.Prev}}<a href="{{.Data.Prev}}">&larr; Previous</a>{{end}}
+	{{if .Data.Next}}<a href="{{.Data.Next}}">Next &rarr;</a>{{end}}
+</div>
+{{end}}
+`
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/kusubooru/shimmie"
+	"github.com/kusubooru/teian/internal/mock"
+	"github.com/kusubooru/teian/teian"
+	"github.com/kusubooru/teian/teian/memstore"
+)
+
+func TestApp_handleVote(t *testing.T) {
+	s := &mock.SuggestionStore{}
+	s.VoteFn = func(ctx context.Context, id uint64, username string, vote int) error {
+		switch id {
+		case 2:
+			return teian.ErrNotFound
+		case 3:
+			return teian.Errorf(teian.ErrConflict, "suggestion is closed")
+		}
+		return nil
+	}
+	app := App{Log: discardLogger, Suggestions: s}
+
+	tests := []struct {
+		v        url.Values
+		code     int
+		location string
+	}{
+		{url.Values{"id": {"1"}, "vote": {"1"}}, 303, "/suggest/list"},
+		{url.Values{"id": {"1"}, "vote": {"-1"}, "redirect": {"/suggest/list?o=dd"}}, 303, "/suggest/list?o=dd"},
+		{url.Values{"id": {"1"}, "vote": {"0"}, "redirect": {"http://evil.example"}}, 303, "/suggest/list"},
+		{url.Values{"id": {"1"}, "vote": {"2"}}, 400, ""},
+		{url.Values{"id": {"x"}, "vote": {"1"}}, 400, ""},
+		{url.Values{"id": {"2"}, "vote": {"1"}}, 404, ""},
+		{url.Values{"id": {"3"}, "vote": {"1"}}, 409, ""},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		app.handleVote(w, newRequest("POST", tt.v, &shimmie.User{Name: "jin"}))
+		resp := w.Result()
+		if got, want := resp.StatusCode, tt.code; got != want {
+			t.Errorf("handleVote(%v) StatusCode = %d, want %d", tt.v, got, want)
+		}
+		if got, want := resp.Header.Get("Location"), tt.location; got != want {
+			t.Errorf("handleVote(%v) Location = %q, want %q", tt.v, got, want)
+		}
+	}
+}
+
+func TestApp_serveList(t *testing.T) {
+	s := &mock.SuggestionStore{}
+	var query teian.Query
+	s.QueryFn = func(ctx context.Context, q teian.Query) (*teian.Page, error) {
+		query = q
+		return &teian.Page{Suggestions: []teian.Suggestion{{ID: 7, Username: "mary", Text: "vote for me", Upvotes: 3}}}, nil
+	}
+	s.VotesOfFn = func(ctx context.Context, username string) (map[uint64]int, error) {
+		return map[uint64]int{7: 1}, nil
+	}
+	app := App{Log: discardLogger, Suggestions: s}
+
+	w := httptest.NewRecorder()
+	app.serveList(w, newRequest("GET", nil, &shimmie.User{Name: "jin"}))
+	if got, want := w.Result().StatusCode, 200; got != want {
+		t.Fatalf("StatusCode = %d, want %d", got, want)
+	}
+	if got, want := query.Order, teian.OrderVotesDesc; got != want {
+		t.Errorf("serveList queried with order %q, want %q", got, want)
+	}
+	if len(query.Statuses) == 0 {
+		t.Error("serveList should only query open suggestions")
+	}
+	body := w.Body.String()
+	if !strings.Contains(body, "vote for me") {
+		t.Error("serveList body does not contain suggestion text")
+	}
+	if !strings.Contains(body, `class="voted"`) {
+		t.Error("serveList body does not mark the user's vote")
+	}
+}
+
+func TestApp_serveList_orderByUser(t *testing.T) {
+	ctx := context.Background()
+	s := memstore.NewSuggestionStore()
+	for i := 0; i < listPageSize+1; i++ {
+		if err := s.Create(ctx, "john", &teian.Suggestion{Text: "secret", Public: true, Anonymous: true}); err != nil {
+			t.Fatal("store.Create failed:", err)
+		}
+	}
+	app := App{Log: discardLogger, Suggestions: s}
+
+	for _, o := range []string{"ua", "ud"} {
+		r := httptest.NewRequest("GET", "/suggest/list?o="+o, nil)
+		r = r.WithContext(shimmie.NewContextWithUser(r.Context(), &shimmie.User{Name: "jin"}))
+		w := httptest.NewRecorder()
+		app.serveList(w, r)
+		if got, want := w.Result().StatusCode, 200; got != want {
+			t.Fatalf("StatusCode = %d, want %d", got, want)
+		}
+		m := regexp.MustCompile(`c=([A-Za-z0-9_-]+)`).FindStringSubmatch(w.Body.String())
+		if m == nil {
+			t.Fatalf("serveList with o=%s has no next page link", o)
+		}
+		cursor, err := base64.RawURLEncoding.DecodeString(m[1])
+		if err != nil {
+			t.Fatal("could not decode cursor:", err)
+		}
+		if strings.Contains(string(cursor), "john") {
+			t.Errorf("serveList with o=%s has cursor %q with the author of anonymous suggestions", o, cursor)
+		}
+	}
+}